@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 
 	"clortho/internal/api"
 	"clortho/internal/config"
 	"clortho/internal/database"
+	"clortho/internal/metrics"
+	"clortho/internal/service"
 	"clortho/internal/store"
+	"clortho/internal/tracing"
 	"clortho/internal/version"
 )
 
@@ -32,6 +39,13 @@ func main() {
 	}
 
 	ctx := context.Background()
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
 	pool, err := database.New(ctx, cfg.DatabaseURL)
 	if err != nil {
 		slog.Error("Failed to connect to database", "error", err)
@@ -39,19 +53,146 @@ func main() {
 	}
 	defer pool.Close()
 
-	licenseStore := store.NewPostgresLicenseStore(pool)
-	productStore := store.NewPostgresProductStore(pool)
-	productGroupStore := store.NewPostgresProductGroupStore(pool)
-	releaseStore := store.NewPostgresReleaseStore(pool)
-	featureStore := store.NewPostgresFeatureStore(pool)
-	logStore := store.NewPostgresLogStore(pool)
+	if cfg.Metrics.Enabled {
+		if err := metrics.RegisterPoolStats(pool); err != nil {
+			slog.Warn("Failed to register database pool metrics", "error", err)
+		}
+	}
+
+	var licenseStore store.LicenseStore = store.NewPostgresLicenseStore(pool)
+	var productStore store.ProductStore = store.NewPostgresProductStore(pool)
+	var productGroupStore store.ProductGroupStore = store.NewPostgresProductGroupStore(pool)
+	var releaseStore store.ReleaseStore = store.NewPostgresReleaseStore(pool)
+	var featureStore store.FeatureStore = store.NewPostgresFeatureStore(pool)
+	var planStore store.PlanStore = store.NewPostgresPlanStore(pool)
 	statsStore := store.NewPostgresStatsStore(pool)
+	flagStore := store.NewPostgresFlagStore(pool)
+	logStore := store.NewPostgresLogStore(pool)
 
-	server := api.NewServer(cfg, pool, licenseStore, productStore, productGroupStore, releaseStore, featureStore, logStore, statsStore)
+	// Background jobs stop before the pool is closed
+	bgCtx, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+	if cfg.LicenseCache.Enabled {
+		licenseCache := store.NewLicenseCache(cfg.LicenseCache, pool)
+		go licenseCache.Listen(bgCtx)
 
-	slog.Info("Clortho the Keymaster ("+version.Version+") is now onduty", "port", cfg.Port)
-	if err := server.Router.Run(":" + cfg.Port); err != nil {
+		licenseStore = store.NewCachedLicenseStore(licenseStore, licenseCache)
+		productStore = store.NewCacheInvalidatingProductStore(productStore, licenseCache)
+		productGroupStore = store.NewCacheInvalidatingProductGroupStore(productGroupStore, licenseCache)
+		releaseStore = store.NewCacheInvalidatingReleaseStore(releaseStore, licenseCache)
+		featureStore = store.NewCacheInvalidatingFeatureStore(featureStore, licenseCache)
+		planStore = store.NewCacheInvalidatingPlanStore(planStore, licenseCache)
+	}
+
+	logWriter := service.NewLogWriter(logStore, cfg.AsyncLog)
+	logWriter.UsePrivacy(service.NewLogPrivacy(productStore, cfg.LogPrivacy))
+	logWriter.Start()
+
+	if cfg.AnomalyDetection.Enabled {
+		detector := service.NewAnomalyDetector(flagStore, licenseStore, logWriter, cfg.AnomalyDetection)
+		go detector.Run(bgCtx)
+	}
+	// Inserts fail once the premade months run out, so partitions are always
+	// maintained; log_partitions.enabled only turns on retention
+	partitions := service.NewLogPartitionManager(store.NewPostgresLogPartitionStore(pool), cfg.LogPartitions)
+	// Expired admin log partitions are only dropped once the chain is signed
+	// past them. A key generated at startup would not verify those
+	// checkpoints after a restart, so the chain is then never checkpointed
+	// and its partitions are kept.
+	if cfg.EphemeralSigningKeys {
+		slog.Warn("Admin log checkpoints are disabled and admin log partitions are kept: configure a response signing key pair to enable them")
+	} else if checkpointer, err := service.NewAdminLogCheckpointer(logStore, cfg.ResponseSigningPrivateKey, cfg.AdminLogChain.CheckpointInterval); err != nil {
+		slog.Error("Failed to set up admin log checkpoints", "error", err)
+	} else {
+		partitions.UseCheckpointer(checkpointer)
+		if cfg.AdminLogChain.CheckpointInterval > 0 {
+			go checkpointer.Run(bgCtx)
+		}
+	}
+	go partitions.Run(bgCtx)
+	if cfg.CheckRollups.Enabled {
+		rollups := service.NewCheckRollupJob(statsStore, cfg.CheckRollups)
+		go rollups.Run(bgCtx)
+	}
+	if cfg.LogForwarding.Enabled {
+		sink, err := service.NewLogSink(cfg.LogForwarding.Sink)
+		if err != nil {
+			slog.Error("Failed to set up log forwarding", "error", err)
+		} else {
+			forwarder := service.NewLogForwarder(logStore, sink, cfg.LogForwarding)
+			go forwarder.Run(bgCtx)
+		}
+	}
+	if cfg.LogPrivacy.RetentionInterval > 0 {
+		retention := service.NewLogRetentionJob(logStore, cfg.LogPrivacy)
+		go retention.Run(bgCtx)
+	}
+	if cfg.SuspensionResumeInterval > 0 {
+		resumer := service.NewSuspensionResumer(licenseStore, logWriter, cfg.SuspensionResumeInterval)
+		go resumer.Run(bgCtx)
+	}
+
+	server := api.NewServer(cfg, pool, api.Stores{
+		Licenses:      licenseStore,
+		Products:      productStore,
+		ProductGroups: productGroupStore,
+		Releases:      releaseStore,
+		Features:      featureStore,
+		Logs:          logWriter,
+		Stats:         statsStore,
+		Flags:         flagStore,
+		AdminLogChain: logStore,
+		LogPrivacy:    logStore,
+		Plans:         planStore,
+	})
+
+	httpServer := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: server.Router,
+	}
+	// Live streams never finish on their own, so end them when draining
+	httpServer.RegisterOnShutdown(server.LicenseChecks.Close)
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Clortho the Keymaster ("+version.Version+") is now onduty", "port", cfg.Port)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	exitCode := 0
+	select {
+	case <-sigCtx.Done():
+		slog.Info("Shutdown signal received, draining requests")
+	case err := <-serveErr:
 		slog.Error("Failed to run server", "error", err)
-		os.Exit(1)
+		exitCode = 1
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down HTTP server cleanly", "error", err)
+		exitCode = 1
+	}
+
+	stopBackground()
+
+	// Flush queued logs only after in-flight requests have finished producing them
+	if err := logWriter.Close(shutdownCtx); err != nil {
+		slog.Error("Failed to flush async logs", "error", err)
+		exitCode = 1
+	}
+
+	slog.Info("Clortho is now off duty")
+	if exitCode != 0 {
+		pool.Close()
+		os.Exit(exitCode)
 	}
 }
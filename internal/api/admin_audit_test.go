@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/api/middleware"
+	"clortho/internal/config"
+	"clortho/internal/models"
+)
+
+func TestAdminAudit_ActorAndChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{AdminSecret: "test-secret"}
+
+	productID := uuid.New()
+	mockProductStore := new(MockProductStore)
+	mockProductStore.On("GetProduct", mock.Anything, productID.String()).Return(&models.Product{
+		ID:            productID,
+		Name:          "Old Name",
+		LicensePrefix: "OLD",
+		LicenseLength: 16,
+	}, nil)
+	mockProductStore.On("UpdateProduct", mock.Anything, mock.Anything).Return(nil)
+
+	logged := make(chan *models.AdminLog, 1)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged <- args.Get(1).(*models.AdminLog)
+	}).Return(nil)
+
+	router := gin.New()
+	router.PUT("/admin/products/:id", middleware.JWTAuth(cfg), handlers.UpdateProductHandler(mockProductStore, mockLogStore))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "alice",
+		"jti": "token-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(cfg.AdminSecret))
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("PUT", "/admin/products/"+productID.String(), strings.NewReader(`{"name": "New Name", "license_prefix": "OLD"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "admin-cli/1.0")
+	req.RemoteAddr = "192.0.2.10:4321"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var entry *models.AdminLog
+	select {
+	case entry = <-logged:
+	case <-time.After(time.Second):
+		t.Fatal("admin action was not logged")
+	}
+
+	assert.Equal(t, "UPDATE_PRODUCT", entry.Action)
+	assert.Equal(t, &models.AdminActor{
+		Subject:   "alice",
+		TokenID:   "token-1",
+		IPAddress: "192.0.2.10",
+		UserAgent: "admin-cli/1.0",
+	}, entry.Actor)
+	assert.Equal(t, map[string]models.FieldChange{
+		"name": {Before: "Old Name", After: "New Name"},
+	}, entry.Changes, "unchanged fields and updated_at are left out")
+}
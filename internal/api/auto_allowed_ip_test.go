@@ -26,7 +26,7 @@ func TestGenerateLicense_AutoIPInheritance(t *testing.T) {
 	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	router := gin.New()
-	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, mockLogStore))
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, new(MockPlanStore), mockLogStore))
 
 	t.Run("Inherit_From_Product", func(t *testing.T) {
 		productID := uuid.New()
@@ -204,9 +204,7 @@ func TestCheckLicense_AutoIPLogic(t *testing.T) {
 
 		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).Return(license, nil).Once()
 		
-		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
-			return len(l.AllowedIPs) == 1 && l.AllowedIPs[0] == ip
-		})).Return(nil).Once()
+		mockLicenseStore.On("AddAutoAllowedIP", mock.Anything, key, ip).Return(true, nil).Once()
 
 		req, _ := http.NewRequest("GET", "/check", nil)
 		req.Header.Set("X-License-Key", key)
@@ -223,6 +221,39 @@ func TestCheckLicense_AutoIPLogic(t *testing.T) {
 		mockLicenseStore.AssertExpectations(t)
 	})
 
+	t.Run("AutoAllowedIP_LimitReachedConcurrently", func(t *testing.T) {
+		key := "TEST-LIMIT-RACE"
+		ip := "192.168.1.102"
+		// The cached license has room left, but another check took the last seat
+		license := &models.License{
+			ID:                 uuid.New(),
+			Key:                key,
+			Type:               models.LicenseTypePerpetual,
+			AllowedIPs:         []string{"10.0.0.1"},
+			AutoAllowedIP:      true,
+			AutoAllowedIPLimit: 2,
+			Status:             models.LicenseStatusActive,
+		}
+
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).Return(license, nil).Once()
+		mockLicenseStore.On("AddAutoAllowedIP", mock.Anything, key, ip).Return(false, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/check", nil)
+		req.Header.Set("X-License-Key", key)
+		req.Header.Set("X-Forwarded-For", ip)
+		req.RemoteAddr = "127.0.0.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.False(t, resp["valid"].(bool), "Should be invalid when the store refuses the IP")
+		assert.Equal(t, "IP address not allowed", resp["reason"])
+
+		mockLicenseStore.AssertExpectations(t)
+	})
+
 	t.Run("AutoAllowedIP_LimitReached", func(t *testing.T) {
 		key := "TEST-LIMIT-REACHED"
 		ip := "192.168.1.101"
@@ -325,7 +356,7 @@ func TestUpdateLicense_AutoAllowedIP(t *testing.T) {
 	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	router := gin.New()
-	router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, mockLogStore))
+	router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, new(MockProductStore), new(MockPlanStore), mockLogStore))
 
 	t.Run("Update_AutoAllowedIP_Settings", func(t *testing.T) {
 		key := "TEST-UPDATE-AUTO-IP"
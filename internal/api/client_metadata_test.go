@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckLicenseHandler_ClientMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	license := &models.License{
+		ID:        uuid.New(),
+		ProductID: uuid.New(),
+		Key:       "testkey",
+		Status:    models.LicenseStatusActive,
+		Releases:  []string{"2.0.0"},
+	}
+
+	setup := func() (*gin.Engine, chan *models.LicenseCheckLog) {
+		mockLicenseStore := new(MockLicenseStore)
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, "testkey").Return(license, nil)
+		logged := make(chan *models.LicenseCheckLog, 1)
+		mockLogStore := new(MockLogStore)
+		mockLogStore.On("CreateLicenseCheckLog", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			logged <- args.Get(1).(*models.LicenseCheckLog)
+		}).Return(nil)
+
+		router := gin.New()
+		handler := handlers.CheckLicenseHandler(mockLicenseStore, new(MockProductStore), "", mockLogStore)
+		router.GET("/check", handler)
+		router.POST("/check", handler)
+		return router, logged
+	}
+
+	waitForLog := func(t *testing.T, logged chan *models.LicenseCheckLog) *models.LicenseCheckLog {
+		select {
+		case entry := <-logged:
+			return entry
+		case <-time.After(time.Second):
+			t.Fatal("check was not logged")
+			return nil
+		}
+	}
+
+	t.Run("Headers", func(t *testing.T) {
+		router, logged := setup()
+
+		req, _ := http.NewRequest("GET", "/check?version=2.0.0", nil)
+		req.Header.Set("X-License-Key", "testkey")
+		req.Header.Set("X-Client-App-Version", "2.0.0")
+		req.Header.Set("X-Client-OS", "linux")
+		req.Header.Set("X-Client-Hostname-Hash", "9f86d081")
+		req.Header.Set("X-Client-SDK-Version", "1.4.0")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, models.ClientMetadata{
+			AppVersion:   "2.0.0",
+			OS:           "linux",
+			HostnameHash: "9f86d081",
+			SDKVersion:   "1.4.0",
+		}, waitForLog(t, logged).Client)
+	})
+
+	t.Run("BodyOverridesHeaders", func(t *testing.T) {
+		router, logged := setup()
+
+		body := `{"version": "2.0.0", "client": {"app_version": "2.0.1", "os": "windows", "sdk_version": "` + strings.Repeat("x", 200) + `"}}`
+		req, _ := http.NewRequest("POST", "/check", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-License-Key", "testkey")
+		req.Header.Set("X-Client-App-Version", "1.0.0")
+		req.Header.Set("X-Client-Hostname-Hash", "9f86d081")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		entry := waitForLog(t, logged)
+		assert.Equal(t, "2.0.1", entry.Client.AppVersion)
+		assert.Equal(t, "windows", entry.Client.OS)
+		assert.Equal(t, "9f86d081", entry.Client.HostnameHash)
+		assert.Len(t, entry.Client.SDKVersion, 128)
+		assert.Equal(t, "2.0.0", entry.RequestPayload["version"])
+	})
+
+	t.Run("InvalidBody", func(t *testing.T) {
+		router, logged := setup()
+
+		req, _ := http.NewRequest("POST", "/check", strings.NewReader(`{"client": "linux"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-License-Key", "testkey")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, http.StatusBadRequest, waitForLog(t, logged).StatusCode)
+	})
+}
@@ -73,7 +73,7 @@ func TestGenerateLicenseHandler_Duration(t *testing.T) {
 	mockLogStore := new(MockLogStore)
 	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
 	mockProductGroupStore := new(MockProductGroupStore)
-	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, mockLogStore))
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, new(MockPlanStore), mockLogStore))
 
 	t.Run("Success with duration", func(t *testing.T) {
 		pID := uuid.New()
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListFlaggedLicenses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("ListsFlagsForOwner", func(t *testing.T) {
+		mockFlagStore := new(MockFlagStore)
+		router := gin.New()
+		router.GET("/admin/keys/flagged", handlers.ListFlaggedLicensesHandler(mockFlagStore))
+
+		owner := "owner-1"
+		flags := []models.LicenseFlag{{
+			LicenseID:   uuid.New(),
+			LicenseKey:  "LEAKED-KEY",
+			Reasons:     []string{models.FlagReasonDistinctIPs},
+			DistinctIPs: 120,
+			Suspended:   true,
+		}}
+		mockFlagStore.On("ListLicenseFlags", mock.Anything, &owner, mock.Anything).Return(flags, 1, nil)
+
+		req, _ := http.NewRequest("GET", "/admin/keys/flagged?owner_id=owner-1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp models.PaginatedList[models.LicenseFlag]
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.Equal(t, 1, resp.TotalCount)
+		assert.Equal(t, "LEAKED-KEY", resp.Items[0].LicenseKey)
+		assert.True(t, resp.Items[0].Suspended)
+		mockFlagStore.AssertExpectations(t)
+	})
+
+	t.Run("EmptyListIsNotNull", func(t *testing.T) {
+		mockFlagStore := new(MockFlagStore)
+		router := gin.New()
+		router.GET("/admin/keys/flagged", handlers.ListFlaggedLicensesHandler(mockFlagStore))
+
+		mockFlagStore.On("ListLicenseFlags", mock.Anything, (*string)(nil), mock.Anything).Return([]models.LicenseFlag(nil), 0, nil)
+
+		req, _ := http.NewRequest("GET", "/admin/keys/flagged", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"items":[]`)
+	})
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/google/uuid"
 
 	"clortho/internal/models"
-	"clortho/internal/service"
 	"clortho/internal/store"
 )
 
@@ -53,6 +52,9 @@ func UpdateFeatureHandler(featureStore store.FeatureStore, logStore store.LogSto
 				c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
 				return
 			}
+			slog.Error("Failed to get feature", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature"})
+			return
 		}
 
 		feature := &models.Feature{
@@ -73,6 +75,10 @@ func UpdateFeatureHandler(featureStore store.FeatureStore, logStore store.LogSto
 		}
 
 		// Audit Log
+		updated := *existingFeature
+		updated.Name = feature.Name
+		updated.Code = feature.Code
+		updated.Description = feature.Description
 		logEntry := &models.AdminLog{
 			Action:     "UPDATE_FEATURE",
 			EntityType: "features",
@@ -81,9 +87,10 @@ func UpdateFeatureHandler(featureStore store.FeatureStore, logStore store.LogSto
 			Details: map[string]interface{}{
 				"request": req,
 			},
+			Changes:   auditChanges(existingFeature, updated),
 			CreatedAt: time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Feature updated"})
 	}
@@ -111,7 +118,7 @@ func DeleteFeatureHandler(featureStore store.FeatureStore, logStore store.LogSto
 			Details:    map[string]interface{}{},
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Feature deleted"})
 	}
@@ -186,7 +193,7 @@ func CreateFeatureHandler(featureStore store.FeatureStore, logStore store.LogSto
 			Details:    logDetails,
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusCreated, feature)
 	}
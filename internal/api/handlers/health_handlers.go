@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clortho/internal/database"
+	"clortho/internal/service"
+	"clortho/internal/store"
+)
+
+const (
+	healthStatusOK          = "ok"
+	healthStatusUnavailable = "unavailable"
+)
+
+type healthCheck struct {
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks"`
+}
+
+// queueDepther is implemented by log stores that buffer writes (service.LogWriter).
+type queueDepther interface {
+	QueueDepth() int
+}
+
+// LivenessHandler handles GET /livez. It only reports that the process is serving requests.
+func LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": healthStatusOK})
+	}
+}
+
+// ReadinessHandler handles GET /readyz
+func ReadinessHandler(db *pgxpool.Pool, responseSigningPrivateKey string, logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		checks := map[string]healthCheck{
+			"database":    checkDatabase(ctx, db),
+			"migrations":  checkMigrations(ctx, db),
+			"signing_key": checkSigningKey(responseSigningPrivateKey),
+			"async_log":   checkAsyncLog(logStore),
+		}
+
+		response := readinessResponse{Status: healthStatusOK, Checks: checks}
+		status := http.StatusOK
+		for _, check := range checks {
+			if check.Status != healthStatusOK {
+				response.Status = healthStatusUnavailable
+				status = http.StatusServiceUnavailable
+				break
+			}
+		}
+
+		c.JSON(status, response)
+	}
+}
+
+func checkDatabase(ctx context.Context, db *pgxpool.Pool) healthCheck {
+	if db == nil {
+		return healthCheck{Status: healthStatusUnavailable, Error: "no database configured"}
+	}
+
+	start := time.Now()
+	if err := db.Ping(ctx); err != nil {
+		return healthCheck{Status: healthStatusUnavailable, Error: err.Error()}
+	}
+
+	stat := db.Stat()
+	return healthCheck{
+		Status: healthStatusOK,
+		Details: map[string]interface{}{
+			"latency_ms":     time.Since(start).Milliseconds(),
+			"total_conns":    stat.TotalConns(),
+			"idle_conns":     stat.IdleConns(),
+			"acquired_conns": stat.AcquiredConns(),
+			"max_conns":      stat.MaxConns(),
+		},
+	}
+}
+
+func checkMigrations(ctx context.Context, db *pgxpool.Pool) healthCheck {
+	if db == nil {
+		return healthCheck{Status: healthStatusUnavailable, Error: "no database configured"}
+	}
+
+	current, dirty, err := database.CurrentSchemaVersion(ctx, db)
+	if err != nil {
+		return healthCheck{Status: healthStatusUnavailable, Error: err.Error()}
+	}
+
+	details := map[string]interface{}{
+		"current":  current,
+		"expected": database.SchemaVersion,
+		"dirty":    dirty,
+	}
+	if dirty {
+		return healthCheck{Status: healthStatusUnavailable, Error: "schema is dirty", Details: details}
+	}
+	if current != database.SchemaVersion {
+		return healthCheck{Status: healthStatusUnavailable, Error: fmt.Sprintf("schema version %d does not match expected %d", current, database.SchemaVersion), Details: details}
+	}
+	return healthCheck{Status: healthStatusOK, Details: details}
+}
+
+func checkSigningKey(responseSigningPrivateKey string) healthCheck {
+	if _, err := service.ParseSigningKey(responseSigningPrivateKey); err != nil {
+		return healthCheck{Status: healthStatusUnavailable, Error: err.Error()}
+	}
+	return healthCheck{Status: healthStatusOK}
+}
+
+func checkAsyncLog(logStore store.LogStore) healthCheck {
+	q, ok := logStore.(queueDepther)
+	if !ok {
+		return healthCheck{Status: healthStatusOK, Details: map[string]interface{}{"buffered": false}}
+	}
+	return healthCheck{
+		Status: healthStatusOK,
+		Details: map[string]interface{}{
+			"buffered":    true,
+			"queue_depth": q.QueueDepth(),
+		},
+	}
+}
@@ -5,14 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 
+	"clortho/internal/metrics"
 	"clortho/internal/models"
 	"clortho/internal/service"
 	"clortho/internal/store"
@@ -20,7 +26,8 @@ import (
 
 type generateLicenseRequest struct {
 	ProductID       string             `json:"product_id" binding:"required"`
-	Type            models.LicenseType `json:"type" binding:"required"`
+	PlanID          string             `json:"plan_id"`
+	Type            models.LicenseType `json:"type"`
 	ExpiresAt       *time.Time         `json:"expires_at"`
 	Duration        string             `json:"duration"`
 	Prefix          string             `json:"prefix"`
@@ -32,9 +39,24 @@ type generateLicenseRequest struct {
 	OwnerID         *string            `json:"owner_id"`
 	AutoAllowedIP     *bool              `json:"auto_allowed_ip"`
 	AutoAllowedIPLimit *int              `json:"auto_allowed_ip_limit"`
+	// CreateMissing creates the feature codes and release versions the
+	// product does not have yet instead of rejecting them.
+	CreateMissing   bool               `json:"create_missing"`
+}
+
+type suspendLicenseRequest struct {
+	Reason   string     `json:"reason" binding:"required"`
+	Until    *time.Time `json:"until"`
+	Duration string     `json:"duration"`
+}
+
+type renewLicenseRequest struct {
+	Duration string `json:"duration"`
 }
 
 type updateLicenseRequest struct {
+	// PlanID moves the license to another plan, or off its plan when empty.
+	PlanID          *string            `json:"plan_id"`
 	Type            models.LicenseType `json:"type"`
 	ExpiresAt       *time.Time         `json:"expires_at"`
 	Duration        string             `json:"duration"`
@@ -48,26 +70,153 @@ type updateLicenseRequest struct {
 	OwnerID         *string              `json:"owner_id"`
 	AutoAllowedIP     *bool              `json:"auto_allowed_ip"`
 	AutoAllowedIPLimit *int              `json:"auto_allowed_ip_limit"`
+	CreateMissing   bool                 `json:"create_missing"`
+}
+
+// Headers carrying client telemetry on /check.
+const (
+	clientAppVersionHeader   = "X-Client-App-Version"
+	clientOSHeader           = "X-Client-OS"
+	clientHostnameHashHeader = "X-Client-Hostname-Hash"
+	clientSDKVersionHeader   = "X-Client-SDK-Version"
+)
+
+// maxClientMetadataLength bounds each client metadata field; longer values
+// are truncated.
+const maxClientMetadataLength = 128
+
+// Bounds of a POST /check body.
+const (
+	// MaxCheckBodyBytes is plenty for a key, the features, a fingerprint and
+	// the client metadata.
+	MaxCheckBodyBytes    = 16 << 10
+	maxCheckFeatures     = 100
+	maxFingerprintLength = 256
+)
+
+// errCheckBodyTooLarge is answered with 413.
+var errCheckBodyTooLarge = errors.New("Request body too large")
+
+type checkLicenseRequest struct {
+	Key         string                `json:"key"`
+	Version     string                `json:"version"`
+	Feature     string                `json:"feature"`
+	Features    []string              `json:"features"`
+	Fingerprint string                `json:"fingerprint"`
+	Client      models.ClientMetadata `json:"client"`
+}
+
+// featureCheckResult is the result of one of the features of a POST /check.
+type featureCheckResult struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+	// Value is the value of an enabled feature that has one, such as a quota.
+	Value interface{} `json:"value,omitempty"`
+}
+
+// parseCheckLicenseRequest reads a check from the query string and the
+// X-License-Key and X-Client-* headers and, for POST, from the JSON body.
+// Fields set in the body take precedence. Errors are meant for the client.
+func parseCheckLicenseRequest(c *gin.Context) (checkLicenseRequest, error) {
+	req := checkLicenseRequest{
+		Key:     c.GetHeader("X-License-Key"),
+		Version: c.Query("version"),
+		Feature: c.Query("feature"),
+		Client: models.ClientMetadata{
+			AppVersion:   c.GetHeader(clientAppVersionHeader),
+			OS:           c.GetHeader(clientOSHeader),
+			HostnameHash: c.GetHeader(clientHostnameHashHeader),
+			SDKVersion:   c.GetHeader(clientSDKVersionHeader),
+		},
+	}
+
+	if c.Request.Method == http.MethodPost {
+		// The body is cached as the check rate limiter reads the key first
+		var body checkLicenseRequest
+		if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil && !errors.Is(err, io.EOF) {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return req, errCheckBodyTooLarge
+			}
+			return req, errors.New("Invalid request body")
+		}
+		for dst, v := range map[*string]string{
+			&req.Key:                 body.Key,
+			&req.Version:             body.Version,
+			&req.Fingerprint:         body.Fingerprint,
+			&req.Feature:             body.Feature,
+			&req.Client.AppVersion:   body.Client.AppVersion,
+			&req.Client.OS:           body.Client.OS,
+			&req.Client.HostnameHash: body.Client.HostnameHash,
+			&req.Client.SDKVersion:   body.Client.SDKVersion,
+		} {
+			if v != "" {
+				*dst = v
+			}
+		}
+
+		seen := make(map[string]bool, len(body.Features))
+		for _, code := range body.Features {
+			if code == "" {
+				return req, errors.New("features must not contain empty codes")
+			}
+			if !seen[code] {
+				seen[code] = true
+				req.Features = append(req.Features, code)
+			}
+		}
+		if len(req.Features) > maxCheckFeatures {
+			return req, fmt.Errorf("At most %d features can be checked at once", maxCheckFeatures)
+		}
+		if len(req.Fingerprint) > maxFingerprintLength {
+			return req, fmt.Errorf("fingerprint must be at most %d bytes", maxFingerprintLength)
+		}
+	}
+
+	for _, field := range []*string{&req.Client.AppVersion, &req.Client.OS, &req.Client.HostnameHash, &req.Client.SDKVersion} {
+		*field = truncateString(strings.TrimSpace(*field), maxClientMetadataLength)
+	}
+	return req, nil
+}
+
+// truncateString cuts s to at most n bytes without splitting a character.
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
 }
 
-// CheckLicenseHandler handles GET /check
+// CheckLicenseHandler handles GET and POST /check
 func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.ProductStore, responseSigningPrivateKey string, logStore store.LogStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		key := c.GetHeader("X-License-Key")
-		
+		req, reqErr := parseCheckLicenseRequest(c)
+
 		logEntry := &models.LicenseCheckLog{
 			RequestPayload: map[string]interface{}{
-				"version": c.Query("version"),
-				"feature": c.Query("feature"),
+				"version": req.Version,
+				"feature": req.Feature,
 			},
-			LicenseKey: key,
+			LicenseKey: req.Key,
 			IPAddress: c.ClientIP(),
 			UserAgent: c.Request.UserAgent(),
+			Client:    req.Client,
 			CreatedAt: time.Now(),
 		}
-		
+		if req.Features != nil {
+			logEntry.RequestPayload["features"] = req.Features
+		}
+		if req.Fingerprint != "" {
+			logEntry.RequestPayload["fingerprint"] = req.Fingerprint
+		}
+		outcome := metrics.CheckOutcomeMissingKey
+
 		defer func() {
-			service.AsyncLogLicenseCheck(c.Request.Context(), logStore, logEntry, logEntry.StatusCode == http.StatusOK && (logEntry.ResponsePayload["valid"] == true), func() string {
+			metrics.LicenseChecksTotal.WithLabelValues(outcome).Inc()
+			service.AsyncLogLicenseCheck(c.Request.Context(), logStore, logEntry, logEntry.Valid(), func() string {
 				if r, ok := logEntry.ResponsePayload["reason"].(string); ok {
 					return r
 				}
@@ -75,13 +224,47 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 			}())
 		}()
 
-		key, ok := requireLicenseKey(c)
-		if !ok {
+		if reqErr != nil {
+			status := http.StatusBadRequest
+			if errors.Is(reqErr, errCheckBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			outcome = metrics.CheckOutcomeBadRequest
+			logEntry.StatusCode = status
+			logEntry.ResponsePayload = map[string]interface{}{"error": reqErr.Error()}
+			c.JSON(status, gin.H{"error": reqErr.Error()})
+			return
+		}
+
+		key := req.Key
+		if key == "" {
+			logEntry.StatusCode = http.StatusBadRequest
+			logEntry.ResponsePayload = map[string]interface{}{"error": "X-License-Key header is required"}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "X-License-Key header is required"})
 			return
 		}
 
 		license, err := licenseStore.GetLicenseByKey(c.Request.Context(), key)
+		if err != nil && errors.Is(err, store.ErrNotFound) {
+			// A key retired by rotation still resolves to its license so the
+			// check history stays attached, but it is never valid again.
+			if rotated, rErr := licenseStore.GetLicenseByRotatedKey(c.Request.Context(), key); rErr == nil {
+				logEntry.ProductID = &rotated.ProductID
+				logEntry.LicenseID = &rotated.ID
+
+				outcome = metrics.CheckOutcomeKeyRotated
+				response := gin.H{
+					"valid":  false,
+					"reason": "key rotated",
+				}
+				logEntry.StatusCode = http.StatusOK
+				logEntry.ResponsePayload = map[string]interface{}(response)
+				c.JSON(http.StatusOK, response)
+				return
+			}
+		}
 		if err != nil {
+			outcome = metrics.CheckOutcomeNotFound
 			logEntry.StatusCode = http.StatusNotFound
 			logEntry.ResponsePayload = map[string]interface{}{"error": "License not found"}
 			c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
@@ -93,14 +276,25 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 
 		valid := true
 		var reason string
+		var resumesAt *time.Time
+		outcome = metrics.CheckOutcomeValid
 
 		// Check expiration
 		if license.Status == models.LicenseStatusRevoked {
 			valid = false
 			reason = "License is revoked"
+			outcome = metrics.CheckOutcomeRevoked
+		} else if license.IsSuspended(time.Now()) {
+			// The suspension reason is internal; clients only learn that the
+			// hold is temporary and when it ends, if known.
+			valid = false
+			reason = "License is suspended"
+			resumesAt = license.SuspendedUntil
+			outcome = metrics.CheckOutcomeSuspended
 		} else if license.ExpiresAt != nil && license.ExpiresAt.Before(time.Now()) {
 			valid = false
 			reason = "License has expired"
+			outcome = metrics.CheckOutcomeExpired
 		}
 
 		// Check IP restrictions
@@ -110,6 +304,7 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 			if clientIP == nil {
 				valid = false
 				reason = "Unable to determine client IP for validation"
+				outcome = metrics.CheckOutcomeIPNotAllowed
 			} else {
 				ipAllowed := false
 
@@ -144,11 +339,13 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 				}
 
 				if !ipAllowed && license.AutoAllowedIP {
+					// The store rechecks the limit against the current row,
+					// which the license read here may lag behind
 					if len(license.AllowedIPs) < license.AutoAllowedIPLimit {
-						license.AllowedIPs = append(license.AllowedIPs, clientIPStr)
-						if err := licenseStore.UpdateLicense(c.Request.Context(), license); err != nil {
+						added, err := licenseStore.AddAutoAllowedIP(c.Request.Context(), license.Key, clientIPStr)
+						if err != nil {
 							slog.Error("Failed to auto-add IP to license", "error", err, "license_id", license.ID)
-						} else {
+						} else if added {
 							slog.Info("Auto-added IP to license", "license_id", license.ID, "ip", clientIPStr)
 							ipAllowed = true
 						}
@@ -158,12 +355,13 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 				if !ipAllowed {
 					valid = false
 					reason = "IP address not allowed"
+					outcome = metrics.CheckOutcomeIPNotAllowed
 				}
 			}
 		}
 
-		// Check version if query param is provided
-		version := c.Query("version")
+		// Check version if provided
+		version := req.Version
 		if version != "" && valid {
 			versionAllowed := false
 			if len(license.Releases) == 0 {
@@ -179,11 +377,12 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 			if !versionAllowed {
 				valid = false
 				reason = "License not valid for version " + version
+				outcome = metrics.CheckOutcomeVersion
 			}
 		}
 
-		// Check feature if query param is provided
-		feature := c.Query("feature")
+		// Check feature if provided
+		feature := req.Feature
 		if feature != "" && valid {
 			featureAllowed := false
 			for _, code := range license.Features {
@@ -195,6 +394,29 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 			if !featureAllowed {
 				valid = false
 				reason = "Feature not enabled: " + feature
+				outcome = metrics.CheckOutcomeFeature
+			}
+		}
+
+		// Unlike feature, each of features gets its own result and does not
+		// fail the check
+		var featureResults map[string]featureCheckResult
+		var enabledFeatures map[string]bool
+		if req.Features != nil {
+			featureResults = make(map[string]featureCheckResult, len(req.Features))
+			enabledFeatures = make(map[string]bool, len(req.Features))
+			for _, code := range req.Features {
+				result := featureCheckResult{}
+				if valid {
+					result.Enabled = slices.Contains(license.Features, code)
+					if !result.Enabled {
+						result.Reason = "Feature not enabled: " + code
+					} else {
+						result.Value = license.FeatureValues[code]
+					}
+				}
+				featureResults[code] = result
+				enabledFeatures[code] = result.Enabled
 			}
 		}
 
@@ -205,10 +427,19 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 		if reason != "" {
 			response["reason"] = reason
 		}
+		if resumesAt != nil {
+			response["resumes_at"] = resumesAt
+		}
+		if featureResults != nil {
+			response["features"] = featureResults
+		}
+		if req.Fingerprint != "" {
+			response["fingerprint"] = req.Fingerprint
+		}
 
 		if responseSigningPrivateKey != "" {
 			// Generate signed response token (JWT)
-			token, err := service.SignLicense(responseSigningPrivateKey, key, license.ExpiresAt, valid, license.Features)
+			token, err := service.SignCheckResult(responseSigningPrivateKey, key, license.ExpiresAt, valid, license.Features, enabledFeatures, req.Fingerprint)
 			if err != nil {
 				slog.Error("Failed to generate response signing token", "error", err, "key", key)
 			} else {
@@ -223,7 +454,8 @@ func CheckLicenseHandler(licenseStore store.LicenseStore, productStore store.Pro
 }
 
 // GenerateLicenseHandler handles POST /admin/keys
-func GenerateLicenseHandler(licenseStore store.LicenseStore, productStore store.ProductStore, productGroupStore store.ProductGroupStore, logStore store.LogStore) gin.HandlerFunc {
+func GenerateLicenseHandler(licenseStore store.LicenseStore, productStore store.ProductStore, productGroupStore store.ProductGroupStore, planStore store.PlanStore, logStore store.LogStore) gin.HandlerFunc {
+	resolver := service.NewLicensePolicyResolver(productStore, productGroupStore)
 	return func(c *gin.Context) {
 		var req generateLicenseRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -238,121 +470,97 @@ func GenerateLicenseHandler(licenseStore store.LicenseStore, productStore store.
 			return
 		}
 
-		var expiresAt *time.Time
-		if req.ExpiresAt != nil {
-			expiresAt = req.ExpiresAt
-		} else if req.Duration != "" {
-			exp, err := ParseExpirationDuration(req.Duration)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid duration format: %v", err)})
+		var plan *models.Plan
+		if req.PlanID != "" {
+			if req.FeatureCodes != nil || req.ReleaseVersions != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Licenses on a plan get feature_codes and release_versions from the plan"})
+				return
+			}
+			var ok bool
+			if plan, ok = getLicensePlan(c, planStore, req.PlanID); !ok {
 				return
 			}
-			expiresAt = &exp
 		}
 
-		product, err := productStore.GetProduct(c.Request.Context(), req.ProductID)
+		policy, err := resolver.ResolvePlan(c.Request.Context(), req.ProductID, plan, req.policyOverrides())
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product_id or product not found"})
+			if errors.Is(err, service.ErrProductNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product_id or product not found"})
+				return
+			}
+			if errors.Is(err, service.ErrPlanNotCovered) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Plan belongs to another product or product group"})
+				return
+			}
+			slog.Error("Failed to resolve license policy", "error", err, "product_id", req.ProductID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve license policy"})
 			return
 		}
 
-		// Resolve settings with inheritance from ProductGroup
-		prefix := product.LicensePrefix
-		separator := product.LicenseSeparator
-		charsetRaw := product.LicenseCharset
-		length := req.Length
-		if length == 0 {
-			length = product.LicenseLength
-		}
-
-		autoAllowedIP := product.AutoAllowedIP
-		autoAllowedIPLimit := product.AutoAllowedIPLimit
-	
-		// If product belongs to a group, inherit missing settings
-		if product.ProductGroupID != nil {
-			group, err := productGroupStore.GetProductGroup(c.Request.Context(), product.ProductGroupID.String())
-			if err == nil {
-				if prefix == "" {
-					prefix = group.LicensePrefix
-				}
-				if separator == "" || separator == "-" {
-					if group.LicenseSeparator != "" {
-						separator = group.LicenseSeparator
-					}
-				}
-				if charsetRaw == "" {
-					charsetRaw = group.LicenseCharset
-				}
-				if length == 0 {
-					length = group.LicenseLength
-				}
-				if !autoAllowedIP {
-					autoAllowedIP = group.AutoAllowedIP
-				}
-				if autoAllowedIPLimit == 0 {
-					autoAllowedIPLimit = group.AutoAllowedIPLimit
-				}
+		var expiresAt *time.Time
+		if req.ExpiresAt != nil {
+			expiresAt = req.ExpiresAt
+		} else if duration := licenseDuration(policy); duration != "" {
+			exp, err := ParseExpirationDuration(duration)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid duration format: %v", err)})
+				return
 			}
+			expiresAt = &exp
 		}
-
-		// Override with Request values
-		if req.AutoAllowedIP != nil {
-			autoAllowedIP = *req.AutoAllowedIP
-		}
-		if req.AutoAllowedIPLimit != nil {
-			autoAllowedIPLimit = *req.AutoAllowedIPLimit
-		}
-
-		if length <= 0 {
-			length = 12 // Default length
-		}
-
-		if prefix == "" {
-			prefix = "LICENSE"
-		}
-		if req.Prefix != "" {
-			prefix = req.Prefix
-		}
-
-		parsedCharset, err := service.ParseCharset(charsetRaw)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid charset configuration: %v", err)})
+		if err := validateLicensePolicy(policy, expiresAt, time.Now()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		key, err := service.GenerateLicenseKey(prefix, length, separator, parsedCharset)
+		key, err := generateLicenseKey(c, policy)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate license key"})
 			return
 		}
 
-		productID := product.ID
+		productID := policy.ProductID
 
 		license := &models.License{
 			ID:              uuid.New(),
 			Key:             key,
 			OwnerID:         req.OwnerID,
-			Type:            req.Type,
+			Type:            policy.LicenseType,
 			ProductID:       productID,
 			ExpiresAt:       expiresAt,
 			AllowedIPs:      req.AllowedIPs,
 			AllowedNetworks: req.AllowedNetworks,
 			Status:          models.LicenseStatusActive,
-			AutoAllowedIP:     autoAllowedIP,
-			AutoAllowedIPLimit: autoAllowedIPLimit,
+			AutoAllowedIP:     policy.AutoAllowedIP,
+			AutoAllowedIPLimit: policy.AutoAllowedIPLimit,
 			CreatedAt:       time.Now(),
 			UpdatedAt:       time.Now(),
 		}
 
-		if len(req.FeatureCodes) > 0 {
-			license.Features = req.FeatureCodes
+		if len(policy.DefaultFeatures) > 0 {
+			license.Features = policy.DefaultFeatures
+		}
+
+		if len(policy.DefaultReleases) > 0 {
+			license.Releases = policy.DefaultReleases
 		}
 
-		if len(req.ReleaseVersions) > 0 {
-			license.Releases = req.ReleaseVersions
+		if plan != nil {
+			license.PlanID = &plan.ID
+			license.FeatureValues = plan.Features
 		}
 
-		if err := licenseStore.CreateLicense(c.Request.Context(), license); err != nil {
+		var created map[string]interface{}
+		if req.CreateMissing {
+			var features, releases []string
+			features, releases, err = licenseStore.CreateLicenseWithMissingCatalog(c.Request.Context(), license)
+			created = createdCatalogDetails(license, features, releases)
+		} else {
+			err = licenseStore.CreateLicense(c.Request.Context(), license)
+		}
+		if err != nil {
+			if respondUnknownCatalog(c, err) {
+				return
+			}
 			slog.Error("Failed to create license", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save license"})
 			return
@@ -363,6 +571,9 @@ func GenerateLicenseHandler(licenseStore store.LicenseStore, productStore store.
 		details := map[string]interface{}(nil)
 		dt, _ := json.Marshal(license)
 		json.Unmarshal(dt, &details)
+		for k, v := range created {
+			details[k] = v
+		}
 
 		logEntry := &models.AdminLog{
 			Action:     "GENERATE_LICENSE",
@@ -372,7 +583,7 @@ func GenerateLicenseHandler(licenseStore store.LicenseStore, productStore store.
 			Details:    details,
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusCreated, license)
 	}
@@ -416,7 +627,7 @@ func RevokeLicenseHandler(licenseStore store.LicenseStore, logStore store.LogSto
 			Details:    map[string]interface{}{"key": key},
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, gin.H{"message": "License revoked"})
 	}
@@ -466,14 +677,14 @@ func DeleteLicenseHandler(licenseStore store.LicenseStore, logStore store.LogSto
 			Details:    map[string]interface{}{"key": key},
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, gin.H{"message": "License deleted permanently"})
 	}
 }
 
 // UpdateLicenseHandler handles PUT /admin/keys
-func UpdateLicenseHandler(licenseStore store.LicenseStore, logStore store.LogStore) gin.HandlerFunc {
+func UpdateLicenseHandler(licenseStore store.LicenseStore, productStore store.ProductStore, planStore store.PlanStore, logStore store.LogStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		key, ok := requireLicenseKey(c)
 		if !ok {
@@ -491,6 +702,36 @@ func UpdateLicenseHandler(licenseStore store.LicenseStore, logStore store.LogSto
 			c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
 			return
 		}
+		before := *existing
+
+		onPlan := existing.PlanID != nil
+		if req.PlanID != nil {
+			onPlan = *req.PlanID != ""
+		}
+		if onPlan && (req.FeatureCodes != nil || req.ReleaseVersions != nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Licenses on a plan get feature_codes and release_versions from the plan; set plan_id to \"\" to take it off its plan"})
+			return
+		}
+		if req.PlanID != nil && *req.PlanID == "" {
+			// Off the plan the license keeps what the plan gave it
+			existing.PlanID = nil
+		} else if req.PlanID != nil {
+			plan, ok := getLicensePlan(c, planStore, *req.PlanID)
+			if !ok {
+				return
+			}
+			product, err := productStore.GetProduct(c.Request.Context(), existing.ProductID.String())
+			if err != nil {
+				slog.Error("Failed to get product", "error", err, "product_id", existing.ProductID)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update license"})
+				return
+			}
+			if !plan.Covers(product) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Plan belongs to another product or product group"})
+				return
+			}
+			plan.ApplyTo(existing)
+		}
 
 		if req.Type != "" {
 			existing.Type = req.Type
@@ -524,6 +765,10 @@ func UpdateLicenseHandler(licenseStore store.LicenseStore, logStore store.LogSto
 
 		if req.Status != "" {
 			existing.Status = req.Status
+			if req.Status != models.LicenseStatusSuspended {
+				existing.SuspensionReason = nil
+				existing.SuspendedUntil = nil
+			}
 		}
 
 		if req.AutoAllowedIP != nil {
@@ -536,7 +781,18 @@ func UpdateLicenseHandler(licenseStore store.LicenseStore, logStore store.LogSto
 
 		existing.UpdatedAt = time.Now()
 
-		if err := licenseStore.UpdateLicense(c.Request.Context(), existing); err != nil {
+		var created map[string]interface{}
+		if req.CreateMissing {
+			var features, releases []string
+			features, releases, err = licenseStore.UpdateLicenseWithMissingCatalog(c.Request.Context(), existing)
+			created = createdCatalogDetails(existing, features, releases)
+		} else {
+			err = licenseStore.UpdateLicense(c.Request.Context(), existing)
+		}
+		if err != nil {
+			if respondUnknownCatalog(c, err) {
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update license"})
 			return
 		}
@@ -544,24 +800,65 @@ func UpdateLicenseHandler(licenseStore store.LicenseStore, logStore store.LogSto
 		details := map[string]interface{}(nil)
 		dt, _ := json.Marshal(req)
 		json.Unmarshal(dt, &details)
+		for k, v := range created {
+			details[k] = v
+		}
 		logEntry := &models.AdminLog{
 			Action:     "UPDATE_LICENSE",
 			EntityType: "LICENSE",
 			EntityID:   &existing.ID,
 			OwnerID:    req.OwnerID,
 			Details:    details,
+			Changes:    auditChanges(before, existing),
 			CreatedAt:  time.Now(),
 		}
 		if existing.ID != uuid.Nil {
 			logEntry.EntityID = &existing.ID
 		}
 
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, existing)
 	}
 }
 
+// createdCatalogDetails logs the features and releases created for license
+// and returns them keyed for the admin log details.
+func createdCatalogDetails(license *models.License, features, releases []string) map[string]interface{} {
+	created := map[string]interface{}{}
+	if len(features) > 0 {
+		slog.Info("Created missing features", "product_id", license.ProductID, "codes", features)
+		created["created_features"] = features
+	}
+	if len(releases) > 0 {
+		slog.Info("Created missing releases", "product_id", license.ProductID, "versions", releases)
+		created["created_releases"] = releases
+	}
+	return created
+}
+
+// respondUnknownCatalog answers 422 listing the unknown feature codes and
+// release versions when err is a *store.UnknownCatalogError.
+func respondUnknownCatalog(c *gin.Context, err error) bool {
+	var unknown *store.UnknownCatalogError
+	if !errors.As(err, &unknown) {
+		return false
+	}
+	unknownFeatures, unknownReleases := unknown.FeatureCodes, unknown.ReleaseVersions
+	if unknownFeatures == nil {
+		unknownFeatures = []string{}
+	}
+	if unknownReleases == nil {
+		unknownReleases = []string{}
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":                    "Unknown feature codes or release versions",
+		"unknown_feature_codes":    unknownFeatures,
+		"unknown_release_versions": unknownReleases,
+	})
+	return true
+}
+
 // GetLicenseHandler handles GET /admin/keys
 func GetLicenseHandler(licenseStore store.LicenseStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -621,6 +918,413 @@ func GetLicenseHandler(licenseStore store.LicenseStore) gin.HandlerFunc {
 	}
 }
 
+// RotateLicenseKeyHandler handles POST /admin/keys/rotate
+func RotateLicenseKeyHandler(licenseStore store.LicenseStore, productStore store.ProductStore, productGroupStore store.ProductGroupStore, logStore store.LogStore) gin.HandlerFunc {
+	resolver := service.NewLicensePolicyResolver(productStore, productGroupStore)
+	return func(c *gin.Context) {
+		oldKey, ok := requireLicenseKey(c)
+		if !ok {
+			return
+		}
+
+		slog.Info("Rotating license key", "key", oldKey)
+
+		license, err := licenseStore.GetLicenseByKey(c.Request.Context(), oldKey)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
+				return
+			}
+			slog.Error("Failed to get license for rotation", "error", err, "key", oldKey)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate license key"})
+			return
+		}
+
+		policy, err := resolver.Resolve(c.Request.Context(), license.ProductID.String(), models.LicensePolicyOverrides{})
+		if err != nil {
+			slog.Error("Failed to resolve license policy for key rotation", "error", err, "product_id", license.ProductID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate license key"})
+			return
+		}
+
+		newKey, err := generateLicenseKey(c, policy)
+		if err != nil {
+			return
+		}
+
+		if err := licenseStore.RotateLicenseKey(c.Request.Context(), oldKey, newKey); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
+				return
+			}
+			slog.Error("Failed to rotate license key", "error", err, "key", oldKey)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate license key"})
+			return
+		}
+
+		license.Key = newKey
+		license.UpdatedAt = time.Now()
+
+		slog.Info("License key rotated", "license_id", license.ID)
+
+		logEntry := &models.AdminLog{
+			Action:     "ROTATE_LICENSE_KEY",
+			EntityType: "LICENSE",
+			EntityID:   &license.ID,
+			OwnerID:    license.OwnerID,
+			Details: map[string]interface{}{
+				"old_key": oldKey,
+				"new_key": newKey,
+			},
+			CreatedAt: time.Now(),
+		}
+		logAdminAction(c, logStore, logEntry)
+
+		c.JSON(http.StatusOK, license)
+	}
+}
+
+// RenewLicenseHandler handles POST /admin/keys/renew. The license is
+// extended from its expiry, or from now if it has expired, by the requested
+// duration or else the license_duration of its plan or product.
+func RenewLicenseHandler(licenseStore store.LicenseStore, productStore store.ProductStore, productGroupStore store.ProductGroupStore, planStore store.PlanStore, logStore store.LogStore) gin.HandlerFunc {
+	resolver := service.NewLicensePolicyResolver(productStore, productGroupStore)
+	return func(c *gin.Context) {
+		key, ok := requireLicenseKey(c)
+		if !ok {
+			return
+		}
+
+		var req renewLicenseRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		license, err := licenseStore.GetLicenseByKey(c.Request.Context(), key)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
+				return
+			}
+			slog.Error("Failed to get license for renewal", "error", err, "key", key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to renew license"})
+			return
+		}
+		if license.Status == models.LicenseStatusRevoked {
+			c.JSON(http.StatusConflict, gin.H{"error": "Revoked licenses cannot be renewed"})
+			return
+		}
+		if license.Type == models.LicenseTypePerpetual {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Perpetual licenses do not expire"})
+			return
+		}
+
+		var overrides models.LicensePolicyOverrides
+		if req.Duration != "" {
+			overrides.LicenseDuration = &req.Duration
+		}
+		var plan *models.Plan
+		if license.PlanID != nil {
+			if plan, err = planStore.GetPlan(c.Request.Context(), license.PlanID.String()); err != nil {
+				slog.Error("Failed to get license plan for renewal", "error", err, "plan_id", license.PlanID)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to renew license"})
+				return
+			}
+		}
+		policy, err := resolver.ResolvePlan(c.Request.Context(), license.ProductID.String(), plan, overrides)
+		if err != nil {
+			if errors.Is(err, service.ErrPlanNotCovered) {
+				c.JSON(http.StatusConflict, gin.H{"error": "License plan belongs to another product or product group"})
+				return
+			}
+			slog.Error("Failed to resolve license policy for renewal", "error", err, "product_id", license.ProductID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to renew license"})
+			return
+		}
+		if policy.LicenseDuration == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration is required when neither the plan nor the product sets a license_duration"})
+			return
+		}
+
+		now := time.Now()
+		from := now
+		if license.ExpiresAt != nil && license.ExpiresAt.After(now) {
+			from = *license.ExpiresAt
+		}
+		expiresAt, err := addExpirationDuration(from, policy.LicenseDuration)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid duration format: %v", err)})
+			return
+		}
+
+		before := *license
+		license.ExpiresAt = &expiresAt
+		if license.Status == models.LicenseStatusExpired {
+			license.Status = models.LicenseStatusActive
+		}
+		license.UpdatedAt = now
+
+		if err := licenseStore.UpdateLicense(c.Request.Context(), license); err != nil {
+			slog.Error("Failed to renew license", "error", err, "key", key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to renew license"})
+			return
+		}
+
+		slog.Info("License renewed", "key", key, "expires_at", expiresAt)
+
+		logEntry := &models.AdminLog{
+			Action:     "RENEW_LICENSE",
+			EntityType: "LICENSE",
+			EntityID:   &license.ID,
+			OwnerID:    license.OwnerID,
+			Details: map[string]interface{}{
+				"key":             key,
+				"duration":        policy.LicenseDuration,
+				"duration_source": policy.Sources["license_duration"],
+			},
+			Changes:   auditChanges(before, license),
+			CreatedAt: now,
+		}
+		logAdminAction(c, logStore, logEntry)
+
+		c.JSON(http.StatusOK, license)
+	}
+}
+
+// SuspendLicenseHandler handles POST /admin/keys/suspend
+func SuspendLicenseHandler(licenseStore store.LicenseStore, logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := requireLicenseKey(c)
+		if !ok {
+			return
+		}
+
+		var req suspendLicenseRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Until != nil && req.Duration != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot specify both until and duration"})
+			return
+		}
+
+		until := req.Until
+		if req.Duration != "" {
+			t, err := ParseExpirationDuration(req.Duration)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid duration format: %v", err)})
+				return
+			}
+			until = &t
+		}
+		if until != nil && !until.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+			return
+		}
+
+		license, err := licenseStore.GetLicenseByKey(c.Request.Context(), key)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
+				return
+			}
+			slog.Error("Failed to get license for suspension", "error", err, "key", key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend license"})
+			return
+		}
+
+		// Suspending a suspended license replaces its reason and resume time
+		if license.Status != models.LicenseStatusActive && license.Status != models.LicenseStatusSuspended {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cannot suspend a %s license", license.Status)})
+			return
+		}
+
+		previous := license.Status
+		license.Status = models.LicenseStatusSuspended
+		license.SuspensionReason = &req.Reason
+		license.SuspendedUntil = until
+		license.UpdatedAt = time.Now()
+		if err := licenseStore.UpdateLicense(c.Request.Context(), license); err != nil {
+			slog.Error("Failed to suspend license", "error", err, "key", key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend license"})
+			return
+		}
+
+		slog.Info("License suspended", "key", key, "until", until)
+
+		logAdminAction(c, logStore, &models.AdminLog{
+			Action:     "SUSPEND_LICENSE",
+			EntityType: "LICENSE",
+			EntityID:   &license.ID,
+			OwnerID:    license.OwnerID,
+			Details: map[string]interface{}{
+				"key":             key,
+				"reason":          req.Reason,
+				"suspended_until": until,
+				"previous_status": previous,
+			},
+			CreatedAt: time.Now(),
+		})
+
+		c.JSON(http.StatusOK, license)
+	}
+}
+
+// ResumeLicenseHandler handles POST /admin/keys/resume
+func ResumeLicenseHandler(licenseStore store.LicenseStore, logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := requireLicenseKey(c)
+		if !ok {
+			return
+		}
+
+		license, err := licenseStore.GetLicenseByKey(c.Request.Context(), key)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
+				return
+			}
+			slog.Error("Failed to get license for resumption", "error", err, "key", key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume license"})
+			return
+		}
+
+		if license.Status != models.LicenseStatusSuspended {
+			c.JSON(http.StatusConflict, gin.H{"error": "License is not suspended"})
+			return
+		}
+
+		reason := license.SuspensionReason
+		license.Status = models.LicenseStatusActive
+		license.SuspensionReason = nil
+		license.SuspendedUntil = nil
+		license.UpdatedAt = time.Now()
+		if err := licenseStore.UpdateLicense(c.Request.Context(), license); err != nil {
+			slog.Error("Failed to resume license", "error", err, "key", key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume license"})
+			return
+		}
+
+		slog.Info("License resumed", "key", key)
+
+		logAdminAction(c, logStore, &models.AdminLog{
+			Action:     "RESUME_LICENSE",
+			EntityType: "LICENSE",
+			EntityID:   &license.ID,
+			OwnerID:    license.OwnerID,
+			Details: map[string]interface{}{
+				"key":               key,
+				"suspension_reason": reason,
+				"automatic":         false,
+			},
+			CreatedAt: time.Now(),
+		})
+
+		c.JSON(http.StatusOK, license)
+	}
+}
+
+// policyOverrides returns the license settings set by the request.
+func (r generateLicenseRequest) policyOverrides() models.LicensePolicyOverrides {
+	o := models.LicensePolicyOverrides{
+		AutoAllowedIP:      r.AutoAllowedIP,
+		AutoAllowedIPLimit: r.AutoAllowedIPLimit,
+		DefaultFeatures:    r.FeatureCodes,
+		DefaultReleases:    r.ReleaseVersions,
+	}
+	if r.Prefix != "" {
+		o.LicensePrefix = &r.Prefix
+	}
+	if r.Length != 0 {
+		o.LicenseLength = &r.Length
+	}
+	if r.Type != "" {
+		o.LicenseType = &r.Type
+	}
+	if r.Duration != "" {
+		o.LicenseDuration = &r.Duration
+	}
+	return o
+}
+
+// licenseDuration returns the duration a license of policy is valid for.
+// Perpetual licenses do not inherit a duration; they only expire when the
+// request asks for it.
+func licenseDuration(policy *models.EffectiveLicensePolicy) string {
+	if policy.LicenseType == models.LicenseTypePerpetual && policy.Sources["license_duration"] != models.PolicySourceRequest {
+		return ""
+	}
+	return policy.LicenseDuration
+}
+
+// validateLicensePolicy rejects licenses the policy cannot issue: unknown
+// types, perpetual licenses that expire, timed and trial licenses that do
+// not, expiry in the past and negative key lengths or IP limits.
+func validateLicensePolicy(policy *models.EffectiveLicensePolicy, expiresAt *time.Time, now time.Time) error {
+	switch policy.LicenseType {
+	case "":
+		return fmt.Errorf("type is required when the product sets no license_type")
+	case models.LicenseTypePerpetual:
+		if expiresAt != nil {
+			return fmt.Errorf("perpetual licenses cannot have expires_at or duration")
+		}
+	case models.LicenseTypeTimed, models.LicenseTypeTrial:
+		if expiresAt == nil {
+			return fmt.Errorf("%s licenses require expires_at, duration or a license_duration on the product", policy.LicenseType)
+		}
+	default:
+		return fmt.Errorf("type must be %q, %q or %q", models.LicenseTypePerpetual, models.LicenseTypeTimed, models.LicenseTypeTrial)
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	if policy.LicenseLength <= 0 {
+		return fmt.Errorf("length must be positive")
+	}
+	if policy.AutoAllowedIPLimit < 0 {
+		return fmt.Errorf("auto_allowed_ip_limit must not be negative")
+	}
+	return nil
+}
+
+// generateLicenseKey generates a key in the format of policy. On failure it
+// writes the error response.
+func generateLicenseKey(c *gin.Context, policy *models.EffectiveLicensePolicy) (string, error) {
+	charset, err := service.ParseCharset(policy.LicenseCharset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid charset configuration: %v", err)})
+		return "", err
+	}
+
+	key, err := service.GenerateLicenseKey(policy.LicensePrefix, policy.LicenseLength, policy.LicenseSeparator, charset)
+	if err != nil {
+		slog.Error("Failed to generate license key", "error", err, "product_id", policy.ProductID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate license key"})
+		return "", err
+	}
+	return key, nil
+}
+
+// getLicensePlan reads the plan a license is put on. On failure it writes
+// the error response.
+func getLicensePlan(c *gin.Context, planStore store.PlanStore, id string) (*models.Plan, bool) {
+	plan, err := planStore.GetPlan(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan_id or plan not found"})
+			return nil, false
+		}
+		slog.Error("Failed to get plan", "error", err, "plan_id", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get plan"})
+		return nil, false
+	}
+	return plan, true
+}
+
 func requireLicenseKey(c *gin.Context) (string, bool) {
 	key := c.GetHeader("X-License-Key")
 	if key == "" {
@@ -629,3 +1333,39 @@ func requireLicenseKey(c *gin.Context) (string, bool) {
 	}
 	return key, true
 }
+
+// ListFlaggedLicensesHandler handles GET /admin/keys/flagged
+func ListFlaggedLicensesHandler(flagStore store.FlagStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ownerID *string
+		if idStr := c.Query("owner_id"); idStr != "" {
+			ownerID = &idStr
+		}
+
+		pagination := ParsePaginationParams(c)
+
+		flags, totalCount, err := flagStore.ListLicenseFlags(c.Request.Context(), ownerID, pagination)
+		if err != nil {
+			slog.Error("Failed to list flagged licenses", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list flagged licenses"})
+			return
+		}
+
+		if flags == nil {
+			flags = []models.LicenseFlag{}
+		}
+
+		totalPages := 0
+		if pagination.Limit > 0 {
+			totalPages = (totalCount + pagination.Limit - 1) / pagination.Limit
+		}
+
+		c.JSON(http.StatusOK, models.PaginatedList[models.LicenseFlag]{
+			Items:      flags,
+			TotalCount: totalCount,
+			Page:       pagination.Page,
+			Limit:      pagination.Limit,
+			TotalPages: totalPages,
+		})
+	}
+}
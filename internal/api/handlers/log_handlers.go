@@ -2,118 +2,66 @@ package handlers
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"strconv"
 
+	"clortho/internal/api/middleware"
 	"clortho/internal/models"
+	"clortho/internal/service"
 	"clortho/internal/store"
 )
 
+// GetLicenseCheckLogsHandler lists the license check logs matching any
+// combination of filters, newest first. Pages are chained with the
+// next_cursor of the previous one.
 func GetLicenseCheckLogsHandler(logStore store.LogStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 		defer cancel()
 
-		licenseKey := c.Query("license_key")
-		productID := c.Query("product_id")
-		productGroupID := c.Query("product_group_id")
-		statusCodeStr := c.Query("status_code")
+		filter, ok := parseLicenseCheckLogFilter(c)
+		if !ok {
+			return
+		}
 
-		var statusCode *int
-		if statusCodeStr != "" {
-			code, err := strconv.Atoi(statusCodeStr)
+		var after *models.LogPageCursor
+		if cursorStr := c.Query("cursor"); cursorStr != "" {
+			cursor, err := models.ParseLogPageCursor(cursorStr)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status_code parameter"})
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
 				return
 			}
-			statusCode = &code
+			after = cursor
 		}
 
 		pagination := ParsePaginationParams(c)
 
-		if licenseKey != "" {
-			logs, totalCount, err := logStore.GetLicenseCheckLogsByLicenseKey(ctx, licenseKey, statusCode, pagination)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch license check logs"})
-				return
-			}
-			
-			if logs == nil {
-				logs = []models.LicenseCheckLog{}
-			}
-
-			totalPages := 0
-			if pagination.Limit > 0 {
-				totalPages = (totalCount + pagination.Limit - 1) / pagination.Limit
-			}
-
-			c.JSON(http.StatusOK, models.PaginatedList[models.LicenseCheckLog]{
-				Items:      logs,
-				TotalCount: totalCount,
-				Page:       pagination.Page,
-				Limit:      pagination.Limit,
-				TotalPages: totalPages,
-			})
+		logs, next, err := logStore.QueryLicenseCheckLogs(ctx, filter, after, pagination.Limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch license check logs"})
 			return
 		}
 
-		if productID != "" {
-			logs, totalCount, err := logStore.GetLicenseCheckLogsByProductID(ctx, productID, statusCode, pagination)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch license check logs"})
-				return
-			}
-			
-			if logs == nil {
-				logs = []models.LicenseCheckLog{}
-			}
-
-			totalPages := 0
-			if pagination.Limit > 0 {
-				totalPages = (totalCount + pagination.Limit - 1) / pagination.Limit
-			}
-
-			c.JSON(http.StatusOK, models.PaginatedList[models.LicenseCheckLog]{
-				Items:      logs,
-				TotalCount: totalCount,
-				Page:       pagination.Page,
-				Limit:      pagination.Limit,
-				TotalPages: totalPages,
-			})
-			return
+		if logs == nil {
+			logs = []models.LicenseCheckLog{}
 		}
 
-		if productGroupID != "" {
-			logs, totalCount, err := logStore.GetLicenseCheckLogsByProductGroupID(ctx, productGroupID, statusCode, pagination)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch license check logs"})
-				return
-			}
-			
-			if logs == nil {
-				logs = []models.LicenseCheckLog{}
-			}
-
-			totalPages := 0
-			if pagination.Limit > 0 {
-				totalPages = (totalCount + pagination.Limit - 1) / pagination.Limit
-			}
-
-			c.JSON(http.StatusOK, models.PaginatedList[models.LicenseCheckLog]{
-				Items:      logs,
-				TotalCount: totalCount,
-				Page:       pagination.Page,
-				Limit:      pagination.Limit,
-				TotalPages: totalPages,
-			})
-			return
+		page := models.LogPage[models.LicenseCheckLog]{Items: logs, Limit: pagination.Limit}
+		if next != nil {
+			page.NextCursor = next.String()
 		}
-
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing filter parameter (license_key, product_id, or product_group_id)"})
+		c.JSON(http.StatusOK, page)
 	}
 }
 
@@ -122,14 +70,14 @@ func GetAdminLogsHandler(logStore store.LogStore) gin.HandlerFunc {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 		defer cancel()
 
-		var ownerID *string
-		if idStr := c.Query("owner_id"); idStr != "" {
-			ownerID = &idStr
+		filter, ok := parseAdminLogFilter(c)
+		if !ok {
+			return
 		}
 
 		pagination := ParsePaginationParams(c)
 
-		logs, totalCount, err := logStore.ListAdminLogs(ctx, ownerID, pagination)
+		logs, totalCount, err := logStore.ListAdminLogs(ctx, filter, pagination)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch admin logs"})
 			return
@@ -153,3 +101,506 @@ func GetAdminLogsHandler(logStore store.LogStore) gin.HandlerFunc {
 		})
 	}
 }
+
+// parseLicenseCheckLogFilter reads the license check log filters from the
+// query. It answers 400 and returns false when one is invalid.
+func parseLicenseCheckLogFilter(c *gin.Context) (models.LicenseCheckLogFilter, bool) {
+	filter := models.LicenseCheckLogFilter{
+		LicenseKey:       c.Query("license_key"),
+		ProductID:        c.Query("product_id"),
+		ProductGroupID:   c.Query("product_group_id"),
+		OwnerID:          c.Query("owner_id"),
+		IPAddress:        c.Query("ip"),
+		UserAgent:        c.Query("user_agent"),
+		Reason:           c.Query("reason"),
+		ClientAppVersion: c.Query("app_version"),
+		ClientOS:         c.Query("os"),
+		ClientSDKVersion: c.Query("sdk_version"),
+	}
+	for param, v := range map[string]string{"product_id": filter.ProductID, "product_group_id": filter.ProductGroupID} {
+		if _, err := uuid.Parse(v); v != "" && err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " parameter"})
+			return filter, false
+		}
+	}
+	if filter.IPAddress != "" {
+		if _, _, err := net.ParseCIDR(filter.IPAddress); err != nil && net.ParseIP(filter.IPAddress) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ip parameter, expected an address or a CIDR network"})
+			return filter, false
+		}
+	}
+	if statusCodeStr := c.Query("status_code"); statusCodeStr != "" {
+		code, err := strconv.Atoi(statusCodeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status_code parameter"})
+			return filter, false
+		}
+		filter.StatusCode = &code
+	}
+	if validStr := c.Query("valid"); validStr != "" {
+		valid, err := strconv.ParseBool(validStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid valid parameter"})
+			return filter, false
+		}
+		filter.Valid = &valid
+	}
+	return filter, parseTimeRange(c, &filter.Since, &filter.Until)
+}
+
+// parseAdminLogFilter reads the admin log filters from the query. It answers
+// 400 and returns false when one is invalid.
+func parseAdminLogFilter(c *gin.Context) (models.AdminLogFilter, bool) {
+	filter := models.AdminLogFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+	}
+	if idStr := c.Query("owner_id"); idStr != "" {
+		filter.OwnerID = &idStr
+	}
+	if idStr := c.Query("entity_id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity_id parameter"})
+			return filter, false
+		}
+		filter.EntityID = &id
+	}
+	return filter, parseTimeRange(c, &filter.Since, &filter.Until)
+}
+
+// parseTimeRange reads the since and until query parameters (RFC 3339). It
+// answers 400 and returns false when one is invalid.
+func parseTimeRange(c *gin.Context, since, until **time.Time) bool {
+	for _, p := range []struct {
+		param string
+		dst   **time.Time
+	}{{"since", since}, {"until", until}} {
+		if v := c.Query(p.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + p.param + ", expected RFC 3339"})
+				return false
+			}
+			*p.dst = &t
+		}
+	}
+	return true
+}
+
+// VerifyAdminLogChainHandler walks the admin log hash chain and reports the
+// first broken link. Checkpoints are verified with the response signing key.
+func VerifyAdminLogChainHandler(chainStore store.AdminLogChainStore, publicKeyBase64 string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		publicKey, err := service.ParseVerifyingKey(publicKeyBase64)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Response signing key is not configured"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+		defer cancel()
+
+		report, err := service.VerifyAdminLogChain(ctx, chainStore, publicKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify admin logs"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// EraseLicenseCheckLogsHandler scrubs the client data from every license
+// check of a data subject, selected by exactly one of license_id,
+// license_key or owner_id. The erasure is recorded in the admin log without
+// the key.
+func EraseLicenseCheckLogsHandler(privacyStore store.LogPrivacyStore, logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.LogErasureRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		selectors := 0
+		for _, set := range []bool{req.LicenseID != nil, req.LicenseKey != "", req.OwnerID != ""} {
+			if set {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of license_id, license_key or owner_id is required"})
+			return
+		}
+
+		erased, err := privacyStore.EraseLicenseCheckLogs(c.Request.Context(), req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to erase license check logs"})
+			return
+		}
+
+		details := map[string]interface{}{"erased": erased}
+		if req.LicenseKey != "" {
+			details["license_key"] = models.HashLicenseKey(req.LicenseKey)
+		}
+		logEntry := &models.AdminLog{
+			Action:     "ERASE_LICENSE_CHECK_LOGS",
+			EntityType: "license_check_logs",
+			EntityID:   req.LicenseID,
+			Details:    details,
+			CreatedAt:  time.Now(),
+		}
+		if req.OwnerID != "" {
+			logEntry.OwnerID = &req.OwnerID
+		}
+		logAdminAction(c, logStore, logEntry)
+
+		c.JSON(http.StatusOK, gin.H{"erased": erased})
+	}
+}
+
+// Log export formats.
+const (
+	exportFormatNDJSON = "ndjson"
+	exportFormatCSV    = "csv"
+)
+
+// exportFlushRows is how many rows are written between flushes of an export.
+const exportFlushRows = 500
+
+var licenseCheckLogCSVHeader = []string{"id", "created_at", "license_key", "product_id", "license_id", "ip_address", "user_agent", "status_code",
+	"client_app_version", "client_os", "client_hostname_hash", "client_sdk_version", "request_payload", "response_payload"}
+
+var adminLogCSVHeader = []string{"id", "created_at", "seq", "action", "entity_type", "entity_id", "owner_id",
+	"actor", "actor_token_id", "actor_ip", "actor_user_agent", "details", "changes", "hash"}
+
+// ExportLicenseCheckLogsHandler streams the license check logs matching the
+// same filters as GetLicenseCheckLogsHandler, oldest first, as NDJSON or CSV.
+func ExportLicenseCheckLogsHandler(logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := parseExportFormat(c)
+		if !ok {
+			return
+		}
+		filter, ok := parseLicenseCheckLogFilter(c)
+		if !ok {
+			return
+		}
+
+		w := &logExportWriter{c: c, name: "license-checks", format: format, csvHeader: licenseCheckLogCSVHeader}
+		err := logStore.ExportLicenseCheckLogs(c.Request.Context(), filter, func(log *models.LicenseCheckLog) error {
+			return w.write(log, func() ([]string, error) { return licenseCheckLogCSVRecord(log) })
+		})
+		w.finish(err)
+	}
+}
+
+// ExportAdminLogsHandler streams the admin logs matching the same filters as
+// GetAdminLogsHandler, oldest first, as NDJSON or CSV.
+func ExportAdminLogsHandler(logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := parseExportFormat(c)
+		if !ok {
+			return
+		}
+		filter, ok := parseAdminLogFilter(c)
+		if !ok {
+			return
+		}
+
+		w := &logExportWriter{c: c, name: "admin-actions", format: format, csvHeader: adminLogCSVHeader}
+		err := logStore.ExportAdminLogs(c.Request.Context(), filter, func(log *models.AdminLog) error {
+			return w.write(log, func() ([]string, error) { return adminLogCSVRecord(log) })
+		})
+		w.finish(err)
+	}
+}
+
+func parseExportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", exportFormatNDJSON)
+	if format != exportFormatNDJSON && format != exportFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, expected ndjson or csv"})
+		return "", false
+	}
+	return format, true
+}
+
+// logExportWriter writes the rows of an export as they are read. The status
+// and headers are only sent with the first row, so a failure before it is
+// still answered with an error.
+type logExportWriter struct {
+	c         *gin.Context
+	name      string
+	format    string
+	csvHeader []string
+
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+	rows    int
+}
+
+func (w *logExportWriter) start() error {
+	w.started = true
+	// The body is streamed, it cannot be buffered to be signed
+	middleware.SkipResponseSigning(w.c)
+
+	contentType := "application/x-ndjson"
+	if w.format == exportFormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w.c.Header("Content-Type", contentType)
+	w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, w.name, w.format))
+	w.c.Status(http.StatusOK)
+
+	if w.format == exportFormatCSV {
+		w.csv = csv.NewWriter(w.c.Writer)
+		return w.csv.Write(w.csvHeader)
+	}
+	w.json = json.NewEncoder(w.c.Writer)
+	return nil
+}
+
+func (w *logExportWriter) write(v interface{}, csvRecord func() ([]string, error)) error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	if w.format == exportFormatCSV {
+		record, err := csvRecord()
+		if err != nil {
+			return err
+		}
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+	} else if err := w.json.Encode(v); err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%exportFlushRows == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *logExportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+func (w *logExportWriter) finish(err error) {
+	if err == nil && !w.started {
+		// Nothing matched, send the headers alone
+		err = w.start()
+	}
+	if err == nil {
+		err = w.flush()
+	}
+	if err == nil {
+		return
+	}
+
+	if !w.started {
+		w.c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export logs"})
+		return
+	}
+	slog.Error("Log export failed", "export", w.name, "rows", w.rows, "error", err)
+	// The status is already sent; closing the connection makes the client
+	// see a truncated transfer rather than a complete export
+	if conn, _, hijackErr := w.c.Writer.Hijack(); hijackErr == nil {
+		conn.Close()
+	}
+	w.c.Abort()
+}
+
+func licenseCheckLogCSVRecord(log *models.LicenseCheckLog) ([]string, error) {
+	requestPayload, err := json.Marshal(log.RequestPayload)
+	if err != nil {
+		return nil, err
+	}
+	responsePayload, err := json.Marshal(log.ResponsePayload)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		log.ID.String(),
+		log.CreatedAt.UTC().Format(time.RFC3339Nano),
+		log.LicenseKey,
+		uuidString(log.ProductID),
+		uuidString(log.LicenseID),
+		log.IPAddress,
+		log.UserAgent,
+		strconv.Itoa(log.StatusCode),
+		log.Client.AppVersion,
+		log.Client.OS,
+		log.Client.HostnameHash,
+		log.Client.SDKVersion,
+		string(requestPayload),
+		string(responsePayload),
+	}, nil
+}
+
+func adminLogCSVRecord(log *models.AdminLog) ([]string, error) {
+	details, err := json.Marshal(log.Details)
+	if err != nil {
+		return nil, err
+	}
+	var changes []byte
+	if log.Changes != nil {
+		if changes, err = json.Marshal(log.Changes); err != nil {
+			return nil, err
+		}
+	}
+	var seq, ownerID string
+	if log.Seq != nil {
+		seq = strconv.FormatInt(*log.Seq, 10)
+	}
+	if log.OwnerID != nil {
+		ownerID = *log.OwnerID
+	}
+	var actor models.AdminActor
+	if log.Actor != nil {
+		actor = *log.Actor
+	}
+	return []string{
+		log.ID.String(),
+		log.CreatedAt.UTC().Format(time.RFC3339Nano),
+		seq,
+		log.Action,
+		log.EntityType,
+		uuidString(log.EntityID),
+		ownerID,
+		actor.Subject,
+		actor.TokenID,
+		actor.IPAddress,
+		actor.UserAgent,
+		string(details),
+		string(changes),
+		log.Hash,
+	}, nil
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// licenseCheckStreamHeartbeat is how often an idle stream sends a comment, so
+// proxies and clients do not time it out.
+const licenseCheckStreamHeartbeat = 15 * time.Second
+
+// licenseCheckEvent is a license check as sent on the stream. Checks are
+// streamed before they are written, so they have no ID yet.
+type licenseCheckEvent struct {
+	*models.LicenseCheckLog
+	ID    *uuid.UUID `json:"id,omitempty"`
+	Valid bool       `json:"valid"`
+}
+
+// StreamLicenseChecksHandler pushes license checks over Server-Sent Events as
+// they are logged. Checks can be filtered by product_id, license_key, valid
+// and owner_id (the owner of the product). A client that falls behind gets a
+// "dropped" event with the number of checks it missed.
+func StreamLicenseChecksHandler(feed *service.LicenseCheckFeed, productStore store.ProductStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		licenseKey := c.Query("license_key")
+		hashedKey := models.HashLicenseKey(licenseKey)
+		ownerID := c.Query("owner_id")
+		var productID *uuid.UUID
+		if idStr := c.Query("product_id"); idStr != "" {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product_id parameter"})
+				return
+			}
+			productID = &id
+		}
+		var valid *bool
+		if validStr := c.Query("valid"); validStr != "" {
+			v, err := strconv.ParseBool(validStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid valid parameter"})
+				return
+			}
+			valid = &v
+		}
+
+		sub := feed.Subscribe(func(log *models.LicenseCheckLog) bool {
+			if licenseKey != "" && log.LicenseKey != licenseKey && log.LicenseKey != hashedKey {
+				return false
+			}
+			if productID != nil && (log.ProductID == nil || *log.ProductID != *productID) {
+				return false
+			}
+			if ownerID != "" && log.ProductID == nil {
+				return false
+			}
+			return valid == nil || log.Valid() == *valid
+		})
+		defer feed.Unsubscribe(sub)
+
+		// Owners are resolved here rather than when publishing, so checks
+		// never wait on the database. Products do not change owner, so the
+		// answer is kept for the stream.
+		owned := make(map[uuid.UUID]bool)
+		ownsProduct := func(id uuid.UUID) bool {
+			if v, ok := owned[id]; ok {
+				return v
+			}
+			ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+			defer cancel()
+			product, err := productStore.GetProduct(ctx, id.String())
+			if err != nil {
+				return false
+			}
+			owned[id] = product.OwnerID != nil && *product.OwnerID == ownerID
+			return owned[id]
+		}
+
+		middleware.SkipResponseSigning(c)
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.WriteString(": connected\n\n")
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(licenseCheckStreamHeartbeat)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-heartbeat.C:
+				_, err := io.WriteString(w, ": heartbeat\n\n")
+				return err == nil
+			case log, ok := <-sub.C:
+				if !ok {
+					return false
+				}
+				if n := sub.Dropped(); n > 0 {
+					c.SSEvent("dropped", gin.H{"count": n})
+				}
+				if ownerID != "" && !ownsProduct(*log.ProductID) {
+					return true
+				}
+				c.SSEvent("check", licenseCheckEvent{LicenseCheckLog: &log, Valid: log.Valid()})
+				return true
+			}
+		})
+	}
+}
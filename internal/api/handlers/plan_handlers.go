@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+type createPlanRequest struct {
+	Name            string                 `json:"name" binding:"required"`
+	Code            string                 `json:"code" binding:"required"`
+	Description     string                 `json:"description"`
+	ProductID       *string                `json:"product_id"`
+	ProductGroupID  *string                `json:"product_group_id"`
+	Features        map[string]interface{} `json:"features"`
+	Releases        []string               `json:"releases"`
+	LicenseType     models.LicenseType     `json:"license_type"`
+	LicenseDuration string                 `json:"license_duration"`
+	SeatLimit       int                    `json:"seat_limit"`
+	OwnerID         *string                `json:"owner_id"`
+}
+
+type updatePlanRequest struct {
+	Name            string                 `json:"name"`
+	Code            string                 `json:"code"`
+	Description     *string                `json:"description"`
+	Features        map[string]interface{} `json:"features"`
+	Releases        []string               `json:"releases"`
+	LicenseType     *models.LicenseType    `json:"license_type"`
+	LicenseDuration *string                `json:"license_duration"`
+	SeatLimit       *int                   `json:"seat_limit"`
+}
+
+// ListPlansHandler handles GET /admin/plans, allowing filtering by product_id or product_group_id
+func ListPlansHandler(planStore store.PlanStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ownerID, productID, productGroupID *string
+		if idStr := c.Query("owner_id"); idStr != "" {
+			ownerID = &idStr
+		}
+		if idStr := c.Query("product_id"); idStr != "" {
+			productID = &idStr
+		}
+		if idStr := c.Query("product_group_id"); idStr != "" {
+			productGroupID = &idStr
+		}
+
+		pagination := ParsePaginationParams(c)
+
+		plans, totalCount, err := planStore.ListPlans(c.Request.Context(), productID, productGroupID, ownerID, pagination)
+		if err != nil {
+			slog.Error("Failed to list plans", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list plans"})
+			return
+		}
+
+		// Ensure plans is an empty slice instead of nil for JSON consistency
+		if plans == nil {
+			plans = []models.Plan{}
+		}
+
+		totalPages := 0
+		if pagination.Limit > 0 {
+			totalPages = (totalCount + pagination.Limit - 1) / pagination.Limit
+		}
+
+		c.JSON(http.StatusOK, models.PaginatedList[models.Plan]{
+			Items:      plans,
+			TotalCount: totalCount,
+			Page:       pagination.Page,
+			Limit:      pagination.Limit,
+			TotalPages: totalPages,
+		})
+	}
+}
+
+// CreatePlanHandler handles POST /admin/plans
+func CreatePlanHandler(planStore store.PlanStore, productStore store.ProductStore, productGroupStore store.ProductGroupStore, logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createPlanRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if (req.ProductID == nil) == (req.ProductGroupID == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of product_id or product_group_id is required"})
+			return
+		}
+
+		plan := &models.Plan{
+			ID:              uuid.New(),
+			OwnerID:         req.OwnerID,
+			Name:            req.Name,
+			Code:            req.Code,
+			Description:     req.Description,
+			Features:        req.Features,
+			Releases:        req.Releases,
+			LicenseType:     req.LicenseType,
+			LicenseDuration: req.LicenseDuration,
+			SeatLimit:       req.SeatLimit,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		}
+		if plan.Features == nil {
+			plan.Features = map[string]interface{}{}
+		}
+		if plan.Releases == nil {
+			plan.Releases = []string{}
+		}
+		if err := validatePlan(plan); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.ProductID != nil {
+			product, err := productStore.GetProduct(c.Request.Context(), *req.ProductID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product_id or product not found"})
+				return
+			}
+			plan.ProductID = &product.ID
+		} else {
+			group, err := productGroupStore.GetProductGroup(c.Request.Context(), *req.ProductGroupID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product_group_id or product group not found"})
+				return
+			}
+			plan.ProductGroupID = &group.ID
+		}
+
+		if err := planStore.CreatePlan(c.Request.Context(), plan); err != nil {
+			if errors.Is(err, store.ErrDuplicate) {
+				c.JSON(http.StatusConflict, gin.H{"error": "A plan with this code already exists"})
+				return
+			}
+			if respondUnknownCatalog(c, err) {
+				return
+			}
+			slog.Error("Failed to create plan", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create plan"})
+			return
+		}
+
+		details := map[string]interface{}(nil)
+		dt, _ := json.Marshal(plan)
+		json.Unmarshal(dt, &details)
+
+		logEntry := &models.AdminLog{
+			Action:     "CREATE_PLAN",
+			EntityType: "PLAN",
+			EntityID:   &plan.ID,
+			OwnerID:    plan.OwnerID,
+			Details:    details,
+			CreatedAt:  time.Now(),
+		}
+		logAdminAction(c, logStore, logEntry)
+
+		c.JSON(http.StatusCreated, plan)
+	}
+}
+
+// GetPlanHandler handles GET /admin/plans/:id
+func GetPlanHandler(planStore store.PlanStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plan, err := planStore.GetPlan(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+				return
+			}
+			slog.Error("Failed to get plan", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch plan"})
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+	}
+}
+
+// UpdatePlanHandler handles PUT /admin/plans/:id. Features, releases and
+// the seat limit are applied to every license on the plan.
+func UpdatePlanHandler(planStore store.PlanStore, logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req updatePlanRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		plan, err := planStore.GetPlan(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+				return
+			}
+			slog.Error("Failed to get plan", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update plan"})
+			return
+		}
+		before := *plan
+
+		if req.Name != "" {
+			plan.Name = req.Name
+		}
+		if req.Code != "" {
+			plan.Code = req.Code
+		}
+		if req.Description != nil {
+			plan.Description = *req.Description
+		}
+		if req.Features != nil {
+			plan.Features = req.Features
+		}
+		if req.Releases != nil {
+			plan.Releases = req.Releases
+		}
+		if req.LicenseType != nil {
+			plan.LicenseType = *req.LicenseType
+		}
+		if req.LicenseDuration != nil {
+			plan.LicenseDuration = *req.LicenseDuration
+		}
+		if req.SeatLimit != nil {
+			plan.SeatLimit = *req.SeatLimit
+		}
+		if err := validatePlan(plan); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		plan.UpdatedAt = time.Now()
+
+		updated, err := planStore.UpdatePlan(c.Request.Context(), plan)
+		if err != nil {
+			if errors.Is(err, store.ErrDuplicate) {
+				c.JSON(http.StatusConflict, gin.H{"error": "A plan with this code already exists"})
+				return
+			}
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+				return
+			}
+			if respondUnknownCatalog(c, err) {
+				return
+			}
+			slog.Error("Failed to update plan", "error", err, "plan_id", plan.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update plan"})
+			return
+		}
+		slog.Info("Plan updated", "plan_id", plan.ID, "licenses_updated", updated)
+
+		details := map[string]interface{}(nil)
+		dt, _ := json.Marshal(req)
+		json.Unmarshal(dt, &details)
+		if details == nil {
+			details = map[string]interface{}{}
+		}
+		details["licenses_updated"] = updated
+
+		logEntry := &models.AdminLog{
+			Action:     "UPDATE_PLAN",
+			EntityType: "PLAN",
+			EntityID:   &plan.ID,
+			OwnerID:    plan.OwnerID,
+			Details:    details,
+			Changes:    auditChanges(before, plan),
+			CreatedAt:  time.Now(),
+		}
+		logAdminAction(c, logStore, logEntry)
+
+		c.JSON(http.StatusOK, plan)
+	}
+}
+
+// DeletePlanHandler handles DELETE /admin/plans/:id. Licenses on the plan
+// keep their features and releases.
+func DeletePlanHandler(planStore store.PlanStore, logStore store.LogStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plan, err := planStore.GetPlan(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+				return
+			}
+			slog.Error("Failed to get plan", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete plan"})
+			return
+		}
+
+		if err := planStore.DeletePlan(c.Request.Context(), plan.ID.String()); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+				return
+			}
+			slog.Error("Failed to delete plan", "error", err, "plan_id", plan.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete plan"})
+			return
+		}
+
+		logEntry := &models.AdminLog{
+			Action:     "DELETE_PLAN",
+			EntityType: "PLAN",
+			EntityID:   &plan.ID,
+			OwnerID:    plan.OwnerID,
+			Details:    map[string]interface{}{"code": plan.Code},
+			CreatedAt:  time.Now(),
+		}
+		logAdminAction(c, logStore, logEntry)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Plan deleted"})
+	}
+}
+
+// validatePlan checks the term and seat limit of a plan.
+func validatePlan(plan *models.Plan) error {
+	if err := validateLicenseDefaults(plan.LicenseType, plan.LicenseDuration); err != nil {
+		return err
+	}
+	if plan.SeatLimit < 0 {
+		return fmt.Errorf("seat_limit must not be negative")
+	}
+	for code := range plan.Features {
+		if code == "" {
+			return fmt.Errorf("feature codes must not be empty")
+		}
+	}
+	return nil
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/google/uuid"
 
 	"clortho/internal/models"
-	"clortho/internal/service"
 	"clortho/internal/store"
 )
 
@@ -21,8 +20,12 @@ type createProductGroupRequest struct {
 	LicenseSeparator string `json:"license_separator"`
 	LicenseCharset   string `json:"license_charset"`
 	LicenseLength    int    `json:"license_length"`
+	LicenseType      models.LicenseType `json:"license_type"`
+	LicenseDuration  string `json:"license_duration"`
 	AutoAllowedIP    bool   `json:"auto_allowed_ip"`
 	AutoAllowedIPLimit int `json:"auto_allowed_ip_limit"`
+	DefaultFeatures  []string `json:"default_features"`
+	DefaultReleases  []string `json:"default_releases"`
 	OwnerID          *string `json:"owner_id"`
 }
 
@@ -33,8 +36,12 @@ type updateProductGroupRequest struct {
 	LicenseSeparator string `json:"license_separator"`
 	LicenseCharset   string `json:"license_charset"`
 	LicenseLength    *int   `json:"license_length"`
+	LicenseType      models.LicenseType `json:"license_type"`
+	LicenseDuration  string `json:"license_duration"`
 	AutoAllowedIP    *bool  `json:"auto_allowed_ip"`
 	AutoAllowedIPLimit *int `json:"auto_allowed_ip_limit"`
+	DefaultFeatures  []string `json:"default_features"`
+	DefaultReleases  []string `json:"default_releases"`
 	OwnerID          *string `json:"owner_id"`
 }
 
@@ -84,6 +91,10 @@ func CreateProductGroupHandler(productGroupStore store.ProductGroupStore, logSto
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if err := validateLicenseDefaults(req.LicenseType, req.LicenseDuration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		group := &models.ProductGroup{
 			ID:               uuid.New(),
@@ -94,8 +105,12 @@ func CreateProductGroupHandler(productGroupStore store.ProductGroupStore, logSto
 			LicenseSeparator: req.LicenseSeparator,
 			LicenseCharset:   req.LicenseCharset,
 			LicenseLength:    req.LicenseLength,
+			LicenseType:      req.LicenseType,
+			LicenseDuration:  req.LicenseDuration,
 			AutoAllowedIP:    req.AutoAllowedIP,
 			AutoAllowedIPLimit: req.AutoAllowedIPLimit,
+			DefaultFeatures:  req.DefaultFeatures,
+			DefaultReleases:  req.DefaultReleases,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		}
@@ -107,11 +122,13 @@ func CreateProductGroupHandler(productGroupStore store.ProductGroupStore, logSto
 
 		logEntry := &models.AdminLog{
 			Action:     "CREATE_PRODUCT_GROUP",
+			EntityType: "PRODUCT_GROUP",
+			EntityID:   &group.ID,
 			OwnerID:    group.OwnerID,
 			Details:    details,
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		if err := productGroupStore.CreateProductGroup(c.Request.Context(), group); err != nil {
 			slog.Error("Failed to create product group", "error", err)
@@ -152,7 +169,12 @@ func UpdateProductGroupHandler(productGroupStore store.ProductGroupStore, logSto
 			c.JSON(http.StatusNotFound, gin.H{"error": "Product group not found"})
 			return
 		}
+		before := *group
 
+		if err := validateLicenseDefaults(req.LicenseType, req.LicenseDuration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if req.Name != "" {
 			group.Name = req.Name
 		}
@@ -171,12 +193,24 @@ func UpdateProductGroupHandler(productGroupStore store.ProductGroupStore, logSto
 		if req.LicenseLength != nil && *req.LicenseLength > 0 {
 			group.LicenseLength = *req.LicenseLength
 		}
+		if req.LicenseType != "" {
+			group.LicenseType = req.LicenseType
+		}
+		if req.LicenseDuration != "" {
+			group.LicenseDuration = req.LicenseDuration
+		}
 		if req.AutoAllowedIP != nil {
 			group.AutoAllowedIP = *req.AutoAllowedIP
 		}
 		if req.AutoAllowedIPLimit != nil {
 			group.AutoAllowedIPLimit = *req.AutoAllowedIPLimit
 		}
+		if req.DefaultFeatures != nil {
+			group.DefaultFeatures = req.DefaultFeatures
+		}
+		if req.DefaultReleases != nil {
+			group.DefaultReleases = req.DefaultReleases
+		}
 
 		group.UpdatedAt = time.Now()
 
@@ -192,12 +226,14 @@ func UpdateProductGroupHandler(productGroupStore store.ProductGroupStore, logSto
 
 		logEntry := &models.AdminLog{
 			Action:     "UPDATE_PRODUCT_GROUP",
+			EntityType: "PRODUCT_GROUP",
 			EntityID:   &group.ID,
 			OwnerID:    group.OwnerID,
 			Details:    details,
+			Changes:    auditChanges(before, group),
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, group)
 	}
@@ -235,6 +271,6 @@ func DeleteProductGroupHandler(productGroupStore store.ProductGroupStore, logSto
 			Details:    nil,
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 	}
 }
@@ -1,7 +1,8 @@
 package handlers
 
 import (
-
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
@@ -26,6 +27,11 @@ type createProductRequest struct {
 	ProductGroupID   string `json:"product_group_id"`
 	AutoAllowedIP    bool   `json:"auto_allowed_ip"`
 	AutoAllowedIPLimit int  `json:"auto_allowed_ip_limit"`
+	RateLimits       map[string]models.RateLimit `json:"rate_limits"`
+	AnomalyThresholds models.AnomalyThresholds `json:"anomaly_thresholds"`
+	LogPrivacy       models.LogPrivacyPolicy `json:"log_privacy"`
+	DefaultFeatures  []string `json:"default_features"`
+	DefaultReleases  []string `json:"default_releases"`
 	OwnerID          *string `json:"owner_id"`
 }
 
@@ -41,9 +47,68 @@ type updateProductRequest struct {
 	ProductGroupID   string `json:"product_group_id"`
 	AutoAllowedIP    *bool  `json:"auto_allowed_ip"`
 	AutoAllowedIPLimit *int `json:"auto_allowed_ip_limit"`
+	RateLimits       map[string]models.RateLimit `json:"rate_limits"`
+	AnomalyThresholds *models.AnomalyThresholds `json:"anomaly_thresholds"`
+	LogPrivacy       *models.LogPrivacyPolicy `json:"log_privacy"`
+	DefaultFeatures  []string `json:"default_features"`
+	DefaultReleases  []string `json:"default_releases"`
 	OwnerID          *string `json:"owner_id"`
 }
 
+// validateRateLimits rejects negative overrides; a zero rate with a zero burst blocks all checks.
+func validateRateLimits(limits map[string]models.RateLimit) error {
+	for name, limit := range limits {
+		if limit.RequestsPerSecond < 0 || limit.Burst < 0 {
+			return fmt.Errorf("rate limit %q must not be negative", name)
+		}
+	}
+	return nil
+}
+
+func validateAnomalyThresholds(t models.AnomalyThresholds) error {
+	for name, v := range map[string]*int{
+		"distinct_ips":         t.DistinctIPs,
+		"distinct_user_agents": t.DistinctUserAgents,
+		"distinct_prefixes":    t.DistinctPrefixes,
+	} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("anomaly threshold %q must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// validateLogPrivacy rejects unknown key modes and negative retention.
+func validateLogPrivacy(p models.LogPrivacyPolicy) error {
+	if p.LicenseKey != nil {
+		switch *p.LicenseKey {
+		case models.LogKeyFull, models.LogKeyHash, models.LogKeyMask:
+		default:
+			return fmt.Errorf("log_privacy license_key must be %q, %q or %q", models.LogKeyFull, models.LogKeyHash, models.LogKeyMask)
+		}
+	}
+	if p.RetentionDays != nil && *p.RetentionDays < 0 {
+		return fmt.Errorf("log_privacy retention_days must not be negative")
+	}
+	return nil
+}
+
+// validateLicenseDefaults rejects unknown license types and durations that
+// ParseExpirationDuration cannot read.
+func validateLicenseDefaults(licenseType models.LicenseType, duration string) error {
+	switch licenseType {
+	case "", models.LicenseTypePerpetual, models.LicenseTypeTimed, models.LicenseTypeTrial:
+	default:
+		return fmt.Errorf("license_type must be %q, %q or %q", models.LicenseTypePerpetual, models.LicenseTypeTimed, models.LicenseTypeTrial)
+	}
+	if duration != "" {
+		if _, err := ParseExpirationDuration(duration); err != nil {
+			return fmt.Errorf("invalid license_duration: %v", err)
+		}
+	}
+	return nil
+}
+
 // ListProductsHandler handles GET /admin/products
 func ListProductsHandler(productStore store.ProductStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -92,6 +157,23 @@ func CreateProductHandler(productStore store.ProductStore, logStore store.LogSto
 			return
 		}
 
+		if err := validateRateLimits(req.RateLimits); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validateAnomalyThresholds(req.AnomalyThresholds); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validateLogPrivacy(req.LogPrivacy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validateLicenseDefaults(req.LicenseType, req.LicenseDuration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		product := &models.Product{
 			ID:               uuid.New(),
 			OwnerID:          req.OwnerID,
@@ -105,6 +187,11 @@ func CreateProductHandler(productStore store.ProductStore, logStore store.LogSto
 			LicenseDuration:  req.LicenseDuration,
 			AutoAllowedIP:    req.AutoAllowedIP,
 			AutoAllowedIPLimit: req.AutoAllowedIPLimit,
+			RateLimits:       req.RateLimits,
+			AnomalyThresholds: req.AnomalyThresholds,
+			LogPrivacy:       req.LogPrivacy,
+			DefaultFeatures:  req.DefaultFeatures,
+			DefaultReleases:  req.DefaultReleases,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		}
@@ -139,7 +226,7 @@ func CreateProductHandler(productStore store.ProductStore, logStore store.LogSto
 			},
 			CreatedAt: time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusCreated, product)
 	}
@@ -180,6 +267,11 @@ func GetProductHandler(productStore store.ProductStore, groupStore store.Product
 				"auto_allowed_ip":       product.AutoAllowedIP,
 				"auto_allowed_ip_limit": product.AutoAllowedIPLimit,
 				"product_group_id":      product.ProductGroupID,
+				"rate_limits":           product.RateLimits,
+				"anomaly_thresholds":    product.AnomalyThresholds,
+				"log_privacy":           product.LogPrivacy,
+				"default_features":      product.DefaultFeatures,
+				"default_releases":      product.DefaultReleases,
 				"created_at":            product.CreatedAt,
 				"updated_at":            product.UpdatedAt,
 				"group":                 group,
@@ -191,6 +283,26 @@ func GetProductHandler(productStore store.ProductStore, groupStore store.Product
 	}
 }
 
+// GetEffectivePolicyHandler handles GET /admin/products/:id/effective-policy.
+// It returns the settings licenses of the product are created with and the
+// level each one comes from.
+func GetEffectivePolicyHandler(productStore store.ProductStore, groupStore store.ProductGroupStore) gin.HandlerFunc {
+	resolver := service.NewLicensePolicyResolver(productStore, groupStore)
+	return func(c *gin.Context) {
+		policy, err := resolver.Resolve(c.Request.Context(), c.Param("id"), models.LicensePolicyOverrides{})
+		if err != nil {
+			if errors.Is(err, service.ErrProductNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+				return
+			}
+			slog.Error("Failed to resolve license policy", "error", err, "product_id", c.Param("id"))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve license policy"})
+			return
+		}
+		c.JSON(http.StatusOK, policy)
+	}
+}
+
 // UpdateProductHandler handles PUT /admin/products/:id
 func UpdateProductHandler(productStore store.ProductStore, logStore store.LogStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -206,7 +318,12 @@ func UpdateProductHandler(productStore store.ProductStore, logStore store.LogSto
 			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 			return
 		}
+		before := *product
 
+		if err := validateLicenseDefaults(req.LicenseType, req.LicenseDuration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if req.Name != "" {
 			product.Name = req.Name
 		}
@@ -245,6 +362,33 @@ func UpdateProductHandler(productStore store.ProductStore, logStore store.LogSto
 		if req.AutoAllowedIPLimit != nil {
 			product.AutoAllowedIPLimit = *req.AutoAllowedIPLimit
 		}
+		if req.RateLimits != nil {
+			if err := validateRateLimits(req.RateLimits); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			product.RateLimits = req.RateLimits
+		}
+		if req.AnomalyThresholds != nil {
+			if err := validateAnomalyThresholds(*req.AnomalyThresholds); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			product.AnomalyThresholds = *req.AnomalyThresholds
+		}
+		if req.LogPrivacy != nil {
+			if err := validateLogPrivacy(*req.LogPrivacy); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			product.LogPrivacy = *req.LogPrivacy
+		}
+		if req.DefaultFeatures != nil {
+			product.DefaultFeatures = req.DefaultFeatures
+		}
+		if req.DefaultReleases != nil {
+			product.DefaultReleases = req.DefaultReleases
+		}
 
 		product.UpdatedAt = time.Now()
 
@@ -263,9 +407,10 @@ func UpdateProductHandler(productStore store.ProductStore, logStore store.LogSto
 			Details: map[string]interface{}{
 				"request": req,
 			},
+			Changes:   auditChanges(before, product),
 			CreatedAt: time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, product)
 	}
@@ -302,7 +447,7 @@ func DeleteProductHandler(productStore store.ProductStore, logStore store.LogSto
 			Details:    map[string]interface{}{},
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
 	}
@@ -9,7 +9,6 @@ import (
 	"github.com/google/uuid"
 
 	"clortho/internal/models"
-	"clortho/internal/service"
 	"clortho/internal/store"
 )
 
@@ -61,6 +60,12 @@ func UpdateReleaseHandler(releaseStore store.ReleaseStore, logStore store.LogSto
 		}
 
 		// Audit Log
+		var changes map[string]models.FieldChange
+		if existingRelease != nil {
+			updated := *existingRelease
+			updated.Version = release.Version
+			changes = auditChanges(existingRelease, updated)
+		}
 		logEntry := &models.AdminLog{
 			Action:     "UPDATE_RELEASE",
 			EntityType: "releases",
@@ -69,9 +74,10 @@ func UpdateReleaseHandler(releaseStore store.ReleaseStore, logStore store.LogSto
 			Details: map[string]interface{}{
 				"request": req,
 			},
+			Changes:   changes,
 			CreatedAt: time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Release updated"})
 	}
@@ -99,7 +105,7 @@ func DeleteReleaseHandler(releaseStore store.ReleaseStore, logStore store.LogSto
 			Details:    map[string]interface{}{},
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Release deleted"})
 	}
@@ -171,7 +177,7 @@ func CreateReleaseHandler(releaseStore store.ReleaseStore, logStore store.LogSto
 			Details:    logDetails,
 			CreatedAt:  time.Now(),
 		}
-		service.AsyncLogAdminAction(c.Request.Context(), logStore, logEntry)
+		logAdminAction(c, logStore, logEntry)
 
 		c.JSON(http.StatusCreated, release)
 	}
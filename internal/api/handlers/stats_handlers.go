@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"clortho/internal/models"
 	"clortho/internal/store"
 )
 
@@ -43,3 +47,174 @@ func GetDashboardStatsHandler(statsStore store.StatsStore) gin.HandlerFunc {
 		c.JSON(http.StatusOK, stats)
 	}
 }
+
+// maxTimeseriesBuckets bounds the size of a timeseries response.
+const maxTimeseriesBuckets = 2000
+
+var statsIntervals = map[string]time.Duration{
+	models.StatsIntervalHour: time.Hour,
+	models.StatsIntervalDay:  24 * time.Hour,
+	models.StatsIntervalWeek: 7 * 24 * time.Hour,
+}
+
+// parseCheckStatsFilter reads the range and scope of check analytics. The
+// range ends at until (RFC 3339, default now) and starts at since (RFC 3339)
+// or duration before until (default 7d).
+func parseCheckStatsFilter(c *gin.Context) (models.CheckStatsFilter, error) {
+	filter := models.CheckStatsFilter{Until: time.Now()}
+
+	if v := c.Query("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("Invalid until, expected RFC 3339")
+		}
+		filter.Until = t
+	}
+
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("Invalid since, expected RFC 3339")
+		}
+		filter.Since = t
+	} else {
+		durationStr := c.DefaultQuery("duration", "7d")
+		expiryTime, err := ParseExpirationDuration(durationStr)
+		if err != nil {
+			return filter, errors.New("Invalid duration format. Use '7d', '2w', '1mo' or standard Go duration (e.g. 24h)")
+		}
+		filter.Since = filter.Until.Add(-time.Until(expiryTime))
+	}
+
+	if !filter.Since.Before(filter.Until) {
+		return filter, errors.New("since must be before until")
+	}
+
+	for param, dst := range map[string]**string{
+		"product_id":       &filter.ProductID,
+		"product_group_id": &filter.ProductGroupID,
+		"owner_id":         &filter.OwnerID,
+	} {
+		if v := c.Query(param); v != "" {
+			*dst = &v
+		}
+	}
+
+	return filter, nil
+}
+
+// GetCheckTimeseriesHandler handles GET /admin/stats/timeseries
+func GetCheckTimeseriesHandler(statsStore store.StatsStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		interval := c.DefaultQuery("interval", models.StatsIntervalDay)
+		size, ok := statsIntervals[interval]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval. Use 'hour', 'day' or 'week'"})
+			return
+		}
+
+		filter, err := parseCheckStatsFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if filter.Until.Sub(filter.Since)/size >= maxTimeseriesBuckets {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Range too large for interval, use a larger interval or a shorter range"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		series, err := statsStore.GetCheckTimeseries(ctx, interval, filter)
+		if err != nil {
+			slog.Error("Failed to get check timeseries", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get check timeseries"})
+			return
+		}
+
+		c.JSON(http.StatusOK, series)
+	}
+}
+
+// GetTopChecksHandler handles GET /admin/stats/top/:dimension
+func GetTopChecksHandler(statsStore store.StatsStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dimension := c.Param("dimension")
+		switch dimension {
+		case models.TopDimensionLicenses, models.TopDimensionFailingKeys, models.TopDimensionUserAgents, models.TopDimensionVersions:
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown dimension. Use 'licenses', 'failing-keys', 'user-agents' or 'versions'"})
+			return
+		}
+
+		limit := 10
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+				return
+			}
+			limit = n
+		}
+
+		filter, err := parseCheckStatsFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		entries, err := statsStore.GetTopChecks(ctx, dimension, filter, limit)
+		if err != nil {
+			slog.Error("Failed to get top checks", "error", err, "dimension", dimension)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top checks"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"dimension": dimension,
+			"since":     filter.Since,
+			"until":     filter.Until,
+			"items":     entries,
+		})
+	}
+}
+
+// GetVersionAdoptionHandler handles GET /admin/stats/adoption
+func GetVersionAdoptionHandler(statsStore store.StatsStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dimension := c.DefaultQuery("dimension", models.AdoptionDimensionAppVersion)
+		switch dimension {
+		case models.AdoptionDimensionAppVersion, models.AdoptionDimensionSDKVersion:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dimension. Use 'app_version' or 'sdk_version'"})
+			return
+		}
+
+		filter, err := parseCheckStatsFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		products, err := statsStore.GetVersionAdoption(ctx, dimension, filter)
+		if err != nil {
+			slog.Error("Failed to get version adoption", "error", err, "dimension", dimension)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get version adoption"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"dimension": dimension,
+			"since":     filter.Since,
+			"until":     filter.Until,
+			"products":  products,
+		})
+	}
+}
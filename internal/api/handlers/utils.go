@@ -2,19 +2,61 @@ package handlers
 
 import (
 	"fmt"
+	"log/slog"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 
 	"clortho/internal/models"
+	"clortho/internal/service"
+	"clortho/internal/store"
 )
 
+// logAdminAction records an admin action attributed to the principal that
+// authenticated the request.
+func logAdminAction(c *gin.Context, logStore store.LogStore, entry *models.AdminLog) {
+	entry.Actor = adminActor(c)
+	service.AsyncLogAdminAction(c.Request.Context(), logStore, entry)
+}
+
+// adminActor returns the principal of a request authenticated by JWTAuth.
+func adminActor(c *gin.Context) *models.AdminActor {
+	actor := &models.AdminActor{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if v, ok := c.Get("claims"); ok {
+		if claims, ok := v.(jwt.MapClaims); ok {
+			actor.Subject, _ = claims["sub"].(string)
+			actor.TokenID, _ = claims["jti"].(string)
+		}
+	}
+	return actor
+}
+
+// auditChanges returns the changes of an update for its admin log. A failure
+// only loses the diff, never the update.
+func auditChanges(before, after interface{}) map[string]models.FieldChange {
+	changes, err := service.AuditChanges(before, after)
+	if err != nil {
+		slog.Error("Failed to diff audited entity", "error", err)
+	}
+	return changes
+}
+
 
 // ParseExpirationDuration parses a duration string like "3d", "2w", "1mo", "1y"
 // and returns the expiration time from now.
 func ParseExpirationDuration(d string) (time.Time, error) {
+	return addExpirationDuration(time.Now(), d)
+}
+
+// addExpirationDuration returns from extended by a duration string as read
+// by ParseExpirationDuration.
+func addExpirationDuration(from time.Time, d string) (time.Time, error) {
 	if len(d) < 2 {
 		return time.Time{}, fmt.Errorf("duration too short")
 	}
@@ -34,20 +76,19 @@ func ParseExpirationDuration(d string) (time.Time, error) {
 		return time.Time{}, fmt.Errorf("invalid number")
 	}
 
-	now := time.Now()
 	switch unit {
 	case "m":
-		return now.Add(time.Minute * time.Duration(val)), nil
+		return from.Add(time.Minute * time.Duration(val)), nil
 	case "h":
-		return now.Add(time.Hour * time.Duration(val)), nil
+		return from.Add(time.Hour * time.Duration(val)), nil
 	case "d":
-		return now.AddDate(0, 0, val), nil
+		return from.AddDate(0, 0, val), nil
 	case "w":
-		return now.AddDate(0, 0, val*7), nil
+		return from.AddDate(0, 0, val*7), nil
 	case "mo":
-		return now.AddDate(0, val, 0), nil
+		return from.AddDate(0, val, 0), nil
 	case "y":
-		return now.AddDate(val, 0, 0), nil
+		return from.AddDate(val, 0, 0), nil
 	default:
 		return time.Time{}, fmt.Errorf("unknown unit %q", unit)
 	}
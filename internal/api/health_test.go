@@ -0,0 +1,71 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"clortho/internal/api/handlers"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	privBase64 := base64.StdEncoding.EncodeToString(priv)
+
+	t.Run("Livez", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/livez", handlers.LivenessHandler())
+
+		req, _ := http.NewRequest("GET", "/livez", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Readyz_NoDatabase", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/readyz", handlers.ReadinessHandler(nil, privBase64, new(MockLogStore)))
+
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var resp struct {
+			Status string `json:"status"`
+			Checks map[string]struct {
+				Status string `json:"status"`
+			} `json:"checks"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "unavailable", resp.Status)
+		assert.Equal(t, "unavailable", resp.Checks["database"].Status)
+		assert.Equal(t, "unavailable", resp.Checks["migrations"].Status)
+		assert.Equal(t, "ok", resp.Checks["signing_key"].Status)
+		assert.Equal(t, "ok", resp.Checks["async_log"].Status)
+	})
+
+	t.Run("Readyz_InvalidSigningKey", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/readyz", handlers.ReadinessHandler(nil, "not-a-key", new(MockLogStore)))
+
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var resp map[string]map[string]map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.Equal(t, "unavailable", resp["checks"]["signing_key"]["status"])
+	})
+}
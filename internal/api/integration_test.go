@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -27,6 +28,24 @@ import (
 	"clortho/internal/store"
 )
 
+// postgresStores returns the Postgres stores of pool for NewServer.
+func postgresStores(pool *pgxpool.Pool) Stores {
+	logs := store.NewPostgresLogStore(pool)
+	return Stores{
+		Licenses:      store.NewPostgresLicenseStore(pool),
+		Products:      store.NewPostgresProductStore(pool),
+		ProductGroups: store.NewPostgresProductGroupStore(pool),
+		Releases:      store.NewPostgresReleaseStore(pool),
+		Features:      store.NewPostgresFeatureStore(pool),
+		Logs:          logs,
+		Stats:         store.NewPostgresStatsStore(pool),
+		Flags:         store.NewPostgresFlagStore(pool),
+		AdminLogChain: logs,
+		LogPrivacy:    logs,
+		Plans:         store.NewPostgresPlanStore(pool),
+	}
+}
+
 func TestLicenseLifecycle(t *testing.T) {
 	ctx := context.Background()
 
@@ -86,16 +105,7 @@ func TestLicenseLifecycle(t *testing.T) {
 	require.NoError(t, err)
 	defer pool.Close()
 
-	// Initialize Stores
-	ls := store.NewPostgresLicenseStore(pool)
-	ps := store.NewPostgresProductStore(pool)
-	pgs := store.NewPostgresProductGroupStore(pool)
-	rs := store.NewPostgresReleaseStore(pool)
-	fs := store.NewPostgresFeatureStore(pool)
-	logs := store.NewPostgresLogStore(pool)
-	statsStore := store.NewPostgresStatsStore(pool)
-	// Initialize Server
-	server := NewServer(cfg, pool, ls, ps, pgs, rs, fs, logs, statsStore)
+	server := NewServer(cfg, pool, postgresStores(pool))
 
 	// Generate JWT for auth
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
@@ -270,6 +280,33 @@ func TestLicenseLifecycle(t *testing.T) {
 		assert.Equal(t, "INT-FEAT", featuresClaim[0])
 	}
 
+	// Step 3b: Check License (Release Restriction)
+	t.Log("Step 3b: Check License (Release Restriction)")
+	{
+		// The license read back from the database carries its releases
+		stored, err := server.LicenseStore.GetLicenseByKey(ctx, licenseKey)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.0.0"}, stored.Releases)
+
+		check := func(version string) map[string]interface{} {
+			req, _ := http.NewRequest("GET", "/check?version="+version, nil)
+			req.Header.Set("X-License-Key", licenseKey)
+			w := httptest.NewRecorder()
+			server.Router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			return resp
+		}
+
+		resp := check("2.0.0")
+		assert.Equal(t, false, resp["valid"], "License should not be valid for an unlisted release")
+		assert.Equal(t, "License not valid for version 2.0.0", resp["reason"])
+
+		resp = check("1.0.0")
+		assert.Equal(t, true, resp["valid"], "License should be valid for a listed release")
+	}
+
 	// Step 4: Revoke License
 	t.Log("Step 4: Revoke License")
 	{
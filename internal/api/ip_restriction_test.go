@@ -21,7 +21,6 @@ import (
 	"clortho/internal/config"
 	"clortho/internal/database"
 	"clortho/internal/models"
-	"clortho/internal/store"
 )
 
 func TestIPRestrictions(t *testing.T) {
@@ -80,14 +79,7 @@ func TestIPRestrictions(t *testing.T) {
 	require.NoError(t, err)
 	defer pool.Close()
 
-	ls := store.NewPostgresLicenseStore(pool)
-	ps := store.NewPostgresProductStore(pool)
-	pgs := store.NewPostgresProductGroupStore(pool)
-	rs := store.NewPostgresReleaseStore(pool)
-	fs := store.NewPostgresFeatureStore(pool)
-	logs := store.NewPostgresLogStore(pool)
-	statsStore := store.NewPostgresStatsStore(pool)
-	server := NewServer(cfg, pool, ls, ps, pgs, rs, fs, logs, statsStore)
+	server := NewServer(cfg, pool, postgresStores(pool))
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub": "test-admin",
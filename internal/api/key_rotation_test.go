@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"clortho/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRotateLicenseKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("RotateKey_KeepsLicenseID", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		mockProductStore := new(MockProductStore)
+		mockProductGroupStore := new(MockProductGroupStore)
+		mockLogStore := new(MockLogStore)
+		mockLogStore.On("CreateAdminLog", mock.Anything, mock.MatchedBy(func(l *models.AdminLog) bool {
+			return l.Action == "ROTATE_LICENSE_KEY"
+		})).Return(nil).Maybe()
+
+		router := gin.New()
+		router.POST("/admin/keys/rotate", handlers.RotateLicenseKeyHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, mockLogStore))
+
+		productID := uuid.New()
+		oldKey := "ROT-oldkey"
+		license := &models.License{
+			ID:        uuid.New(),
+			Key:       oldKey,
+			ProductID: productID,
+			Status:    models.LicenseStatusActive,
+			Type:      models.LicenseTypePerpetual,
+		}
+		product := &models.Product{
+			ID:               productID,
+			LicensePrefix:    "ROT",
+			LicenseSeparator: "-",
+			LicenseLength:    16,
+		}
+
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, oldKey).Return(license, nil)
+		mockProductStore.On("GetProduct", mock.Anything, productID.String()).Return(product, nil)
+		mockLicenseStore.On("RotateLicenseKey", mock.Anything, oldKey, mock.MatchedBy(func(k string) bool {
+			return strings.HasPrefix(k, "ROT-") && len(k) == 20 && k != oldKey
+		})).Return(nil)
+
+		req, _ := http.NewRequest("POST", "/admin/keys/rotate", nil)
+		req.Header.Set("X-License-Key", oldKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp models.License
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.Equal(t, license.ID, resp.ID)
+		assert.NotEqual(t, oldKey, resp.Key)
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("RotateKey_NotFound", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		mockLogStore := new(MockLogStore)
+
+		router := gin.New()
+		router.POST("/admin/keys/rotate", handlers.RotateLicenseKeyHandler(mockLicenseStore, new(MockProductStore), new(MockProductGroupStore), mockLogStore))
+
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, "missing").Return(nil, fmt.Errorf("%w: license", store.ErrNotFound))
+
+		req, _ := http.NewRequest("POST", "/admin/keys/rotate", nil)
+		req.Header.Set("X-License-Key", "missing")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("CheckLicense_RotatedKey", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		mockLogStore := new(MockLogStore)
+		mockLogStore.On("CreateLicenseCheckLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+		router := gin.New()
+		router.GET("/check", handlers.CheckLicenseHandler(mockLicenseStore, new(MockProductStore), "", mockLogStore))
+
+		oldKey := "ROT-retired"
+		license := &models.License{
+			ID:        uuid.New(),
+			Key:       "ROT-current",
+			ProductID: uuid.New(),
+			Status:    models.LicenseStatusActive,
+		}
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, oldKey).Return(nil, fmt.Errorf("%w: license", store.ErrNotFound))
+		mockLicenseStore.On("GetLicenseByRotatedKey", mock.Anything, oldKey).Return(license, nil)
+
+		req, _ := http.NewRequest("GET", "/check", nil)
+		req.Header.Set("X-License-Key", oldKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.False(t, resp["valid"].(bool))
+		assert.Equal(t, "key rotated", resp["reason"])
+	})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+func TestGenerateLicenseHandler_UnknownCatalog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockProductStore := new(MockProductStore)
+	mockLogStore := new(MockLogStore)
+	logged := make(chan *models.AdminLog, 1)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged <- args.Get(1).(*models.AdminLog)
+	}).Return(nil).Maybe()
+	router := gin.New()
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, new(MockProductGroupStore), new(MockPlanStore), mockLogStore))
+
+	product := &models.Product{ID: uuid.New(), LicenseType: models.LicenseTypePerpetual}
+	mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Rejected", func(t *testing.T) {
+		mockLicenseStore.On("CreateLicense", mock.Anything, mock.Anything).
+			Return(&store.UnknownCatalogError{FeatureCodes: []string{"typo"}}).Once()
+
+		w := post(fmt.Sprintf(`{"product_id": %q, "feature_codes": ["sso", "typo"], "release_versions": ["1.0.0"]}`, product.ID))
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+
+		var resp struct {
+			UnknownFeatureCodes    []string `json:"unknown_feature_codes"`
+			UnknownReleaseVersions []string `json:"unknown_release_versions"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"typo"}, resp.UnknownFeatureCodes)
+		assert.Equal(t, []string{}, resp.UnknownReleaseVersions)
+		mockLicenseStore.AssertNumberOfCalls(t, "CreateLicenseWithMissingCatalog", 0)
+	})
+
+	t.Run("CreateMissing", func(t *testing.T) {
+		mockLicenseStore.On("CreateLicenseWithMissingCatalog", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.ProductID == product.ID && assert.ObjectsAreEqual([]string{"sso", "new_feature"}, l.Features) && assert.ObjectsAreEqual([]string{"3.0.0"}, l.Releases)
+		})).Return([]string{"new_feature"}, []string{"3.0.0"}, nil).Once()
+
+		w := post(fmt.Sprintf(`{"product_id": %q, "feature_codes": ["sso", "new_feature"], "release_versions": ["3.0.0"], "create_missing": true}`, product.ID))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		select {
+		case entry := <-logged:
+			assert.Equal(t, "GENERATE_LICENSE", entry.Action)
+			assert.Equal(t, []string{"new_feature"}, entry.Details["created_features"])
+			assert.Equal(t, []string{"3.0.0"}, entry.Details["created_releases"])
+		case <-time.After(time.Second):
+			t.Fatal("admin action was not logged")
+		}
+		mockLicenseStore.AssertExpectations(t)
+		mockLicenseStore.AssertNumberOfCalls(t, "CreateLicense", 1)
+	})
+}
+
+func TestUpdateLicenseHandler_UnknownCatalog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+	router := gin.New()
+	router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, new(MockProductStore), new(MockPlanStore), mockLogStore))
+
+	productID := uuid.New()
+	put := func(key, body string) *httptest.ResponseRecorder {
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).
+			Return(&models.License{ID: uuid.New(), Key: key, ProductID: productID, Status: models.LicenseStatusActive}, nil).Once()
+		req, _ := http.NewRequest("PUT", "/admin/keys", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-License-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Rejected", func(t *testing.T) {
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.Anything).
+			Return(fmt.Errorf("failed to update license: %w", &store.UnknownCatalogError{ReleaseVersions: []string{"9.9.9"}})).Once()
+
+		w := put("strict-key", `{"release_versions": ["9.9.9"]}`)
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"unknown_release_versions":["9.9.9"]`)
+	})
+
+	t.Run("CreateMissingRejected", func(t *testing.T) {
+		mockLicenseStore.On("UpdateLicenseWithMissingCatalog", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return assert.ObjectsAreEqual([]string{"reports"}, l.Features)
+		})).Return([]string(nil), []string(nil), fmt.Errorf("connection reset")).Once()
+
+		w := put("create-key", `{"feature_codes": ["reports"], "create_missing": true}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		mockLicenseStore.AssertNumberOfCalls(t, "UpdateLicense", 1)
+		mockLicenseStore.AssertNumberOfCalls(t, "UpdateLicenseWithMissingCatalog", 1)
+	})
+}
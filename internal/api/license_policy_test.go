@@ -0,0 +1,245 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+)
+
+func TestGetEffectivePolicyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockProductStore := new(MockProductStore)
+	mockProductGroupStore := new(MockProductGroupStore)
+	router := gin.New()
+	router.GET("/admin/products/:id/effective-policy", handlers.GetEffectivePolicyHandler(mockProductStore, mockProductGroupStore))
+
+	t.Run("InheritsFromGroup", func(t *testing.T) {
+		groupID := uuid.New()
+		product := &models.Product{ID: uuid.New(), ProductGroupID: &groupID, LicensePrefix: "PROD"}
+		group := &models.ProductGroup{ID: groupID, LicenseType: models.LicenseTypeTrial, LicenseDuration: "14d"}
+		mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil).Once()
+		mockProductGroupStore.On("GetProductGroup", mock.Anything, groupID.String()).Return(group, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/admin/products/"+product.ID.String()+"/effective-policy", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp models.EffectiveLicensePolicy
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "PROD", resp.LicensePrefix)
+		assert.Equal(t, models.LicenseTypeTrial, resp.LicenseType)
+		assert.Equal(t, "14d", resp.LicenseDuration)
+		assert.Equal(t, models.PolicySourceProduct, resp.Sources["license_prefix"])
+		assert.Equal(t, models.PolicySourceProductGroup, resp.Sources["license_type"])
+		assert.Equal(t, models.PolicySourceDefault, resp.Sources["license_length"])
+	})
+
+	t.Run("ProductNotFound", func(t *testing.T) {
+		id := uuid.New().String()
+		mockProductStore.On("GetProduct", mock.Anything, id).Return(nil, errors.New("no rows")).Once()
+
+		req, _ := http.NewRequest("GET", "/admin/products/"+id+"/effective-policy", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestGenerateLicenseHandler_ProductDefaults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockProductStore := new(MockProductStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+	router := gin.New()
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, new(MockProductGroupStore), new(MockPlanStore), mockLogStore))
+
+	product := &models.Product{ID: uuid.New(), LicensePrefix: "TRY", LicenseType: models.LicenseTypeTrial, LicenseDuration: "14d"}
+	mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil)
+
+	t.Run("TypeAndDurationFromProduct", func(t *testing.T) {
+		mockLicenseStore.On("CreateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.Type == models.LicenseTypeTrial && strings.HasPrefix(l.Key, "TRY-") &&
+				l.ExpiresAt != nil && l.ExpiresAt.Sub(time.Now().AddDate(0, 0, 14)).Abs() < time.Minute
+		})).Return(nil).Once()
+
+		body, _ := json.Marshal(map[string]interface{}{"product_id": product.ID.String()})
+		req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("PerpetualDoesNotInheritDuration", func(t *testing.T) {
+		mockLicenseStore.On("CreateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.Type == models.LicenseTypePerpetual && l.ExpiresAt == nil
+		})).Return(nil).Once()
+
+		body, _ := json.Marshal(map[string]interface{}{"product_id": product.ID.String(), "type": "perpetual"})
+		req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockLicenseStore.AssertExpectations(t)
+	})
+}
+
+func TestGenerateLicenseHandler_DefaultFeaturesAndReleases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockProductStore := new(MockProductStore)
+	mockProductGroupStore := new(MockProductGroupStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+	router := gin.New()
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, new(MockPlanStore), mockLogStore))
+
+	groupID := uuid.New()
+	group := &models.ProductGroup{ID: groupID, LicenseType: models.LicenseTypePerpetual, DefaultFeatures: []string{"sso", "audit"}}
+	product := &models.Product{ID: uuid.New(), ProductGroupID: &groupID, DefaultReleases: []string{"2.0.0"}}
+	mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil)
+	mockProductGroupStore.On("GetProductGroup", mock.Anything, groupID.String()).Return(group, nil)
+
+	generate := func(body map[string]interface{}) int {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBuffer(data))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("FromProductAndGroup", func(t *testing.T) {
+		mockLicenseStore.On("CreateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return assert.ObjectsAreEqual([]string{"sso", "audit"}, l.Features) && assert.ObjectsAreEqual([]string{"2.0.0"}, l.Releases)
+		})).Return(nil).Once()
+
+		assert.Equal(t, http.StatusCreated, generate(map[string]interface{}{"product_id": product.ID.String()}))
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("RequestOverridesWithNone", func(t *testing.T) {
+		mockLicenseStore.On("CreateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return len(l.Features) == 0 && assert.ObjectsAreEqual([]string{"1.0.0"}, l.Releases)
+		})).Return(nil).Once()
+
+		assert.Equal(t, http.StatusCreated, generate(map[string]interface{}{
+			"product_id":       product.ID.String(),
+			"feature_codes":    []string{},
+			"release_versions": []string{"1.0.0"},
+		}))
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("RejectsCombinationsThePolicyForbids", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		calls := len(mockLicenseStore.Calls)
+		for name, body := range map[string]map[string]interface{}{
+			"PerpetualWithDuration": {"duration": "1y"},
+			"TimedWithoutExpiry":    {"type": "timed"},
+			"ExpiryInThePast":       {"type": "timed", "expires_at": past},
+			"UnknownType":           {"type": "forever"},
+			"NegativeLength":        {"length": -4},
+			"NegativeIPLimit":       {"auto_allowed_ip_limit": -1},
+		} {
+			body["product_id"] = product.ID.String()
+			assert.Equal(t, http.StatusBadRequest, generate(body), name)
+		}
+		assert.Len(t, mockLicenseStore.Calls, calls, "expected no license to be created")
+	})
+}
+
+func TestRenewLicenseHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockProductStore := new(MockProductStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+	router := gin.New()
+	mockPlanStore := new(MockPlanStore)
+	router.POST("/admin/keys/renew", handlers.RenewLicenseHandler(mockLicenseStore, mockProductStore, new(MockProductGroupStore), mockPlanStore, mockLogStore))
+
+	product := &models.Product{ID: uuid.New(), LicenseDuration: "1mo"}
+	mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil)
+
+	renew := func(key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/keys/renew", bytes.NewBufferString(body))
+		req.Header.Set("X-License-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("ExtendsFromExpiryByProductDuration", func(t *testing.T) {
+		expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+		license := &models.License{ID: uuid.New(), Key: "RENEW-1", ProductID: product.ID, Type: models.LicenseTypeTimed, Status: models.LicenseStatusActive, ExpiresAt: &expiresAt}
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, "RENEW-1").Return(license, nil).Once()
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.ExpiresAt.Equal(expiresAt.AddDate(0, 1, 0))
+		})).Return(nil).Once()
+
+		w := renew("RENEW-1", "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("ExtendsByPlanDuration", func(t *testing.T) {
+		plan := &models.Plan{ID: uuid.New(), ProductID: &product.ID, LicenseDuration: "1y"}
+		mockPlanStore.On("GetPlan", mock.Anything, plan.ID.String()).Return(plan, nil).Once()
+		expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+		license := &models.License{ID: uuid.New(), Key: "RENEW-PLAN", ProductID: product.ID, PlanID: &plan.ID, Type: models.LicenseTypeTimed, Status: models.LicenseStatusActive, ExpiresAt: &expiresAt}
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, "RENEW-PLAN").Return(license, nil).Once()
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.ExpiresAt.Equal(expiresAt.AddDate(1, 0, 0))
+		})).Return(nil).Once()
+
+		w := renew("RENEW-PLAN", "")
+
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		mockLicenseStore.AssertExpectations(t)
+		mockPlanStore.AssertExpectations(t)
+	})
+
+	t.Run("ExpiredLicenseRestartsFromNow", func(t *testing.T) {
+		expiresAt := time.Now().AddDate(0, 0, -10)
+		license := &models.License{ID: uuid.New(), Key: "RENEW-2", ProductID: product.ID, Type: models.LicenseTypeTimed, Status: models.LicenseStatusExpired, ExpiresAt: &expiresAt}
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, "RENEW-2").Return(license, nil).Once()
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.Status == models.LicenseStatusActive && l.ExpiresAt.Sub(time.Now().AddDate(0, 0, 7)).Abs() < time.Minute
+		})).Return(nil).Once()
+
+		w := renew("RENEW-2", `{"duration":"7d"}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("RejectsRevokedAndPerpetual", func(t *testing.T) {
+		revoked := &models.License{ID: uuid.New(), Key: "RENEW-3", ProductID: product.ID, Type: models.LicenseTypeTimed, Status: models.LicenseStatusRevoked}
+		perpetual := &models.License{ID: uuid.New(), Key: "RENEW-4", ProductID: product.ID, Type: models.LicenseTypePerpetual, Status: models.LicenseStatusActive}
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, "RENEW-3").Return(revoked, nil).Once()
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, "RENEW-4").Return(perpetual, nil).Once()
+
+		assert.Equal(t, http.StatusConflict, renew("RENEW-3", "").Code)
+		assert.Equal(t, http.StatusBadRequest, renew("RENEW-4", "").Code)
+	})
+}
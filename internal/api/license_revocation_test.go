@@ -110,7 +110,7 @@ func TestRevokeAndPurgeLicense(t *testing.T) {
 		mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 		router := gin.New()
-		router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, mockLogStore))
+		router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, new(MockProductStore), new(MockPlanStore), mockLogStore))
 
 		key := "test-status-update-key"
 		existingLicense := &models.License{
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSuspendAndResumeLicense(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("SuspendLicense_WithResumeTime", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		mockLogStore := new(MockLogStore)
+		mockLogStore.On("CreateAdminLog", mock.Anything, mock.MatchedBy(func(l *models.AdminLog) bool {
+			return l.Action == "SUSPEND_LICENSE" && l.Details["reason"] == "chargeback dispute"
+		})).Return(nil).Maybe()
+
+		router := gin.New()
+		router.POST("/admin/keys/suspend", handlers.SuspendLicenseHandler(mockLicenseStore, mockLogStore))
+
+		key := "test-suspend-key"
+		license := &models.License{
+			ID:     uuid.New(),
+			Key:    key,
+			Status: models.LicenseStatusActive,
+		}
+		until := time.Now().Add(72 * time.Hour).Truncate(time.Second).UTC()
+
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).Return(license, nil)
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.Status == models.LicenseStatusSuspended &&
+				l.SuspensionReason != nil && *l.SuspensionReason == "chargeback dispute" &&
+				l.SuspendedUntil != nil && l.SuspendedUntil.Equal(until)
+		})).Return(nil)
+
+		body, _ := json.Marshal(map[string]interface{}{"reason": "chargeback dispute", "until": until})
+		req, _ := http.NewRequest("POST", "/admin/keys/suspend", bytes.NewBuffer(body))
+		req.Header.Set("X-License-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("SuspendLicense_RequiresReason", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		router := gin.New()
+		router.POST("/admin/keys/suspend", handlers.SuspendLicenseHandler(mockLicenseStore, new(MockLogStore)))
+
+		req, _ := http.NewRequest("POST", "/admin/keys/suspend", bytes.NewBufferString(`{"duration": "3d"}`))
+		req.Header.Set("X-License-Key", "test-suspend-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockLicenseStore.AssertNotCalled(t, "GetLicenseByKey", mock.Anything, mock.Anything)
+	})
+
+	t.Run("SuspendLicense_RevokedConflict", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		router := gin.New()
+		router.POST("/admin/keys/suspend", handlers.SuspendLicenseHandler(mockLicenseStore, new(MockLogStore)))
+
+		key := "test-revoked-key"
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).Return(&models.License{ID: uuid.New(), Key: key, Status: models.LicenseStatusRevoked}, nil)
+
+		req, _ := http.NewRequest("POST", "/admin/keys/suspend", bytes.NewBufferString(`{"reason": "abuse"}`))
+		req.Header.Set("X-License-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockLicenseStore.AssertNotCalled(t, "UpdateLicense", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ResumeLicense", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		mockLogStore := new(MockLogStore)
+		mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+		router := gin.New()
+		router.POST("/admin/keys/resume", handlers.ResumeLicenseHandler(mockLicenseStore, mockLogStore))
+
+		key := "test-resume-key"
+		reason := "abuse investigation"
+		license := &models.License{
+			ID:               uuid.New(),
+			Key:              key,
+			Status:           models.LicenseStatusSuspended,
+			SuspensionReason: &reason,
+		}
+
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).Return(license, nil)
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.Status == models.LicenseStatusActive && l.SuspensionReason == nil && l.SuspendedUntil == nil
+		})).Return(nil)
+
+		req, _ := http.NewRequest("POST", "/admin/keys/resume", nil)
+		req.Header.Set("X-License-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("ResumeLicense_NotSuspended", func(t *testing.T) {
+		mockLicenseStore := new(MockLicenseStore)
+		router := gin.New()
+		router.POST("/admin/keys/resume", handlers.ResumeLicenseHandler(mockLicenseStore, new(MockLogStore)))
+
+		key := "test-active-key"
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).Return(&models.License{ID: uuid.New(), Key: key, Status: models.LicenseStatusActive}, nil)
+
+		req, _ := http.NewRequest("POST", "/admin/keys/resume", nil)
+		req.Header.Set("X-License-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	checkLicense := func(license *models.License) map[string]interface{} {
+		mockLicenseStore := new(MockLicenseStore)
+		mockLogStore := new(MockLogStore)
+		mockLogStore.On("CreateLicenseCheckLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+		router := gin.New()
+		router.GET("/check", handlers.CheckLicenseHandler(mockLicenseStore, new(MockProductStore), "", mockLogStore))
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, license.Key).Return(license, nil)
+
+		req, _ := http.NewRequest("GET", "/check", nil)
+		req.Header.Set("X-License-Key", license.Key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	t.Run("CheckLicense_Suspended", func(t *testing.T) {
+		reason := "chargeback dispute"
+		until := time.Now().Add(time.Hour)
+		resp := checkLicense(&models.License{
+			ID:               uuid.New(),
+			Key:              "test-suspended-check",
+			Status:           models.LicenseStatusSuspended,
+			SuspensionReason: &reason,
+			SuspendedUntil:   &until,
+		})
+
+		assert.Equal(t, false, resp["valid"])
+		assert.Equal(t, "License is suspended", resp["reason"])
+		assert.NotNil(t, resp["resumes_at"])
+		assert.NotContains(t, resp, "suspension_reason")
+	})
+
+	t.Run("CheckLicense_SuspensionElapsed", func(t *testing.T) {
+		until := time.Now().Add(-time.Minute)
+		resp := checkLicense(&models.License{
+			ID:             uuid.New(),
+			Key:            "test-elapsed-check",
+			Status:         models.LicenseStatusSuspended,
+			SuspendedUntil: &until,
+		})
+
+		assert.Equal(t, true, resp["valid"])
+	})
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/api/middleware"
+	"clortho/internal/models"
+)
+
+func TestLogExportHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	mockLogStore := new(MockLogStore)
+	router := gin.New()
+	router.Use(middleware.ResponseSigningMiddleware(base64.StdEncoding.EncodeToString(priv)))
+	router.GET("/admin/logs/license-checks/export", handlers.ExportLicenseCheckLogsHandler(mockLogStore))
+	router.GET("/admin/logs/admin-actions/export", handlers.ExportAdminLogsHandler(mockLogStore))
+
+	t.Run("AdminLogsAsNDJSON", func(t *testing.T) {
+		since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		logs := []models.AdminLog{
+			{ID: uuid.New(), Action: "CREATE_PRODUCT", EntityType: "products", Details: map[string]interface{}{"name": "A"}},
+			{ID: uuid.New(), Action: "UPDATE_PRODUCT", EntityType: "products", Details: map[string]interface{}{"name": "B"}},
+		}
+		mockLogStore.On("ExportAdminLogs", mock.Anything, mock.MatchedBy(func(f models.AdminLogFilter) bool {
+			return f.Action == "UPDATE_PRODUCT" && f.Since != nil && f.Since.Equal(since)
+		})).Return(logs, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/admin/logs/admin-actions/export?action=UPDATE_PRODUCT&since=2026-03-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "admin-actions.ndjson")
+		// Streamed responses are not signed
+		assert.Empty(t, w.Header().Get("X-Clortho-Signature"))
+
+		scanner := bufio.NewScanner(w.Body)
+		var got []models.AdminLog
+		for scanner.Scan() {
+			var log models.AdminLog
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &log))
+			got = append(got, log)
+		}
+		require.Len(t, got, 2)
+		assert.Equal(t, logs[1].ID, got[1].ID)
+		mockLogStore.AssertExpectations(t)
+	})
+
+	t.Run("LicenseCheckLogsAsCSV", func(t *testing.T) {
+		productID := uuid.New()
+		logs := []models.LicenseCheckLog{
+			{ID: uuid.New(), ProductID: &productID, LicenseKey: "KEY-1", UserAgent: `agent, "quoted"`, StatusCode: 200,
+				ResponsePayload: map[string]interface{}{"valid": true}, CreatedAt: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)},
+		}
+		mockLogStore.On("ExportLicenseCheckLogs", mock.Anything, models.LicenseCheckLogFilter{ProductID: productID.String()}).Return(logs, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/admin/logs/license-checks/export?format=csv&product_id="+productID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, []string{"KEY-1", productID.String()}, records[1][2:4])
+		assert.Equal(t, `agent, "quoted"`, records[1][6])
+		assert.Equal(t, `{"valid":true}`, records[1][13])
+		mockLogStore.AssertExpectations(t)
+	})
+
+	t.Run("EmptyExport", func(t *testing.T) {
+		mockLogStore.On("ExportLicenseCheckLogs", mock.Anything, models.LicenseCheckLogFilter{LicenseKey: "NONE"}).Return([]models.LicenseCheckLog{}, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/admin/logs/license-checks/export?format=csv&license_key=NONE", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Body.String(), "id,created_at,license_key"))
+	})
+
+	t.Run("FailureBeforeFirstRow", func(t *testing.T) {
+		mockLogStore.On("ExportAdminLogs", mock.Anything, models.AdminLogFilter{Actor: "broken"}).Return([]models.AdminLog{}, errors.New("connection lost")).Once()
+
+		req, _ := http.NewRequest("GET", "/admin/logs/admin-actions/export?actor=broken", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+		for _, path := range []string{
+			"/admin/logs/admin-actions/export?format=xml",
+			"/admin/logs/admin-actions/export?until=tomorrow",
+			"/admin/logs/license-checks/export?product_group_id=42",
+			"/admin/logs/license-checks/export?status_code=ok",
+		} {
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		}
+	})
+}
@@ -21,7 +21,6 @@ import (
 	"clortho/internal/config"
 	"clortho/internal/database"
 	"clortho/internal/models"
-	"clortho/internal/store"
 )
 
 func TestLogFiltering(t *testing.T) {
@@ -72,14 +71,8 @@ func TestLogFiltering(t *testing.T) {
 	require.NoError(t, err)
 	defer pool.Close()
 
-	ls := store.NewPostgresLicenseStore(pool)
-	ps := store.NewPostgresProductStore(pool)
-	pgs := store.NewPostgresProductGroupStore(pool)
-	rs := store.NewPostgresReleaseStore(pool)
-	fs := store.NewPostgresFeatureStore(pool)
-	logStore := store.NewPostgresLogStore(pool)
-	statsStore := store.NewPostgresStatsStore(pool)
-	server := NewServer(cfg, pool, ls, ps, pgs, rs, fs, logStore, statsStore)
+	stores := postgresStores(pool)
+	server := NewServer(cfg, pool, stores)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub": "test-admin",
@@ -140,7 +133,7 @@ func TestLogFiltering(t *testing.T) {
 		idStr, ok := resp["id"].(string)
 		if !ok {
 			// If not in response, try to fetch by key
-			l, err := ls.GetLicenseByKey(ctx, licenseKey)
+			l, err := stores.Licenses.GetLicenseByKey(ctx, licenseKey)
 			require.NoError(t, err)
 			idStr = l.ID.String()
 		}
@@ -164,7 +157,7 @@ func TestLogFiltering(t *testing.T) {
 		UserAgent:      "test-agent",
 		StatusCode:     200,
 	}
-	err = logStore.CreateLicenseCheckLog(ctx, log1)
+	err = stores.Logs.CreateLicenseCheckLog(ctx, log1)
 	require.NoError(t, err)
 
 	// Log 2: Not Found 404
@@ -178,7 +171,7 @@ func TestLogFiltering(t *testing.T) {
 		UserAgent:      "test-agent",
 		StatusCode:     404,
 	}
-	err = logStore.CreateLicenseCheckLog(ctx, log2)
+	err = stores.Logs.CreateLicenseCheckLog(ctx, log2)
 	require.NoError(t, err)
 
 	// Log 3: Server Error 500
@@ -192,7 +185,7 @@ func TestLogFiltering(t *testing.T) {
 		UserAgent:      "test-agent",
 		StatusCode:     500,
 	}
-	err = logStore.CreateLicenseCheckLog(ctx, log3)
+	err = stores.Logs.CreateLicenseCheckLog(ctx, log3)
 	require.NoError(t, err)
 
 
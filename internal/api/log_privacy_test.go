@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+)
+
+func TestEraseLicenseCheckLogsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockPrivacyStore := new(MockLogPrivacyStore)
+	mockLogStore := new(MockLogStore)
+	router := gin.New()
+	router.POST("/admin/logs/license-checks/erasure", handlers.EraseLicenseCheckLogsHandler(mockPrivacyStore, mockLogStore))
+
+	t.Run("ByLicenseKey", func(t *testing.T) {
+		logged := make(chan *models.AdminLog, 1)
+		mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			logged <- args.Get(1).(*models.AdminLog)
+		}).Return(nil).Once()
+		mockPrivacyStore.On("EraseLicenseCheckLogs", mock.Anything, models.LogErasureRequest{LicenseKey: "SECRET-KEY"}).Return(int64(7), nil).Once()
+
+		req, _ := http.NewRequest("POST", "/admin/logs/license-checks/erasure", bytes.NewBufferString(`{"license_key":"SECRET-KEY"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]int64
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(7), resp["erased"])
+
+		// The admin log does not keep the key it erased
+		entry := <-logged
+		assert.Equal(t, "ERASE_LICENSE_CHECK_LOGS", entry.Action)
+		assert.Equal(t, models.HashLicenseKey("SECRET-KEY"), entry.Details["license_key"])
+		mockPrivacyStore.AssertExpectations(t)
+	})
+
+	t.Run("RequiresExactlyOneSelector", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"license_key":"KEY","owner_id":"owner-1"}`} {
+			req, _ := http.NewRequest("POST", "/admin/logs/license-checks/erasure", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"clortho/internal/service"
+)
+
+// sseEvent is an event read from a Server-Sent Events stream.
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readSSEEvents sends the events read from r on the returned channel,
+// skipping comments, until the stream ends.
+func readSSEEvents(r *bufio.Reader) <-chan sseEvent {
+	events := make(chan sseEvent)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "":
+				if event.name != "" {
+					events <- event
+				}
+				event = sseEvent{}
+			case strings.HasPrefix(line, "event:"):
+				event.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				event.data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			}
+		}
+	}()
+	return events
+}
+
+func TestStreamLicenseChecksHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	feed := service.NewLicenseCheckFeed()
+	mockProductStore := new(MockProductStore)
+	router := gin.New()
+	router.GET("/admin/logs/license-checks/stream", handlers.StreamLicenseChecksHandler(feed, mockProductStore))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	owner := "owner-1"
+	other := "owner-2"
+	ownedProduct, otherProduct := uuid.New(), uuid.New()
+	mockProductStore.On("GetProduct", mock.Anything, ownedProduct.String()).Return(&models.Product{ID: ownedProduct, OwnerID: &owner}, nil).Once()
+	mockProductStore.On("GetProduct", mock.Anything, otherProduct.String()).Return(&models.Product{ID: otherProduct, OwnerID: &other}, nil).Once()
+
+	resp, err := http.Get(srv.URL + "/admin/logs/license-checks/stream?owner_id=owner-1&valid=false")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := readSSEEvents(bufio.NewReader(resp.Body))
+
+	// Wait for the handler to subscribe
+	require.Eventually(t, func() bool {
+		feed.Publish(&models.LicenseCheckLog{LicenseKey: "PROBE", ProductID: &ownedProduct, StatusCode: http.StatusNotFound})
+		select {
+		case e := <-events:
+			return e.name == "check"
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// nextCheck returns the next check streamed, skipping the probes
+	nextCheck := func() map[string]interface{} {
+		for {
+			select {
+			case e := <-events:
+				require.Equal(t, "check", e.name)
+				var check map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(e.data), &check))
+				if check["license_key"] != "PROBE" {
+					return check
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for a check")
+			}
+		}
+	}
+
+	valid := map[string]interface{}{"valid": true}
+	invalid := map[string]interface{}{"valid": false, "reason": "License expired"}
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "VALID", ProductID: &ownedProduct, StatusCode: http.StatusOK, ResponsePayload: valid})
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "NOT-OWNED", ProductID: &otherProduct, StatusCode: http.StatusOK, ResponsePayload: invalid})
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "NO-PRODUCT", StatusCode: http.StatusNotFound})
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "EXPIRED", ProductID: &ownedProduct, StatusCode: http.StatusOK, ResponsePayload: invalid})
+
+	got := nextCheck()
+	assert.Equal(t, "EXPIRED", got["license_key"])
+	assert.Equal(t, false, got["valid"])
+	assert.NotContains(t, got, "id")
+
+	// The owner of a product is looked up once per stream
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "EXPIRED", ProductID: &ownedProduct, StatusCode: http.StatusOK, ResponsePayload: invalid})
+	assert.Equal(t, "EXPIRED", nextCheck()["license_key"])
+	mockProductStore.AssertExpectations(t)
+
+	// Shutting down ends the stream
+	feed.Close()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stream to end")
+	}
+}
+
+func TestStreamLicenseChecksHandler_InvalidParameters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/logs/license-checks/stream", handlers.StreamLicenseChecksHandler(service.NewLicenseCheckFeed(), new(MockProductStore)))
+
+	for _, query := range []string{"product_id=42", "valid=maybe"} {
+		req, _ := http.NewRequest("GET", "/admin/logs/license-checks/stream?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+
+	"clortho/internal/config"
+	"clortho/internal/metrics"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// productRateLimitsTTL bounds how long a product's rate limit overrides are
+// reused before being read again.
+const productRateLimitsTTL = time.Minute
+
+type checkRateLimitPolicy struct {
+	name  string
+	keyBy []string
+	limit RateLimit
+}
+
+// checkRateLimiter applies every policy of the check limit to a request.
+type checkRateLimiter struct {
+	backend      RateLimitBackend
+	policies     []checkRateLimitPolicy
+	licenseStore store.LicenseStore
+	productStore store.ProductStore
+
+	productLimits *expirable.LRU[string, map[string]models.RateLimit]
+}
+
+// CheckRateLimitMiddleware limits /check per client IP like
+// RateLimitMiddlewareWithBackend, plus one bucket per configured policy keyed
+// by any combination of IP, license key and product. Products can override
+// the limits of a policy keyed by license key or product by name. Every
+// applicable bucket must have a token for the request to pass; the response
+// headers describe the bucket closest to running out.
+func CheckRateLimitMiddleware(cfg config.RateLimitConfig, backend RateLimitBackend, licenseStore store.LicenseStore, productStore store.ProductStore) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	size := cfg.CacheSize
+	if size <= 0 {
+		size = 5000
+	}
+	rl := &checkRateLimiter{
+		backend:       backend,
+		policies:      checkRateLimitPolicies(cfg),
+		licenseStore:  licenseStore,
+		productStore:  productStore,
+		productLimits: expirable.NewLRU[string, map[string]models.RateLimit](size, nil, productRateLimitsTTL),
+	}
+
+	return func(c *gin.Context) {
+		limit, result, ok := rl.allow(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, limit, result)
+		if !result.Allowed {
+			metrics.RateLimitRejectionsTotal.WithLabelValues(c.FullPath()).Inc()
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many requests",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// checkRateLimitPolicies returns the per-IP policy described by the top level
+// settings followed by the valid configured policies.
+func checkRateLimitPolicies(cfg config.RateLimitConfig) []checkRateLimitPolicy {
+	policies := []checkRateLimitPolicy{{
+		name:  config.RateLimitKeyIP,
+		keyBy: []string{config.RateLimitKeyIP},
+		limit: RateLimit{Rate: cfg.RequestsPerSecond, Burst: cfg.Burst},
+	}}
+
+	for _, p := range cfg.Policies {
+		valid := p.Name != "" && len(p.KeyBy) > 0
+		for _, k := range p.KeyBy {
+			if k != config.RateLimitKeyIP && k != config.RateLimitKeyLicenseKey && k != config.RateLimitKeyProduct {
+				valid = false
+			}
+		}
+		if !valid {
+			slog.Error("Ignoring invalid rate limit policy", "name", p.Name, "key_by", p.KeyBy)
+			continue
+		}
+		policies = append(policies, checkRateLimitPolicy{
+			name:  p.Name,
+			keyBy: p.KeyBy,
+			limit: RateLimit{Rate: p.RequestsPerSecond, Burst: p.Burst},
+		})
+	}
+	return policies
+}
+
+// checkLicenseKey returns the license key of a check: the key of a POST body,
+// else the X-License-Key header. The body is cached for the handler, and
+// must be capped by BodyLimitMiddleware first.
+func checkLicenseKey(c *gin.Context) string {
+	if c.Request.Method == http.MethodPost {
+		var body struct {
+			Key string `json:"key"`
+		}
+		if err := c.ShouldBindBodyWith(&body, binding.JSON); err == nil && body.Key != "" {
+			return body.Key
+		}
+	}
+	return c.GetHeader("X-License-Key")
+}
+
+// allow takes a token from every applicable bucket. ok is false when no
+// bucket could be consulted. Policies keyed by IP alone are consulted first,
+// so a client over its IP limit is rejected before its license is looked up;
+// product overrides only apply to the policies keyed by license key or
+// product.
+func (rl *checkRateLimiter) allow(c *gin.Context) (RateLimit, RateLimitResult, bool) {
+	ctx := c.Request.Context()
+	parts := map[string]string{
+		config.RateLimitKeyIP: c.ClientIP(),
+	}
+
+	var worstLimit RateLimit
+	var worst RateLimitResult
+	found := false
+	take := func(p checkRateLimitPolicy, limit RateLimit) {
+		bucket, ok := p.bucket(parts)
+		if !ok {
+			return
+		}
+		result, err := rl.backend.Allow(ctx, bucket, limit)
+		if err != nil {
+			slog.Error("Rate limit backend failed, skipping policy", "error", err, "policy", p.name)
+			return
+		}
+		if !found || worse(result, worst) {
+			worstLimit, worst, found = limit, result, true
+		}
+	}
+
+	var keyed []checkRateLimitPolicy
+	for _, p := range rl.policies {
+		if p.ipOnly() {
+			take(p, p.limit)
+		} else {
+			keyed = append(keyed, p)
+		}
+	}
+	if len(keyed) == 0 || (found && !worst.Allowed) {
+		return worstLimit, worst, found
+	}
+
+	parts[config.RateLimitKeyLicenseKey] = checkLicenseKey(c)
+	var overrides map[string]models.RateLimit
+	if key := parts[config.RateLimitKeyLicenseKey]; key != "" {
+		parts[config.RateLimitKeyProduct], overrides = rl.product(ctx, key)
+	}
+	for _, p := range keyed {
+		limit := p.limit
+		if o, ok := overrides[p.name]; ok {
+			limit = RateLimit{Rate: o.RequestsPerSecond, Burst: o.Burst}
+		}
+		take(p, limit)
+	}
+	return worstLimit, worst, found
+}
+
+// ipOnly reports whether the policy is keyed by the client IP alone.
+func (p checkRateLimitPolicy) ipOnly() bool {
+	for _, k := range p.keyBy {
+		if k != config.RateLimitKeyIP {
+			return false
+		}
+	}
+	return true
+}
+
+// bucket returns the bucket key of the policy, or false when the request
+// lacks one of the values it is keyed by.
+func (p checkRateLimitPolicy) bucket(parts map[string]string) (string, bool) {
+	var b strings.Builder
+	b.WriteString(p.name)
+	for _, k := range p.keyBy {
+		v := parts[k]
+		if v == "" {
+			return "", false
+		}
+		b.WriteString("|" + k + "=" + v)
+	}
+	return b.String(), true
+}
+
+func worse(a, b RateLimitResult) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// product returns the product ID of the license and its rate limit
+// overrides. Unknown keys have no product.
+func (rl *checkRateLimiter) product(ctx context.Context, key string) (string, map[string]models.RateLimit) {
+	license, err := rl.licenseStore.GetLicenseByKey(ctx, key)
+	if err != nil {
+		return "", nil
+	}
+	productID := license.ProductID.String()
+
+	if limits, ok := rl.productLimits.Get(productID); ok {
+		return productID, limits
+	}
+	product, err := rl.productStore.GetProduct(ctx, productID)
+	if err != nil {
+		slog.Warn("Failed to load product rate limits", "error", err, "product_id", productID)
+		return productID, nil
+	}
+	rl.productLimits.Add(productID, product.RateLimits)
+	return productID, product.RateLimits
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+type stubLicenseStore struct {
+	store.LicenseStore
+	licenses map[string]*models.License
+}
+
+func (s stubLicenseStore) GetLicenseByKey(ctx context.Context, key string) (*models.License, error) {
+	if l, ok := s.licenses[key]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("%w: license", store.ErrNotFound)
+}
+
+type stubProductStore struct {
+	store.ProductStore
+	products map[string]*models.Product
+}
+
+func (s stubProductStore) GetProduct(ctx context.Context, id string) (*models.Product, error) {
+	if p, ok := s.products[id]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: product", store.ErrNotFound)
+}
+
+func newCheckRateLimitRouter(cfg config.RateLimitConfig, product *models.Product) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	licenses := stubLicenseStore{licenses: map[string]*models.License{
+		"KEY": {Key: "KEY", ProductID: product.ID},
+	}}
+	products := stubProductStore{products: map[string]*models.Product{product.ID.String(): product}}
+
+	r := gin.New()
+	r.Use(CheckRateLimitMiddleware(cfg, NewRateLimiter(cfg), licenses, products))
+	r.GET("/check", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func checkFrom(r *gin.Engine, ip, key string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/check", nil)
+	req.RemoteAddr = ip + ":1234"
+	if key != "" {
+		req.Header.Set("X-License-Key", key)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCheckRateLimit_PerLicenseKeyAcrossIPs(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled:           true,
+		RequestsPerSecond: 100,
+		Burst:             100,
+		Policies: []config.RateLimitPolicy{
+			{Name: "license_key", KeyBy: []string{"license_key"}, RequestsPerSecond: 0.001, Burst: 2},
+		},
+	}
+	r := newCheckRateLimitRouter(cfg, &models.Product{ID: uuid.New()})
+
+	assert.Equal(t, http.StatusOK, checkFrom(r, "10.0.0.1", "KEY").Code)
+	assert.Equal(t, http.StatusOK, checkFrom(r, "10.0.0.2", "KEY").Code)
+
+	w := checkFrom(r, "10.0.0.3", "KEY")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "one key used from many IPs shares a bucket")
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Requests without a key only hit the per-IP bucket
+	assert.Equal(t, http.StatusOK, checkFrom(r, "10.0.0.3", "").Code)
+}
+
+func TestCheckRateLimit_ProductOverride(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled:           true,
+		RequestsPerSecond: 100,
+		Burst:             100,
+		Policies: []config.RateLimitPolicy{
+			{Name: "license_key", KeyBy: []string{"ip", "license_key"}, RequestsPerSecond: 0.001, Burst: 1},
+		},
+	}
+	product := &models.Product{
+		ID:         uuid.New(),
+		RateLimits: map[string]models.RateLimit{"license_key": {RequestsPerSecond: 100, Burst: 5}},
+	}
+	r := newCheckRateLimitRouter(cfg, product)
+
+	for i := 0; i < 5; i++ {
+		w := checkFrom(r, "10.0.0.1", "KEY")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+	}
+
+	// Unknown keys fall back to the configured limit
+	assert.Equal(t, http.StatusOK, checkFrom(r, "10.0.0.2", "OTHER").Code)
+	assert.Equal(t, http.StatusTooManyRequests, checkFrom(r, "10.0.0.2", "OTHER").Code)
+}
+
+type countingLicenseStore struct {
+	stubLicenseStore
+	lookups int
+}
+
+func (s *countingLicenseStore) GetLicenseByKey(ctx context.Context, key string) (*models.License, error) {
+	s.lookups++
+	return s.stubLicenseStore.GetLicenseByKey(ctx, key)
+}
+
+func TestCheckRateLimit_IPLimitBeforeLicenseLookup(t *testing.T) {
+	product := &models.Product{ID: uuid.New()}
+	newRouter := func(cfg config.RateLimitConfig, licenses *countingLicenseStore) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(CheckRateLimitMiddleware(cfg, NewRateLimiter(cfg), licenses, stubProductStore{products: map[string]*models.Product{product.ID.String(): product}}))
+		r.GET("/check", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+	keys := map[string]*models.License{"KEY": {Key: "KEY", ProductID: product.ID}}
+
+	t.Run("NoKeyedPolicies", func(t *testing.T) {
+		licenses := &countingLicenseStore{stubLicenseStore: stubLicenseStore{licenses: keys}}
+		r := newRouter(config.RateLimitConfig{Enabled: true, RequestsPerSecond: 100, Burst: 100}, licenses)
+
+		assert.Equal(t, http.StatusOK, checkFrom(r, "10.0.0.1", "KEY").Code)
+		assert.Zero(t, licenses.lookups, "the per-IP policy needs no license")
+	})
+
+	t.Run("OverIPLimit", func(t *testing.T) {
+		licenses := &countingLicenseStore{stubLicenseStore: stubLicenseStore{licenses: keys}}
+		r := newRouter(config.RateLimitConfig{
+			Enabled:           true,
+			RequestsPerSecond: 0.001,
+			Burst:             1,
+			Policies: []config.RateLimitPolicy{
+				{Name: "product", KeyBy: []string{"product"}, RequestsPerSecond: 100, Burst: 100},
+			},
+		}, licenses)
+
+		assert.Equal(t, http.StatusOK, checkFrom(r, "10.0.0.1", "KEY").Code)
+		assert.Equal(t, 1, licenses.lookups)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusTooManyRequests, checkFrom(r, "10.0.0.1", fmt.Sprintf("RANDOM-%d", i)).Code)
+		}
+		assert.Equal(t, 1, licenses.lookups, "rejected clients do not cause lookups")
+	})
+}
+
+func TestCheckRateLimit_KeyFromPostBody(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled:           true,
+		RequestsPerSecond: 100,
+		Burst:             100,
+		Policies: []config.RateLimitPolicy{
+			{Name: "license_key", KeyBy: []string{"license_key"}, RequestsPerSecond: 0.001, Burst: 1},
+		},
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CheckRateLimitMiddleware(cfg, NewRateLimiter(cfg), stubLicenseStore{}, stubProductStore{}))
+	r.POST("/check", func(c *gin.Context) {
+		// The handler still reads the body
+		var body struct {
+			Key string `json:"key"`
+		}
+		if err := c.ShouldBindBodyWith(&body, binding.JSON); err != nil || body.Key != "KEY" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	post := func(ip string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/check", strings.NewReader(`{"key": "KEY"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, post("10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, post("10.0.0.2"), "the key of the body is limited")
+}
@@ -1,17 +1,63 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
-
+	"time"
 
 	"clortho/internal/config"
+	"clortho/internal/metrics"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/time/rate"
 )
 
+// RateLimit is a token bucket refilled at Rate tokens per second holding at most Burst tokens.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitResult is the outcome of taking one token from a bucket.
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long until the next token is available when not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitBackend stores token buckets by key.
+type RateLimitBackend interface {
+	Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// NewRateLimitBackend returns the backend selected by cfg.Backend. name
+// separates the buckets of different limiters sharing a backend.
+func NewRateLimitBackend(name string, cfg config.RateLimitConfig, db *pgxpool.Pool) (RateLimitBackend, error) {
+	switch cfg.Backend {
+	case "", config.RateLimitBackendMemory:
+		return NewRateLimiter(cfg), nil
+	case config.RateLimitBackendPostgres:
+		if db == nil {
+			return nil, fmt.Errorf("rate limit backend %q requires a database", cfg.Backend)
+		}
+		return NewPostgresRateLimitBackend(db, name, cfg.CacheTTL), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+	}
+}
+
+// RateLimiter is the in-memory backend. Buckets are local to the process.
 type RateLimiter struct {
 	ips     *expirable.LRU[string, *rate.Limiter]
 	mu      sync.Mutex
@@ -49,20 +95,87 @@ func (rl *RateLimiter) GetLimiter(ip string) *rate.Limiter {
 	return limiter
 }
 
+func (rl *RateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	rl.mu.Lock()
+	limiter, ok := rl.ips.Get(key)
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		rl.ips.Add(key, limiter)
+	}
+	rl.mu.Unlock()
+
+	if limiter.Limit() != rate.Limit(limit.Rate) {
+		limiter.SetLimit(rate.Limit(limit.Rate))
+	}
+	if limiter.Burst() != limit.Burst {
+		limiter.SetBurst(limit.Burst)
+	}
+
+	now := time.Now()
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return RateLimitResult{Allowed: false}, nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		tokens := limiter.TokensAt(now)
+		return RateLimitResult{Allowed: false, RetryAfter: delay, Reset: limit.refillTime(tokens)}, nil
+	}
+	tokens := limiter.TokensAt(now)
+	return RateLimitResult{Allowed: true, Remaining: int(tokens), Reset: limit.refillTime(tokens)}, nil
+}
+
+// refillTime returns how long a bucket holding tokens takes to fill up.
+func (l RateLimit) refillTime(tokens float64) time.Duration {
+	if l.Rate <= 0 || tokens >= float64(l.Burst) {
+		return 0
+	}
+	return time.Duration((float64(l.Burst) - tokens) / l.Rate * float64(time.Second))
+}
+
+// setRateLimitHeaders sets the RateLimit-* headers of the IETF draft and,
+// for rejected requests, Retry-After. Durations are rounded up to seconds.
+func setRateLimitHeaders(c *gin.Context, limit RateLimit, result RateLimitResult) {
+	c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(max(result.Remaining, 0)))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	if !result.Allowed && result.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
 func RateLimitMiddleware(cfg config.RateLimitConfig) gin.HandlerFunc {
+	return RateLimitMiddlewareWithBackend(cfg, NewRateLimiter(cfg))
+}
+
+// RateLimitMiddlewareWithBackend limits requests per client IP using the
+// given backend. Requests are let through when the backend fails so that an
+// unavailable limiter does not take the API down with it.
+func RateLimitMiddlewareWithBackend(cfg config.RateLimitConfig, backend RateLimitBackend) gin.HandlerFunc {
 	if !cfg.Enabled {
 		return func(c *gin.Context) {
 			c.Next()
 		}
 	}
 
-	rl := NewRateLimiter(cfg)
+	limit := RateLimit{Rate: cfg.RequestsPerSecond, Burst: cfg.Burst}
 
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
-		limiter := rl.GetLimiter(ip)
+		result, err := backend.Allow(c.Request.Context(), ip, limit)
+		if err != nil {
+			slog.Error("Rate limit backend failed, allowing request", "error", err)
+			c.Next()
+			return
+		}
 
-		if !limiter.Allow() {
+		setRateLimitHeaders(c, limit, result)
+		if !result.Allowed {
+			metrics.RateLimitRejectionsTotal.WithLabelValues(c.FullPath()).Inc()
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Too many requests",
 			})
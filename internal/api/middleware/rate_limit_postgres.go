@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresRateLimitBackend keeps token buckets in the rate_limit_buckets
+// table so every instance draws from the same bucket. Each request is a
+// single upsert that refills the bucket for the time elapsed since its last
+// update and takes a token when one is available.
+type PostgresRateLimitBackend struct {
+	db     *pgxpool.Pool
+	prefix string
+	ttl    time.Duration
+
+	lastCleanup atomic.Int64
+}
+
+// NewPostgresRateLimitBackend returns a backend whose keys are prefixed with
+// name. Buckets idle for longer than ttl are deleted.
+func NewPostgresRateLimitBackend(db *pgxpool.Pool, name string, ttl time.Duration) *PostgresRateLimitBackend {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &PostgresRateLimitBackend{db: db, prefix: name + ":", ttl: ttl}
+}
+
+// $2 is the refill rate per second and $3 the burst.
+const takeTokenQuery = `
+	INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+	VALUES ($1, GREATEST($3::double precision - 1, 0), $3::double precision >= 1, NOW())
+	ON CONFLICT (key) DO UPDATE SET
+		tokens = CASE
+			WHEN LEAST($3::double precision, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::double precision * $2::double precision) >= 1
+			THEN LEAST($3::double precision, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::double precision * $2::double precision) - 1
+			ELSE LEAST($3::double precision, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::double precision * $2::double precision)
+		END,
+		allowed = LEAST($3::double precision, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at)::double precision * $2::double precision) >= 1,
+		updated_at = NOW()
+	RETURNING tokens, allowed
+`
+
+func (b *PostgresRateLimitBackend) Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	b.maybeCleanup()
+
+	var tokens float64
+	var allowed bool
+	err := b.db.QueryRow(ctx, takeTokenQuery, b.prefix+key, limit.Rate, float64(limit.Burst)).Scan(&tokens, &allowed)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	result := RateLimitResult{Allowed: allowed, Remaining: int(math.Floor(tokens)), Reset: limit.refillTime(tokens)}
+	if !allowed && limit.Rate > 0 {
+		result.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	}
+	return result, nil
+}
+
+// maybeCleanup deletes buckets idle for longer than ttl, at most once per ttl.
+// Such a bucket is normally full again, so dropping it does not change any limit.
+func (b *PostgresRateLimitBackend) maybeCleanup() {
+	now := time.Now().UnixNano()
+	last := b.lastCleanup.Load()
+	if now-last < int64(b.ttl) || !b.lastCleanup.CompareAndSwap(last, now) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		interval := fmt.Sprintf("%d milliseconds", b.ttl.Milliseconds())
+		if _, err := b.db.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE key LIKE $1 AND updated_at < NOW() - $2::interval`, b.prefix+"%", interval); err != nil {
+			slog.Warn("Failed to clean up rate limit buckets", "error", err)
+		}
+	}()
+}
@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"clortho/internal/config"
 
@@ -83,3 +85,35 @@ func TestRateLimitMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRateLimiterAllow(t *testing.T) {
+	rl := NewRateLimiter(config.RateLimitConfig{})
+	limit := RateLimit{Rate: 1, Burst: 2}
+
+	first, err := rl.Allow(context.Background(), "1.2.3.4", limit)
+	assert.NoError(t, err)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+
+	second, _ := rl.Allow(context.Background(), "1.2.3.4", limit)
+	assert.True(t, second.Allowed)
+
+	third, _ := rl.Allow(context.Background(), "1.2.3.4", limit)
+	assert.False(t, third.Allowed)
+	assert.Greater(t, third.RetryAfter, time.Duration(0))
+
+	other, _ := rl.Allow(context.Background(), "5.6.7.8", limit)
+	assert.True(t, other.Allowed, "buckets are per key")
+}
+
+func TestNewRateLimitBackend(t *testing.T) {
+	backend, err := NewRateLimitBackend("check", config.RateLimitConfig{Backend: config.RateLimitBackendMemory}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &RateLimiter{}, backend)
+
+	_, err = NewRateLimitBackend("check", config.RateLimitConfig{Backend: config.RateLimitBackendPostgres}, nil)
+	assert.Error(t, err, "postgres backend needs a database")
+
+	_, err = NewRateLimitBackend("check", config.RateLimitConfig{Backend: "redis"}, nil)
+	assert.Error(t, err)
+}
@@ -15,13 +15,24 @@ import (
 type responseBodyWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
+	skip bool
 }
 
-func (r responseBodyWriter) Write(b []byte) (int, error) {
-	r.body.Write(b)
+func (r *responseBodyWriter) Write(b []byte) (int, error) {
+	if !r.skip {
+		r.body.Write(b)
+	}
 	return r.ResponseWriter.Write(b)
 }
 
+// SkipResponseSigning leaves the response of c unsigned so its body is not
+// buffered. Streamed responses call it before writing.
+func SkipResponseSigning(c *gin.Context) {
+	if w, ok := c.Writer.(*responseBodyWriter); ok {
+		w.skip = true
+	}
+}
+
 func ResponseSigningMiddleware(privateKeyBase64 string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if privateKeyBase64 == "" {
@@ -51,6 +62,10 @@ func ResponseSigningMiddleware(privateKeyBase64 string) gin.HandlerFunc {
 
 		c.Next()
 
+		if w.skip {
+			return
+		}
+
 		timestamp := time.Now().UTC().Format(time.RFC3339)
 		body := w.body.Bytes()
 		
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/api/middleware"
+	"clortho/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLicenseHandler_MultipleFeatures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	license := &models.License{
+		ID:        uuid.New(),
+		ProductID: uuid.New(),
+		Key:       "testkey",
+		Status:    models.LicenseStatusActive,
+		Features:  []string{"sso", "audit"},
+	}
+
+	setup := func(l *models.License) (*gin.Engine, chan *models.LicenseCheckLog) {
+		mockLicenseStore := new(MockLicenseStore)
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, l.Key).Return(l, nil)
+		logged := make(chan *models.LicenseCheckLog, 8)
+		mockLogStore := new(MockLogStore)
+		mockLogStore.On("CreateLicenseCheckLog", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			logged <- args.Get(1).(*models.LicenseCheckLog)
+		}).Return(nil)
+
+		router := gin.New()
+		router.POST("/check", handlers.CheckLicenseHandler(mockLicenseStore, new(MockProductStore), base64.StdEncoding.EncodeToString(privKey), mockLogStore))
+		return router, logged
+	}
+
+	post := func(router *gin.Engine, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/check", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("PerFeatureResults", func(t *testing.T) {
+		router, logged := setup(license)
+
+		w := post(router, `{"key": "testkey", "features": ["sso", "reports", "sso"], "fingerprint": "machine-1", "client": {"app_version": "2.0.0"}}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Valid       bool                              `json:"valid"`
+			Reason      string                            `json:"reason"`
+			Fingerprint string                            `json:"fingerprint"`
+			Token       string                            `json:"token"`
+			Features    map[string]map[string]interface{} `json:"features"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Valid, "a missing feature does not fail the check")
+		assert.Empty(t, resp.Reason)
+		assert.Equal(t, "machine-1", resp.Fingerprint)
+		assert.Equal(t, map[string]map[string]interface{}{
+			"sso":     {"enabled": true},
+			"reports": {"enabled": false, "reason": "Feature not enabled: reports"},
+		}, resp.Features)
+
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(resp.Token, claims, func(*jwt.Token) (interface{}, error) { return pubKey, nil })
+		require.NoError(t, err)
+		assert.Equal(t, "machine-1", claims["fingerprint"])
+		assert.Equal(t, map[string]interface{}{"sso": true, "reports": false}, claims["feature_results"])
+
+		var entry *models.LicenseCheckLog
+		select {
+		case entry = <-logged:
+		case <-time.After(time.Second):
+			t.Fatal("check was not logged")
+		}
+		assert.Equal(t, "testkey", entry.LicenseKey)
+		assert.Equal(t, []string{"sso", "reports"}, entry.RequestPayload["features"])
+		assert.Equal(t, "2.0.0", entry.Client.AppVersion)
+	})
+
+	t.Run("InvalidLicenseDisablesFeatures", func(t *testing.T) {
+		revoked := *license
+		revoked.Key = "revokedkey"
+		revoked.Status = models.LicenseStatusRevoked
+		router, _ := setup(&revoked)
+
+		w := post(router, `{"key": "revokedkey", "features": ["sso"]}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, false, resp["valid"])
+		assert.Equal(t, map[string]interface{}{"sso": map[string]interface{}{"enabled": false}}, resp["features"])
+	})
+
+	t.Run("FeatureValues", func(t *testing.T) {
+		onPlan := *license
+		onPlan.Key = "plankey"
+		onPlan.FeatureValues = map[string]interface{}{"audit": 90.0}
+		router, _ := setup(&onPlan)
+
+		w := post(router, `{"key": "plankey", "features": ["sso", "audit"]}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]interface{}{
+			"sso":   map[string]interface{}{"enabled": true},
+			"audit": map[string]interface{}{"enabled": true, "value": 90.0},
+		}, resp["features"])
+	})
+
+	t.Run("RejectsInvalidFeatures", func(t *testing.T) {
+		router, _ := setup(license)
+
+		codes := make([]string, 101)
+		for i := range codes {
+			codes[i] = "f" + uuid.NewString()
+		}
+		tooMany, _ := json.Marshal(map[string]interface{}{"key": "testkey", "features": codes})
+
+		for _, body := range []string{
+			`{"key": "testkey", "features": ["sso", ""]}`,
+			string(tooMany),
+			`{"key": "testkey", "fingerprint": "` + strings.Repeat("a", 257) + `"}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, post(router, body).Code)
+		}
+	})
+
+	t.Run("RequiresKey", func(t *testing.T) {
+		router, _ := setup(license)
+		assert.Equal(t, http.StatusBadRequest, post(router, `{"features": ["sso"]}`).Code)
+	})
+}
+
+func TestCheckLicenseHandler_BodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateLicenseCheckLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	router := gin.New()
+	router.POST("/check", middleware.BodyLimitMiddleware(handlers.MaxCheckBodyBytes), handlers.CheckLicenseHandler(mockLicenseStore, new(MockProductStore), "", mockLogStore))
+
+	body := `{"key": "testkey", "fingerprint": "` + strings.Repeat("a", handlers.MaxCheckBodyBytes) + `"}`
+
+	t.Run("DeclaredLength", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/check", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("UnknownLength", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/check", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	mockLicenseStore.AssertNotCalled(t, "GetLicenseByKey", mock.Anything, mock.Anything)
+}
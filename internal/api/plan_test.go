@@ -0,0 +1,298 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+func TestCreatePlanHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockPlanStore := new(MockPlanStore)
+	mockProductStore := new(MockProductStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+	router := gin.New()
+	router.POST("/admin/plans", handlers.CreatePlanHandler(mockPlanStore, mockProductStore, new(MockProductGroupStore), mockLogStore))
+
+	product := &models.Product{ID: uuid.New()}
+	mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/plans", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockPlanStore.On("CreatePlan", mock.Anything, mock.MatchedBy(func(p *models.Plan) bool {
+			return p.Code == "pro" && p.ProductID != nil && *p.ProductID == product.ID && p.ProductGroupID == nil &&
+				p.Features["api_calls"] == 10000.0 && p.SeatLimit == 5
+		})).Return(nil).Once()
+
+		w := post(fmt.Sprintf(`{"name": "Pro", "code": "pro", "product_id": %q, "features": {"sso": true, "api_calls": 10000}, "releases": ["2.0.0"], "license_type": "timed", "license_duration": "1y", "seat_limit": 5}`, product.ID))
+
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var plan models.Plan
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &plan))
+		assert.Equal(t, []string{"api_calls", "sso"}, plan.FeatureCodes())
+		assert.Equal(t, []string{"2.0.0"}, plan.Releases)
+	})
+
+	t.Run("RequiresOneScope", func(t *testing.T) {
+		w := post(`{"name": "Pro", "code": "pro"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = post(fmt.Sprintf(`{"name": "Pro", "code": "pro", "product_id": %q, "product_group_id": %q}`, product.ID, uuid.New()))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("InvalidSettings", func(t *testing.T) {
+		w := post(fmt.Sprintf(`{"name": "Pro", "code": "pro", "product_id": %q, "seat_limit": -1}`, product.ID))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = post(fmt.Sprintf(`{"name": "Pro", "code": "pro", "product_id": %q, "license_duration": "soon"}`, product.ID))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("DuplicateCode", func(t *testing.T) {
+		mockPlanStore.On("CreatePlan", mock.Anything, mock.Anything).Return(fmt.Errorf("%w: plan code", store.ErrDuplicate)).Once()
+
+		w := post(fmt.Sprintf(`{"name": "Pro", "code": "pro", "product_id": %q}`, product.ID))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("UnknownCatalog", func(t *testing.T) {
+		mockPlanStore.On("CreatePlan", mock.Anything, mock.Anything).
+			Return(&store.UnknownCatalogError{FeatureCodes: []string{"typo"}, ReleaseVersions: []string{"9.9.9"}}).Once()
+
+		w := post(fmt.Sprintf(`{"name": "Pro", "code": "pro", "product_id": %q, "features": {"typo": true}, "releases": ["9.9.9"]}`, product.ID))
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"unknown_feature_codes":["typo"]`)
+		assert.Contains(t, w.Body.String(), `"unknown_release_versions":["9.9.9"]`)
+	})
+}
+
+func TestUpdatePlanHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockPlanStore := new(MockPlanStore)
+	mockLogStore := new(MockLogStore)
+	router := gin.New()
+	router.PUT("/admin/plans/:id", handlers.UpdatePlanHandler(mockPlanStore, mockLogStore))
+
+	productID := uuid.New()
+	put := func(id, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/admin/plans/"+id, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("PropagatesToLicenses", func(t *testing.T) {
+		plan := &models.Plan{ID: uuid.New(), ProductID: &productID, Name: "Pro", Code: "pro", Features: map[string]interface{}{"sso": true}, Releases: []string{"1.0.0"}, SeatLimit: 5}
+		mockPlanStore.On("GetPlan", mock.Anything, plan.ID.String()).Return(plan, nil).Once()
+		mockPlanStore.On("UpdatePlan", mock.Anything, mock.MatchedBy(func(p *models.Plan) bool {
+			return p.Name == "Pro" && len(p.Features) == 2 && p.Features["audit"] == true &&
+				len(p.Releases) == 1 && p.SeatLimit == 10
+		})).Return(int64(3), nil).Once()
+		logged := make(chan *models.AdminLog, 1)
+		mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			logged <- args.Get(1).(*models.AdminLog)
+		}).Return(nil).Once()
+
+		w := put(plan.ID.String(), `{"features": {"sso": true, "audit": true}, "seat_limit": 10}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var entry *models.AdminLog
+		select {
+		case entry = <-logged:
+		case <-time.After(time.Second):
+			t.Fatal("plan update was not logged")
+		}
+		assert.Equal(t, "UPDATE_PLAN", entry.Action)
+		assert.EqualValues(t, 3, entry.Details["licenses_updated"])
+		assert.Contains(t, entry.Changes, "seat_limit")
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		id := uuid.New().String()
+		mockPlanStore.On("GetPlan", mock.Anything, id).Return(nil, fmt.Errorf("%w: plan", store.ErrNotFound)).Once()
+
+		w := put(id, `{"seat_limit": 10}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("UnlinkableForLicenses", func(t *testing.T) {
+		// e.g. a group plan given a feature only some products of the group define
+		groupID := uuid.New()
+		plan := &models.Plan{ID: uuid.New(), ProductGroupID: &groupID, Name: "Team", Code: "team", Features: map[string]interface{}{"sso": true}}
+		mockPlanStore.On("GetPlan", mock.Anything, plan.ID.String()).Return(plan, nil).Once()
+		mockPlanStore.On("UpdatePlan", mock.Anything, mock.Anything).
+			Return(int64(0), fmt.Errorf("update failed: %w", &store.UnknownCatalogError{FeatureCodes: []string{"audit"}})).Once()
+
+		w := put(plan.ID.String(), `{"features": {"sso": true, "audit": true}}`)
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"unknown_feature_codes":["audit"]`)
+		mockLogStore.AssertNotCalled(t, "CreateAdminLog", mock.Anything, mock.MatchedBy(func(l *models.AdminLog) bool {
+			return l.EntityID != nil && *l.EntityID == plan.ID
+		}))
+	})
+}
+
+func TestGenerateLicenseHandler_Plan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockProductStore := new(MockProductStore)
+	mockProductGroupStore := new(MockProductGroupStore)
+	mockPlanStore := new(MockPlanStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+	router := gin.New()
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, mockPlanStore, mockLogStore))
+
+	groupID := uuid.New()
+	product := &models.Product{ID: uuid.New(), ProductGroupID: &groupID, LicenseType: models.LicenseTypePerpetual, DefaultFeatures: []string{"basic"}}
+	mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil)
+	mockProductGroupStore.On("GetProductGroup", mock.Anything, groupID.String()).Return(&models.ProductGroup{ID: groupID}, nil)
+
+	enterprise := &models.Plan{
+		ID:              uuid.New(),
+		ProductGroupID:  &groupID,
+		Code:            "enterprise",
+		Features:        map[string]interface{}{"sso": true, "api_calls": 100000.0},
+		Releases:        []string{"2.0.0"},
+		LicenseType:     models.LicenseTypeTimed,
+		LicenseDuration: "1y",
+		SeatLimit:       25,
+	}
+	mockPlanStore.On("GetPlan", mock.Anything, enterprise.ID.String()).Return(enterprise, nil)
+	otherProductID := uuid.New()
+	foreign := &models.Plan{ID: uuid.New(), ProductID: &otherProductID, Code: "pro"}
+	mockPlanStore.On("GetPlan", mock.Anything, foreign.ID.String()).Return(foreign, nil)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("BundleFromPlan", func(t *testing.T) {
+		mockLicenseStore.On("CreateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.PlanID != nil && *l.PlanID == enterprise.ID &&
+				l.Type == models.LicenseTypeTimed && l.ExpiresAt != nil && l.ExpiresAt.Sub(time.Now().AddDate(1, 0, 0)).Abs() < time.Minute &&
+				assert.ObjectsAreEqual([]string{"api_calls", "sso"}, l.Features) &&
+				assert.ObjectsAreEqual([]string{"2.0.0"}, l.Releases) &&
+				l.FeatureValues["api_calls"] == 100000.0 &&
+				l.AutoAllowedIP && l.AutoAllowedIPLimit == 25
+		})).Return(nil).Once()
+
+		w := post(fmt.Sprintf(`{"product_id": %q, "plan_id": %q}`, product.ID, enterprise.ID))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("PlanOfAnotherProduct", func(t *testing.T) {
+		w := post(fmt.Sprintf(`{"product_id": %q, "plan_id": %q}`, product.ID, foreign.ID))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("UnknownPlan", func(t *testing.T) {
+		id := uuid.New().String()
+		mockPlanStore.On("GetPlan", mock.Anything, id).Return(nil, fmt.Errorf("%w: plan", store.ErrNotFound)).Once()
+
+		w := post(fmt.Sprintf(`{"product_id": %q, "plan_id": %q}`, product.ID, id))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("FeatureCodesWithPlan", func(t *testing.T) {
+		calls := len(mockLicenseStore.Calls)
+
+		w := post(fmt.Sprintf(`{"product_id": %q, "plan_id": %q, "feature_codes": ["sso"]}`, product.ID, enterprise.ID))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Len(t, mockLicenseStore.Calls, calls)
+	})
+}
+
+func TestUpdateLicenseHandler_Plan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockLicenseStore := new(MockLicenseStore)
+	mockProductStore := new(MockProductStore)
+	mockPlanStore := new(MockPlanStore)
+	mockLogStore := new(MockLogStore)
+	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
+	router := gin.New()
+	router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, mockProductStore, mockPlanStore, mockLogStore))
+
+	product := &models.Product{ID: uuid.New()}
+	mockProductStore.On("GetProduct", mock.Anything, product.ID.String()).Return(product, nil)
+	pro := &models.Plan{ID: uuid.New(), ProductID: &product.ID, Code: "pro", Features: map[string]interface{}{"sso": true}, Releases: []string{"1.0.0"}, SeatLimit: 3}
+	enterprise := &models.Plan{ID: uuid.New(), ProductID: &product.ID, Code: "enterprise", Features: map[string]interface{}{"sso": true, "audit": true}, Releases: []string{"1.0.0", "2.0.0"}, SeatLimit: 10}
+	mockPlanStore.On("GetPlan", mock.Anything, enterprise.ID.String()).Return(enterprise, nil)
+
+	put := func(key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/admin/keys", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-License-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	licenseOnPro := func(key string) *models.License {
+		l := &models.License{ID: uuid.New(), Key: key, ProductID: product.ID, Status: models.LicenseStatusActive}
+		pro.ApplyTo(l)
+		mockLicenseStore.On("GetLicenseByKey", mock.Anything, key).Return(l, nil).Once()
+		return l
+	}
+
+	t.Run("Upgrade", func(t *testing.T) {
+		licenseOnPro("upgrade-key")
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.Key == "upgrade-key" && *l.PlanID == enterprise.ID &&
+				assert.ObjectsAreEqual([]string{"audit", "sso"}, l.Features) &&
+				assert.ObjectsAreEqual([]string{"1.0.0", "2.0.0"}, l.Releases) &&
+				l.AutoAllowedIPLimit == 10 && !l.AutoAllowedIP
+		})).Return(nil).Once()
+
+		w := put("upgrade-key", fmt.Sprintf(`{"plan_id": %q}`, enterprise.ID))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("Detach", func(t *testing.T) {
+		licenseOnPro("detach-key")
+		mockLicenseStore.On("UpdateLicense", mock.Anything, mock.MatchedBy(func(l *models.License) bool {
+			return l.Key == "detach-key" && l.PlanID == nil && assert.ObjectsAreEqual([]string{"reports"}, l.Features)
+		})).Return(nil).Once()
+
+		w := put("detach-key", `{"plan_id": "", "feature_codes": ["reports"]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		mockLicenseStore.AssertExpectations(t)
+	})
+
+	t.Run("FeatureCodesOnPlan", func(t *testing.T) {
+		licenseOnPro("on-plan-key")
+
+		w := put("on-plan-key", `{"feature_codes": ["reports"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockLicenseStore.AssertNumberOfCalls(t, "UpdateLicense", 2)
+	})
+}
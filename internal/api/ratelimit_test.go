@@ -10,7 +10,6 @@ import (
 
 	"clortho/internal/config"
 	"clortho/internal/database"
-	"clortho/internal/store"
 	"crypto/ed25519"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -74,15 +73,7 @@ func TestSeparateRateLimits(t *testing.T) {
 	}
 
 	// Initialize Server with real or stub stores. Using real ones is easier if DB is up.
-	ls := store.NewPostgresLicenseStore(pool)
-	ps := store.NewPostgresProductStore(pool)
-	pgs := store.NewPostgresProductGroupStore(pool)
-	rs := store.NewPostgresReleaseStore(pool)
-	fs := store.NewPostgresFeatureStore(pool)
-	logs := store.NewPostgresLogStore(pool)
-	statsStore := store.NewPostgresStatsStore(pool)
-	
-	server := NewServer(cfg, pool, ls, ps, pgs, rs, fs, logs, statsStore)
+	server := NewServer(cfg, pool, postgresStores(pool))
 
 	// Test 1: Admin Rate Limit Exhaustion
 	t.Run("Admin Rate Limit Exhaustion", func(t *testing.T) {
@@ -1,12 +1,17 @@
 package api
 
 import (
+	"log/slog"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	"clortho/internal/api/handlers"
 	"clortho/internal/api/middleware"
 	"clortho/internal/config"
+	"clortho/internal/service"
 	"clortho/internal/store"
 )
 
@@ -22,10 +27,44 @@ type Server struct {
 	FeatureStore      store.FeatureStore
 	LogStore          store.LogStore
 	StatsStore        store.StatsStore
+	FlagStore         store.FlagStore
+	AdminLogChain     store.AdminLogChainStore
+	LogPrivacy        store.LogPrivacyStore
+	PlanStore         store.PlanStore
+	// LicenseChecks is the feed of the license checks written through
+	// LogStore. It only carries checks when LogStore is a service.LogWriter.
+	LicenseChecks *service.LicenseCheckFeed
+}
+
+// Stores are the stores a Server reads and writes through.
+type Stores struct {
+	Licenses      store.LicenseStore
+	Products      store.ProductStore
+	ProductGroups store.ProductGroupStore
+	Releases      store.ReleaseStore
+	Features      store.FeatureStore
+	// Logs may be a service.LogWriter, whose feed then becomes
+	// Server.LicenseChecks.
+	Logs          store.LogStore
+	Stats         store.StatsStore
+	Flags         store.FlagStore
+	AdminLogChain store.AdminLogChainStore
+	LogPrivacy    store.LogPrivacyStore
+	Plans         store.PlanStore
 }
 
-func NewServer(cfg config.Config, db *pgxpool.Pool, ls store.LicenseStore, ps store.ProductStore, pgs store.ProductGroupStore, rs store.ReleaseStore, fs store.FeatureStore, logs store.LogStore, ss store.StatsStore) *Server {
+func NewServer(cfg config.Config, db *pgxpool.Pool, stores Stores) *Server {
 	r := gin.Default()
+	if cfg.Tracing.Enabled {
+		serviceName := cfg.Tracing.ServiceName
+		if serviceName == "" {
+			serviceName = "clortho"
+		}
+		r.Use(otelgin.Middleware(serviceName))
+	}
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.MetricsMiddleware())
+	}
 
 	r.Use(middleware.ResponseSigningMiddleware(cfg.ResponseSigningPrivateKey))
 	if len(cfg.TrustedProxies) > 0 {
@@ -36,31 +75,63 @@ func NewServer(cfg config.Config, db *pgxpool.Pool, ls store.LicenseStore, ps st
 		Router:            r,
 		DB:                db,
 		Config:            cfg,
-		LicenseStore:      ls,
-		ProductStore:      ps,
-		ProductGroupStore: pgs,
-		ReleaseStore:      rs,
-		FeatureStore:      fs,
-		LogStore:          logs,
-		StatsStore:        ss,
+		LicenseStore:      stores.Licenses,
+		ProductStore:      stores.Products,
+		ProductGroupStore: stores.ProductGroups,
+		ReleaseStore:      stores.Releases,
+		FeatureStore:      stores.Features,
+		LogStore:          stores.Logs,
+		StatsStore:        stores.Stats,
+		FlagStore:         stores.Flags,
+		AdminLogChain:     stores.AdminLogChain,
+		LogPrivacy:        stores.LogPrivacy,
+		PlanStore:         stores.Plans,
+		LicenseChecks:     service.NewLicenseCheckFeed(),
+	}
+	if w, ok := stores.Logs.(*service.LogWriter); ok {
+		server.LicenseChecks = w.LicenseChecks()
 	}
 
 	server.setupRoutes()
 	return server
 }
 
+// rateLimitBackend falls back to the in-memory backend when the configured one
+// cannot be used, so a misconfiguration never disables rate limiting.
+func (s *Server) rateLimitBackend(name string, cfg config.RateLimitConfig) middleware.RateLimitBackend {
+	backend, err := middleware.NewRateLimitBackend(name, cfg, s.DB)
+	if err != nil {
+		slog.Error("Failed to set up rate limit backend, using in-memory limits", "error", err, "limiter", name)
+		return middleware.NewRateLimiter(cfg)
+	}
+	return backend
+}
+
 func (s *Server) setupRoutes() {
 	// Initialize Rate Limiters
-	adminRateLimiter := middleware.RateLimitMiddleware(s.Config.RateLimitAdmin)
-	checkRateLimiter := middleware.RateLimitMiddleware(s.Config.RateLimitCheck)
+	adminRateLimiter := middleware.RateLimitMiddlewareWithBackend(s.Config.RateLimitAdmin, s.rateLimitBackend("admin", s.Config.RateLimitAdmin))
+	checkRateLimiter := middleware.CheckRateLimitMiddleware(s.Config.RateLimitCheck, s.rateLimitBackend("check", s.Config.RateLimitCheck), s.LicenseStore, s.ProductStore)
 
 	// Public routes
 	s.Router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	s.Router.GET("/livez", handlers.LivenessHandler())
+	s.Router.GET("/readyz", handlers.ReadinessHandler(s.DB, s.Config.ResponseSigningPrivateKey, s.LogStore))
+
+	if s.Config.Metrics.Enabled {
+		path := s.Config.Metrics.Path
+		if path == "" {
+			path = "/metrics"
+		}
+		s.Router.GET(path, gin.WrapH(promhttp.Handler()))
+	}
 
 	// License Key Public Endpoints
-	s.Router.GET("/check", checkRateLimiter, handlers.CheckLicenseHandler(s.LicenseStore, s.ProductStore, s.Config.ResponseSigningPrivateKey, s.LogStore))
+	// The body is capped before the rate limiter reads the key from it
+	checkBodyLimit := middleware.BodyLimitMiddleware(handlers.MaxCheckBodyBytes)
+	s.Router.GET("/check", checkBodyLimit, checkRateLimiter, handlers.CheckLicenseHandler(s.LicenseStore, s.ProductStore, s.Config.ResponseSigningPrivateKey, s.LogStore))
+	s.Router.POST("/check", checkBodyLimit, checkRateLimiter, handlers.CheckLicenseHandler(s.LicenseStore, s.ProductStore, s.Config.ResponseSigningPrivateKey, s.LogStore))
 
 	// Protected routes
 	authorized := s.Router.Group("/")
@@ -69,18 +140,27 @@ func (s *Server) setupRoutes() {
 	{
 		// Dashboard Stats
 		authorized.GET("/admin/stats", handlers.GetDashboardStatsHandler(s.StatsStore))
+		authorized.GET("/admin/stats/timeseries", handlers.GetCheckTimeseriesHandler(s.StatsStore))
+		authorized.GET("/admin/stats/top/:dimension", handlers.GetTopChecksHandler(s.StatsStore))
+		authorized.GET("/admin/stats/adoption", handlers.GetVersionAdoptionHandler(s.StatsStore))
 
 		// License Management
 		authorized.GET("/admin/keys", handlers.GetLicenseHandler(s.LicenseStore))
-		authorized.POST("/admin/keys", handlers.GenerateLicenseHandler(s.LicenseStore, s.ProductStore, s.ProductGroupStore, s.LogStore))
-		authorized.PUT("/admin/keys", handlers.UpdateLicenseHandler(s.LicenseStore, s.LogStore))
+		authorized.POST("/admin/keys", handlers.GenerateLicenseHandler(s.LicenseStore, s.ProductStore, s.ProductGroupStore, s.PlanStore, s.LogStore))
+		authorized.PUT("/admin/keys", handlers.UpdateLicenseHandler(s.LicenseStore, s.ProductStore, s.PlanStore, s.LogStore))
 		authorized.DELETE("/admin/keys", handlers.RevokeLicenseHandler(s.LicenseStore, s.LogStore))
 		authorized.DELETE("/admin/keys/purge", handlers.DeleteLicenseHandler(s.LicenseStore, s.LogStore))
+		authorized.POST("/admin/keys/rotate", handlers.RotateLicenseKeyHandler(s.LicenseStore, s.ProductStore, s.ProductGroupStore, s.LogStore))
+		authorized.POST("/admin/keys/renew", handlers.RenewLicenseHandler(s.LicenseStore, s.ProductStore, s.ProductGroupStore, s.PlanStore, s.LogStore))
+		authorized.POST("/admin/keys/suspend", handlers.SuspendLicenseHandler(s.LicenseStore, s.LogStore))
+		authorized.POST("/admin/keys/resume", handlers.ResumeLicenseHandler(s.LicenseStore, s.LogStore))
+		authorized.GET("/admin/keys/flagged", handlers.ListFlaggedLicensesHandler(s.FlagStore))
 
 		// Product Management
 		authorized.GET("/admin/products", handlers.ListProductsHandler(s.ProductStore))
 		authorized.POST("/admin/products", handlers.CreateProductHandler(s.ProductStore, s.LogStore))
 		authorized.GET("/admin/products/:id", handlers.GetProductHandler(s.ProductStore, s.ProductGroupStore))
+		authorized.GET("/admin/products/:id/effective-policy", handlers.GetEffectivePolicyHandler(s.ProductStore, s.ProductGroupStore))
 		authorized.PUT("/admin/products/:id", handlers.UpdateProductHandler(s.ProductStore, s.LogStore))
 		authorized.DELETE("/admin/products/:id", handlers.DeleteProductHandler(s.ProductStore, s.LogStore))
 
@@ -91,6 +171,13 @@ func (s *Server) setupRoutes() {
 		authorized.PUT("/admin/product-groups/:id", handlers.UpdateProductGroupHandler(s.ProductGroupStore, s.LogStore))
 		authorized.DELETE("/admin/product-groups/:id", handlers.DeleteProductGroupHandler(s.ProductGroupStore, s.LogStore))
 
+		// Plan Management
+		authorized.GET("/admin/plans", handlers.ListPlansHandler(s.PlanStore))
+		authorized.POST("/admin/plans", handlers.CreatePlanHandler(s.PlanStore, s.ProductStore, s.ProductGroupStore, s.LogStore))
+		authorized.GET("/admin/plans/:id", handlers.GetPlanHandler(s.PlanStore))
+		authorized.PUT("/admin/plans/:id", handlers.UpdatePlanHandler(s.PlanStore, s.LogStore))
+		authorized.DELETE("/admin/plans/:id", handlers.DeletePlanHandler(s.PlanStore, s.LogStore))
+
 
 		// Feature Management
 		authorized.POST("/admin/features", handlers.CreateFeatureHandler(s.FeatureStore, s.LogStore))
@@ -110,7 +197,12 @@ func (s *Server) setupRoutes() {
 
 		// Log Management
 		authorized.GET("/admin/logs/license-checks", handlers.GetLicenseCheckLogsHandler(s.LogStore))
+		authorized.GET("/admin/logs/license-checks/export", handlers.ExportLicenseCheckLogsHandler(s.LogStore))
+		authorized.GET("/admin/logs/license-checks/stream", handlers.StreamLicenseChecksHandler(s.LicenseChecks, s.ProductStore))
+		authorized.POST("/admin/logs/license-checks/erasure", handlers.EraseLicenseCheckLogsHandler(s.LogPrivacy, s.LogStore))
 		authorized.GET("/admin/logs/admin-actions", handlers.GetAdminLogsHandler(s.LogStore))
+		authorized.GET("/admin/logs/admin-actions/export", handlers.ExportAdminLogsHandler(s.LogStore))
+		authorized.GET("/admin/logs/admin-actions/verify", handlers.VerifyAdminLogChainHandler(s.AdminLogChain, s.Config.ResponseSigningPublicKey))
 
 	}
 }
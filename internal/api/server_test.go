@@ -14,6 +14,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"clortho/internal/api/handlers"
 	"clortho/internal/models"
@@ -83,10 +84,37 @@ func (m *MockLicenseStore) ListLicenses(ctx context.Context, ownerID *string, pa
 	args := m.Called(ctx, ownerID, pagination)
 	return args.Get(0).([]models.License), args.Int(1), args.Error(2)
 }
+func (m *MockLicenseStore) ResumeExpiredSuspensions(ctx context.Context, now time.Time) ([]models.License, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]models.License), args.Error(1)
+}
+func (m *MockLicenseStore) CreateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error) {
+	args := m.Called(ctx, license)
+	return args.Get(0).([]string), args.Get(1).([]string), args.Error(2)
+}
+func (m *MockLicenseStore) UpdateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error) {
+	args := m.Called(ctx, license)
+	return args.Get(0).([]string), args.Get(1).([]string), args.Error(2)
+}
 func (m *MockLicenseStore) UpdateLicense(ctx context.Context, license *models.License) error {
 	args := m.Called(ctx, license)
 	return args.Error(0)
 }
+func (m *MockLicenseStore) AddAutoAllowedIP(ctx context.Context, key, ip string) (bool, error) {
+	args := m.Called(ctx, key, ip)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockLicenseStore) RotateLicenseKey(ctx context.Context, oldKey, newKey string) error {
+	args := m.Called(ctx, oldKey, newKey)
+	return args.Error(0)
+}
+func (m *MockLicenseStore) GetLicenseByRotatedKey(ctx context.Context, key string) (*models.License, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.License), args.Error(1)
+}
 
 // MockReleaseStore is a mock implementation of store.ReleaseStore
 type MockReleaseStore struct {
@@ -210,6 +238,98 @@ func (m *MockProductGroupStore) DeleteProductGroup(ctx context.Context, id strin
 	return args.Error(0)
 }
 
+// MockPlanStore is a mock implementation of store.PlanStore
+type MockPlanStore struct {
+	mock.Mock
+}
+
+func (m *MockPlanStore) ListPlans(ctx context.Context, productID, productGroupID, ownerID *string, pagination models.PaginationParams) ([]models.Plan, int, error) {
+	args := m.Called(ctx, productID, productGroupID, ownerID, pagination)
+	return args.Get(0).([]models.Plan), args.Int(1), args.Error(2)
+}
+
+func (m *MockPlanStore) GetPlan(ctx context.Context, id string) (*models.Plan, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Plan), args.Error(1)
+}
+
+func (m *MockPlanStore) CreatePlan(ctx context.Context, plan *models.Plan) error {
+	args := m.Called(ctx, plan)
+	return args.Error(0)
+}
+
+func (m *MockPlanStore) UpdatePlan(ctx context.Context, plan *models.Plan) (int64, error) {
+	args := m.Called(ctx, plan)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPlanStore) DeletePlan(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// MockFlagStore is a mock implementation of store.FlagStore
+type MockFlagStore struct {
+	mock.Mock
+}
+
+func (m *MockFlagStore) FindAnomalousLicenses(ctx context.Context, since time.Time, defaults models.AnomalyThresholds) ([]models.LicenseFlag, error) {
+	args := m.Called(ctx, since, defaults)
+	return args.Get(0).([]models.LicenseFlag), args.Error(1)
+}
+
+func (m *MockFlagStore) UpsertLicenseFlag(ctx context.Context, flag *models.LicenseFlag) (*time.Time, error) {
+	args := m.Called(ctx, flag)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockFlagStore) SetLicenseFlagSuspended(ctx context.Context, licenseID string) error {
+	args := m.Called(ctx, licenseID)
+	return args.Error(0)
+}
+
+func (m *MockFlagStore) ListLicenseFlags(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.LicenseFlag, int, error) {
+	args := m.Called(ctx, ownerID, pagination)
+	return args.Get(0).([]models.LicenseFlag), args.Int(1), args.Error(2)
+}
+
+// MockStatsStore is a mock implementation of store.StatsStore
+type MockStatsStore struct {
+	mock.Mock
+}
+
+func (m *MockStatsStore) GetDashboardStats(ctx context.Context, ownerID *string, since *time.Time) (*models.DashboardStats, error) {
+	args := m.Called(ctx, ownerID, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DashboardStats), args.Error(1)
+}
+
+func (m *MockStatsStore) GetCheckTimeseries(ctx context.Context, interval string, filter models.CheckStatsFilter) (*models.Timeseries, error) {
+	args := m.Called(ctx, interval, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Timeseries), args.Error(1)
+}
+
+func (m *MockStatsStore) GetTopChecks(ctx context.Context, dimension string, filter models.CheckStatsFilter, limit int) ([]models.TopEntry, error) {
+	args := m.Called(ctx, dimension, filter, limit)
+	return args.Get(0).([]models.TopEntry), args.Error(1)
+}
+
+func (m *MockStatsStore) GetVersionAdoption(ctx context.Context, dimension string, filter models.CheckStatsFilter) ([]models.ProductAdoption, error) {
+	args := m.Called(ctx, dimension, filter)
+	return args.Get(0).([]models.ProductAdoption), args.Error(1)
+}
+
 // MockLogStore is a mock implementation of store.LogStore
 type MockLogStore struct {
 	mock.Mock
@@ -225,26 +345,62 @@ func (m *MockLogStore) CreateAdminLog(ctx context.Context, log *models.AdminLog)
 	return args.Error(0)
 }
 
-func (m *MockLogStore) GetLicenseCheckLogsByLicenseKey(ctx context.Context, licenseKey string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error) {
-	args := m.Called(ctx, licenseKey, statusCode, pagination)
-	return args.Get(0).([]models.LicenseCheckLog), args.Int(1), args.Error(2)
+func (m *MockLogStore) CopyLicenseCheckLogs(ctx context.Context, logs []*models.LicenseCheckLog) error {
+	args := m.Called(ctx, logs)
+	return args.Error(0)
 }
 
-func (m *MockLogStore) GetLicenseCheckLogsByProductID(ctx context.Context, productID string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error) {
-	args := m.Called(ctx, productID, statusCode, pagination)
-	return args.Get(0).([]models.LicenseCheckLog), args.Int(1), args.Error(2)
+func (m *MockLogStore) CopyAdminLogs(ctx context.Context, logs []*models.AdminLog) error {
+	args := m.Called(ctx, logs)
+	return args.Error(0)
 }
 
-func (m *MockLogStore) GetLicenseCheckLogsByProductGroupID(ctx context.Context, productGroupID string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error) {
-	args := m.Called(ctx, productGroupID, statusCode, pagination)
-	return args.Get(0).([]models.LicenseCheckLog), args.Int(1), args.Error(2)
+func (m *MockLogStore) QueryLicenseCheckLogs(ctx context.Context, filter models.LicenseCheckLogFilter, after *models.LogPageCursor, limit int) ([]models.LicenseCheckLog, *models.LogPageCursor, error) {
+	args := m.Called(ctx, filter, after, limit)
+	next, _ := args.Get(1).(*models.LogPageCursor)
+	return args.Get(0).([]models.LicenseCheckLog), next, args.Error(2)
 }
 
-func (m *MockLogStore) ListAdminLogs(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.AdminLog, int, error) {
-	args := m.Called(ctx, ownerID, pagination)
+func (m *MockLogStore) ListAdminLogs(ctx context.Context, filter models.AdminLogFilter, pagination models.PaginationParams) ([]models.AdminLog, int, error) {
+	args := m.Called(ctx, filter, pagination)
 	return args.Get(0).([]models.AdminLog), args.Int(1), args.Error(2)
 }
 
+func (m *MockLogStore) ExportLicenseCheckLogs(ctx context.Context, filter models.LicenseCheckLogFilter, fn func(*models.LicenseCheckLog) error) error {
+	args := m.Called(ctx, filter)
+	for _, log := range args.Get(0).([]models.LicenseCheckLog) {
+		if err := fn(&log); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockLogStore) ExportAdminLogs(ctx context.Context, filter models.AdminLogFilter, fn func(*models.AdminLog) error) error {
+	args := m.Called(ctx, filter)
+	for _, log := range args.Get(0).([]models.AdminLog) {
+		if err := fn(&log); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+// MockLogPrivacyStore is a mock implementation of store.LogPrivacyStore
+type MockLogPrivacyStore struct {
+	mock.Mock
+}
+
+func (m *MockLogPrivacyStore) EraseLicenseCheckLogs(ctx context.Context, req models.LogErasureRequest) (int64, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLogPrivacyStore) DeleteExpiredLicenseCheckLogs(ctx context.Context, defaultDays int, now time.Time) (int64, error) {
+	args := m.Called(ctx, defaultDays, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestCreateProductHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockProductStore := new(MockProductStore)
@@ -517,7 +673,7 @@ func TestUpdateLicenseHandler(t *testing.T) {
 	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	router := gin.New()
-	router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, mockLogStore))
+	router.PUT("/admin/keys", handlers.UpdateLicenseHandler(mockLicenseStore, new(MockProductStore), new(MockPlanStore), mockLogStore))
 
 	t.Run("UpdateLicense_Success", func(t *testing.T) {
 		key := "test-key"
@@ -762,7 +918,7 @@ func TestGenerateLicenseWithCustomSeparator(t *testing.T) {
 	mockLogStore.On("CreateAdminLog", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	mockProductGroupStore := new(MockProductGroupStore)
-	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, mockLogStore))
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, new(MockPlanStore), mockLogStore))
 
 	t.Run("Success_CustomSeparator", func(t *testing.T) {
 		productID := uuid.New()
@@ -969,7 +1125,7 @@ func TestGenerateLicenseWithLength(t *testing.T) {
 
 	mockProductGroupStore := new(MockProductGroupStore)
 	router := gin.New()
-	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, mockLogStore))
+	router.POST("/admin/keys", handlers.GenerateLicenseHandler(mockLicenseStore, mockProductStore, mockProductGroupStore, new(MockPlanStore), mockLogStore))
 
 	t.Run("LengthFromRequest", func(t *testing.T) {
 		productID := uuid.New()
@@ -1085,53 +1241,68 @@ func TestLogHandlers(t *testing.T) {
 		logs := []models.LicenseCheckLog{
 			{ID: uuid.New(), LicenseKey: key},
 		}
-		mockLogStore.On("GetLicenseCheckLogsByLicenseKey", mock.Anything, key, mock.Anything, mock.Anything).Return(logs, 1, nil)
+		mockLogStore.On("QueryLicenseCheckLogs", mock.Anything, models.LicenseCheckLogFilter{LicenseKey: key}, (*models.LogPageCursor)(nil), 10).Return(logs, nil, nil).Once()
 
 		req, _ := http.NewRequest("GET", "/admin/logs/license-checks?license_key="+key, nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusOK, w.Code)
+		var page models.LogPage[models.LicenseCheckLog]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Len(t, page.Items, 1)
+		assert.Empty(t, page.NextCursor)
 		mockLogStore.AssertExpectations(t)
 	})
 
-	t.Run("GetLicenseCheckLogsByProductID", func(t *testing.T) {
-		id := uuid.New().String()
-		logs := []models.LicenseCheckLog{
-			{ID: uuid.New(), ProductID: &uuid.Nil},
-		}
-		mockLogStore.On("GetLicenseCheckLogsByProductID", mock.Anything, id, mock.Anything, mock.Anything).Return(logs, 1, nil)
-
-		req, _ := http.NewRequest("GET", "/admin/logs/license-checks?product_id="+id, nil)
+	t.Run("GetLicenseCheckLogsWithCombinedFilters", func(t *testing.T) {
+		productID := uuid.New().String()
+		groupID := uuid.New().String()
+		next := &models.LogPageCursor{CreatedAt: time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC), ID: uuid.New()}
+		mockLogStore.On("QueryLicenseCheckLogs", mock.Anything, mock.MatchedBy(func(f models.LicenseCheckLogFilter) bool {
+			return f.ProductID == productID && f.ProductGroupID == groupID && f.OwnerID == "owner-1" &&
+				f.IPAddress == "10.0.0.0/8" && f.UserAgent == "curl" && f.Reason == "License expired" &&
+				f.Valid != nil && !*f.Valid && f.Since != nil
+		}), (*models.LogPageCursor)(nil), 2).Return([]models.LicenseCheckLog{{ID: uuid.New()}, {ID: next.ID}}, next, nil).Once()
+
+		req, _ := http.NewRequest("GET", "/admin/logs/license-checks?product_id="+productID+"&product_group_id="+groupID+
+			"&owner_id=owner-1&ip=10.0.0.0/8&user_agent=curl&valid=false&reason=License+expired&since=2026-05-01T00:00:00Z&limit=2", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusOK, w.Code)
-		mockLogStore.AssertExpectations(t)
-	})
+		var page models.LogPage[models.LicenseCheckLog]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, next.String(), page.NextCursor)
 
-	t.Run("GetLicenseCheckLogsByProductGroupID", func(t *testing.T) {
-		id := uuid.New().String()
-		logs := []models.LicenseCheckLog{
-			{ID: uuid.New()},
-		}
-		mockLogStore.On("GetLicenseCheckLogsByProductGroupID", mock.Anything, id, mock.Anything, mock.Anything).Return(logs, 1, nil)
+		// The next page starts after the cursor
+		mockLogStore.On("QueryLicenseCheckLogs", mock.Anything, models.LicenseCheckLogFilter{}, next, 2).Return([]models.LicenseCheckLog{}, nil, nil).Once()
 
-		req, _ := http.NewRequest("GET", "/admin/logs/license-checks?product_group_id="+id, nil)
-		w := httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/admin/logs/license-checks?limit=2&cursor="+page.NextCursor, nil)
+		w = httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"items":[],"limit":2}`, w.Body.String())
 		mockLogStore.AssertExpectations(t)
 	})
 
+	t.Run("GetLicenseCheckLogsInvalidParameters", func(t *testing.T) {
+		for _, query := range []string{"ip=10.0.0.0/33", "ip=localhost", "valid=maybe", "product_id=42", "cursor=not-a-cursor"} {
+			req, _ := http.NewRequest("GET", "/admin/logs/license-checks?"+query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
 	t.Run("GetAdminLogsByOwnerID", func(t *testing.T) {
 		ownerID := "test-owner-id"
 		logs := []models.AdminLog{
 			{ID: uuid.New(), OwnerID: &ownerID},
 		}
 		// Expect ListAdminLogs with specific ownerID
-		mockLogStore.On("ListAdminLogs", mock.Anything, &ownerID, mock.Anything).Return(logs, 1, nil)
+		mockLogStore.On("ListAdminLogs", mock.Anything, models.AdminLogFilter{OwnerID: &ownerID}, mock.Anything).Return(logs, 1, nil)
 
 		req, _ := http.NewRequest("GET", "/admin/logs/admin-actions?owner_id="+ownerID, nil)
 		w := httptest.NewRecorder()
@@ -1140,6 +1311,32 @@ func TestLogHandlers(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 		mockLogStore.AssertExpectations(t)
 	})
+
+	t.Run("GetAdminLogsWithFilters", func(t *testing.T) {
+		entityID := uuid.New()
+		since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+		mockLogStore.On("ListAdminLogs", mock.Anything, mock.MatchedBy(func(f models.AdminLogFilter) bool {
+			return f.OwnerID == nil && f.Actor == "alice" && f.Action == "UPDATE_PRODUCT" && f.EntityType == "products" &&
+				f.EntityID != nil && *f.EntityID == entityID &&
+				f.Since != nil && f.Since.Equal(since) && f.Until != nil && f.Until.Equal(until)
+		}), mock.Anything).Return([]models.AdminLog{}, 0, nil)
+
+		req, _ := http.NewRequest("GET", "/admin/logs/admin-actions?actor=alice&action=UPDATE_PRODUCT&entity_type=products&entity_id="+entityID.String()+
+			"&since=2026-03-01T00:00:00Z&until=2026-03-08T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockLogStore.AssertExpectations(t)
+
+		for _, query := range []string{"entity_id=42", "since=yesterday"} {
+			req, _ := http.NewRequest("GET", "/admin/logs/admin-actions?"+query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
 }
 
 func TestProductGroupFeatureReleaseHandlers(t *testing.T) {
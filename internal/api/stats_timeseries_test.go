@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"clortho/internal/api/handlers"
+	"clortho/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckStatsHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Timeseries_PassesFilter", func(t *testing.T) {
+		mockStatsStore := new(MockStatsStore)
+		router := gin.New()
+		router.GET("/admin/stats/timeseries", handlers.GetCheckTimeseriesHandler(mockStatsStore))
+
+		series := &models.Timeseries{
+			Interval: models.StatsIntervalDay,
+			Since:    since,
+			Until:    until,
+			Buckets: []models.TimeseriesBucket{
+				{Start: since, Checks: 10, Failures: 2, FailuresByReason: map[string]int{"License is revoked": 2}, UniqueLicenses: 3, UniqueNetworks: 4},
+				{Start: since.AddDate(0, 0, 1), FailuresByReason: map[string]int{}},
+			},
+		}
+		mockStatsStore.On("GetCheckTimeseries", mock.Anything, models.StatsIntervalDay, mock.MatchedBy(func(f models.CheckStatsFilter) bool {
+			return f.Since.Equal(since) && f.Until.Equal(until) &&
+				f.ProductGroupID != nil && *f.ProductGroupID == "group-1" &&
+				f.OwnerID != nil && *f.OwnerID == "owner-1" && f.ProductID == nil
+		})).Return(series, nil)
+
+		req, _ := http.NewRequest("GET", "/admin/stats/timeseries?interval=day&since=2026-03-01T00:00:00Z&until=2026-03-03T00:00:00Z&product_group_id=group-1&owner_id=owner-1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp models.Timeseries
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Buckets, 2)
+		assert.Equal(t, 2, resp.Buckets[0].FailuresByReason["License is revoked"])
+		mockStatsStore.AssertExpectations(t)
+	})
+
+	t.Run("Timeseries_RejectsBadInput", func(t *testing.T) {
+		mockStatsStore := new(MockStatsStore)
+		router := gin.New()
+		router.GET("/admin/stats/timeseries", handlers.GetCheckTimeseriesHandler(mockStatsStore))
+
+		for _, query := range []string{
+			"interval=minute",
+			"since=yesterday",
+			"since=2026-03-03T00:00:00Z&until=2026-03-01T00:00:00Z",
+			"interval=hour&duration=1y",
+		} {
+			req, _ := http.NewRequest("GET", "/admin/stats/timeseries?"+query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+		mockStatsStore.AssertNotCalled(t, "GetCheckTimeseries", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Top_Versions", func(t *testing.T) {
+		mockStatsStore := new(MockStatsStore)
+		router := gin.New()
+		router.GET("/admin/stats/top/:dimension", handlers.GetTopChecksHandler(mockStatsStore))
+
+		mockStatsStore.On("GetTopChecks", mock.Anything, models.TopDimensionVersions, mock.Anything, 5).
+			Return([]models.TopEntry{{Value: "2.0.0", Checks: 42, Failures: 1}}, nil)
+
+		req, _ := http.NewRequest("GET", "/admin/stats/top/versions?limit=5&duration=30d", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Dimension string            `json:"dimension"`
+			Items     []models.TopEntry `json:"items"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, models.TopDimensionVersions, resp.Dimension)
+		assert.Equal(t, []models.TopEntry{{Value: "2.0.0", Checks: 42, Failures: 1}}, resp.Items)
+		mockStatsStore.AssertExpectations(t)
+	})
+
+	t.Run("Adoption_PassesDimensionAndFilter", func(t *testing.T) {
+		mockStatsStore := new(MockStatsStore)
+		router := gin.New()
+		router.GET("/admin/stats/adoption", handlers.GetVersionAdoptionHandler(mockStatsStore))
+
+		productID := uuid.New()
+		adoption := []models.ProductAdoption{{
+			ProductID: productID,
+			Licenses:  4,
+			Versions: []models.VersionAdoption{
+				{Version: "1.5.0", Licenses: 3, Share: 0.75, Checks: 120, LastSeen: until},
+				{Version: "1.4.2", Licenses: 1, Share: 0.25, Checks: 30, LastSeen: since},
+			},
+		}}
+		mockStatsStore.On("GetVersionAdoption", mock.Anything, models.AdoptionDimensionSDKVersion, mock.MatchedBy(func(f models.CheckStatsFilter) bool {
+			return f.ProductID != nil && *f.ProductID == productID.String()
+		})).Return(adoption, nil)
+
+		req, _ := http.NewRequest("GET", "/admin/stats/adoption?dimension=sdk_version&product_id="+productID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Dimension string                   `json:"dimension"`
+			Products  []models.ProductAdoption `json:"products"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, models.AdoptionDimensionSDKVersion, resp.Dimension)
+		assert.Equal(t, adoption, resp.Products)
+		mockStatsStore.AssertExpectations(t)
+	})
+
+	t.Run("Adoption_UnknownDimension", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/admin/stats/adoption", handlers.GetVersionAdoptionHandler(new(MockStatsStore)))
+
+		req, _ := http.NewRequest("GET", "/admin/stats/adoption?dimension=os", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Top_UnknownDimension", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/admin/stats/top/:dimension", handlers.GetTopChecksHandler(new(MockStatsStore)))
+
+		req, _ := http.NewRequest("GET", "/admin/stats/top/countries", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
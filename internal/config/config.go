@@ -13,23 +13,226 @@ import (
 )
 
 type Config struct {
-	Port                      string          `yaml:"port"`
-	Debug                     bool            `yaml:"debug"`
-	DatabaseURL               string          `yaml:"database_url"`
-	AdminSecret               string          `yaml:"admin_secret"`
-	ResponseSigningPrivateKey string          `yaml:"response_signing_private_key"`
-	ResponseSigningPublicKey  string          `yaml:"response_signing_public_key"`
-	TrustedProxies            []string        `yaml:"trusted_proxies"`
-	RateLimitAdmin            RateLimitConfig `yaml:"rate_limit_admin"`
-	RateLimitCheck            RateLimitConfig `yaml:"rate_limit_check"`
+	Port                      string                 `yaml:"port"`
+	Debug                     bool                   `yaml:"debug"`
+	DatabaseURL               string                 `yaml:"database_url"`
+	AdminSecret               string                 `yaml:"admin_secret"`
+	ResponseSigningPrivateKey string                 `yaml:"response_signing_private_key"`
+	ResponseSigningPublicKey  string                 `yaml:"response_signing_public_key"`
+	// EphemeralSigningKeys is set when the signing keys were generated at
+	// startup, so nothing they sign verifies after a restart.
+	EphemeralSigningKeys      bool                   `yaml:"-"`
+	TrustedProxies            []string               `yaml:"trusted_proxies"`
+	RateLimitAdmin            RateLimitConfig        `yaml:"rate_limit_admin"`
+	RateLimitCheck            RateLimitConfig        `yaml:"rate_limit_check"`
+	Metrics                   MetricsConfig          `yaml:"metrics"`
+	Tracing                   TracingConfig          `yaml:"tracing"`
+	AsyncLog                  AsyncLogConfig         `yaml:"async_log"`
+	LicenseCache              LicenseCacheConfig     `yaml:"license_cache"`
+	AnomalyDetection          AnomalyDetectionConfig `yaml:"anomaly_detection"`
+	CheckRollups              CheckRollupConfig      `yaml:"check_rollups"`
+	LogPartitions             LogPartitionConfig     `yaml:"log_partitions"`
+	AdminLogChain             AdminLogChainConfig    `yaml:"admin_log_chain"`
+	LogForwarding             LogForwardingConfig    `yaml:"log_forwarding"`
+	LogPrivacy                LogPrivacyConfig       `yaml:"log_privacy"`
+	SuspensionResumeInterval  time.Duration          `yaml:"suspension_resume_interval"` // 0 disables automatic resumption
+	ShutdownTimeout           time.Duration          `yaml:"shutdown_timeout"`
 }
 
+// Rate limit backends.
+const (
+	RateLimitBackendMemory   = "memory"
+	RateLimitBackendPostgres = "postgres"
+)
+
 type RateLimitConfig struct {
 	RequestsPerSecond float64       `yaml:"requests_per_second"`
 	Burst             int           `yaml:"burst"`
 	Enabled           bool          `yaml:"enabled"`
 	CacheSize         int           `yaml:"cache_size"`
 	CacheTTL          time.Duration `yaml:"cache_ttl"`
+	// Backend is "memory" (per instance) or "postgres" (shared by all instances).
+	Backend string `yaml:"backend"`
+	// Policies add buckets on top of the per-IP one. Only used by /check.
+	Policies []RateLimitPolicy `yaml:"policies"`
+}
+
+// Values of RateLimitPolicy.KeyBy.
+const (
+	RateLimitKeyIP         = "ip"
+	RateLimitKeyLicenseKey = "license_key"
+	RateLimitKeyProduct    = "product"
+)
+
+// RateLimitPolicy is a bucket per distinct combination of the KeyBy values
+// of a request. Products can override RequestsPerSecond and Burst by Name
+// unless the policy is keyed by IP alone.
+type RateLimitPolicy struct {
+	Name              string   `yaml:"name"`
+	KeyBy             []string `yaml:"key_by"`
+	RequestsPerSecond float64  `yaml:"requests_per_second"`
+	Burst             int      `yaml:"burst"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+}
+
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Exporter is either "otlp" (OTLP over HTTP) or "stdout".
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Overflow behaviours of the async log writer when its queue is full.
+const (
+	AsyncLogOverflowBlock = "block"
+	AsyncLogOverflowDrop  = "drop"
+	AsyncLogOverflowSpill = "spill"
+)
+
+type AsyncLogConfig struct {
+	QueueSize     int           `yaml:"queue_size"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// Overflow is one of "block", "drop" or "spill".
+	Overflow string `yaml:"overflow"`
+	// SpillDir holds NDJSON files of entries that could not be queued or written.
+	SpillDir string `yaml:"spill_dir"`
+}
+
+type LicenseCacheConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Size    int           `yaml:"size"`
+	TTL     time.Duration `yaml:"ttl"`
+	// NegativeTTL is how long unknown keys are remembered.
+	NegativeTTL time.Duration `yaml:"negative_ttl"`
+	// Notify shares invalidations with other instances over Postgres LISTEN/NOTIFY.
+	Notify bool `yaml:"notify"`
+}
+
+// CheckRollupConfig controls the job that rolls license checks up into the
+// hourly and daily tables read by the stats endpoints.
+type CheckRollupConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often the job runs.
+	Interval time.Duration `yaml:"interval"`
+	// Delay is how long after an hour ends it is rolled up, leaving time for
+	// queued check logs to be written.
+	Delay time.Duration `yaml:"delay"`
+}
+
+// AdminLogChainConfig controls the signed checkpoints of the admin log hash
+// chain.
+type AdminLogChainConfig struct {
+	// CheckpointInterval is how often the chain head is signed with the
+	// response signing key (0 disables periodic checkpoints; the last entry of
+	// an expiring admin log partition is still signed before it is dropped).
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval"`
+}
+
+// Log sink types.
+const (
+	LogSinkSyslog = "syslog"
+	LogSinkHTTP   = "http"
+)
+
+// LogForwardingConfig controls the forwarder shipping new log rows to a
+// syslog or HTTP sink. What was delivered is tracked by a cursor per table in
+// Postgres, so rows are delivered at least once.
+type LogForwardingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often new rows are looked for.
+	Interval  time.Duration `yaml:"interval"`
+	BatchSize int           `yaml:"batch_size"`
+	// Settle is how long after being written a row is forwarded, leaving
+	// time for the transactions that wrote earlier rows to commit.
+	Settle time.Duration `yaml:"settle"`
+	// Tables are the log tables forwarded, both by default.
+	Tables []string `yaml:"tables"`
+	// Name identifies the cursors; a new name starts over from new rows.
+	Name string        `yaml:"name"`
+	Sink LogSinkConfig `yaml:"sink"`
+}
+
+// LogPrivacyConfig is the global privacy policy of the license check logs.
+// Products can override each setting.
+type LogPrivacyConfig struct {
+	// AnonymizeIP zeroes the last octet of IPv4 and all but the first 48 bits
+	// of IPv6 addresses.
+	AnonymizeIP bool `yaml:"anonymize_ip"`
+	// LicenseKey is "full", "hash" (SHA-256) or "mask" (first and last 4
+	// characters).
+	LicenseKey     string `yaml:"license_key"`
+	DropUserAgent  bool   `yaml:"drop_user_agent"`
+	RedactPayloads bool   `yaml:"redact_payloads"`
+	// RetentionDays is how many days checks are kept, 0 keeps them.
+	RetentionDays int `yaml:"retention_days"`
+	// RetentionInterval is how often expired checks are deleted, 0 disables
+	// deleting them.
+	RetentionInterval time.Duration `yaml:"retention_interval"`
+	// PolicyCacheTTL is how long the policy of a product is cached.
+	PolicyCacheTTL time.Duration `yaml:"policy_cache_ttl"`
+}
+
+type LogSinkConfig struct {
+	// Type is "syslog" (RFC 5424) or "http" (NDJSON POSTs).
+	Type string `yaml:"type"`
+	// Network is "tcp", "tls" or "udp" and Address the host:port of a syslog sink.
+	Network string `yaml:"network"`
+	Address string `yaml:"address"`
+	// Facility is the syslog facility code, 16 (local0) by default.
+	Facility int    `yaml:"facility"`
+	AppName  string `yaml:"app_name"`
+	// URL and Headers are used by HTTP sinks.
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// LogPartitionConfig controls the maintenance of the monthly partitions of
+// license_check_logs and admin_logs.
+type LogPartitionConfig struct {
+	// Enabled drops partitions past their retention. Partitions of the
+	// coming months are created either way.
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often partitions are maintained.
+	Interval time.Duration `yaml:"interval"`
+	// PremakeMonths is how many months ahead partitions are created.
+	PremakeMonths int `yaml:"premake_months"`
+	// ArchiveDir receives the archives of dropped partitions.
+	ArchiveDir       string             `yaml:"archive_dir"`
+	LicenseCheckLogs LogRetentionConfig `yaml:"license_check_logs"`
+	AdminLogs        LogRetentionConfig `yaml:"admin_logs"`
+}
+
+type LogRetentionConfig struct {
+	// RetentionMonths is how many whole months are kept before the current
+	// one. 0 keeps everything.
+	RetentionMonths int `yaml:"retention_months"`
+	// Archive writes partitions to ArchiveDir as gzipped NDJSON before they
+	// are dropped.
+	Archive bool `yaml:"archive"`
+}
+
+// AnomalyDetectionConfig holds the global thresholds of the leaked key
+// detector. Products can override each of them. 0 disables a threshold.
+type AnomalyDetectionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often the detector runs.
+	Interval time.Duration `yaml:"interval"`
+	// Window is how far back check logs are considered.
+	Window             time.Duration `yaml:"window"`
+	DistinctIPs        int           `yaml:"distinct_ips"`
+	DistinctUserAgents int           `yaml:"distinct_user_agents"`
+	DistinctPrefixes   int           `yaml:"distinct_prefixes"`
+	// AutoSuspend suspends flagged licenses.
+	AutoSuspend bool `yaml:"auto_suspend"`
 }
 
 func Load() (Config, error) {
@@ -73,6 +276,7 @@ func NewDefaultConfig() Config {
 			Enabled:           true,
 			CacheSize:         5000,
 			CacheTTL:          1 * time.Hour,
+			Backend:           RateLimitBackendMemory,
 		},
 		RateLimitCheck: RateLimitConfig{
 			RequestsPerSecond: 5,
@@ -80,7 +284,76 @@ func NewDefaultConfig() Config {
 			Enabled:           true,
 			CacheSize:         5000,
 			CacheTTL:          1 * time.Hour,
+			Backend:           RateLimitBackendMemory,
 		},
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Path:    "/metrics",
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Exporter:    "otlp",
+			ServiceName: "clortho",
+			SampleRatio: 1,
+		},
+		AsyncLog: AsyncLogConfig{
+			QueueSize:     10000,
+			BatchSize:     500,
+			FlushInterval: 1 * time.Second,
+			Overflow:      AsyncLogOverflowBlock,
+			SpillDir:      "spill",
+		},
+		LicenseCache: LicenseCacheConfig{
+			Enabled:     true,
+			Size:        10000,
+			TTL:         1 * time.Minute,
+			NegativeTTL: 10 * time.Second,
+			Notify:      true,
+		},
+		AnomalyDetection: AnomalyDetectionConfig{
+			Enabled:            false,
+			Interval:           5 * time.Minute,
+			Window:             24 * time.Hour,
+			DistinctIPs:        50,
+			DistinctUserAgents: 20,
+			DistinctPrefixes:   10,
+			AutoSuspend:        false,
+		},
+		CheckRollups: CheckRollupConfig{
+			Enabled:  true,
+			Interval: 5 * time.Minute,
+			Delay:    5 * time.Minute,
+		},
+		LogPartitions: LogPartitionConfig{
+			Enabled:       true,
+			Interval:      time.Hour,
+			PremakeMonths: 3,
+			ArchiveDir:    "archive",
+		},
+		AdminLogChain: AdminLogChainConfig{
+			CheckpointInterval: time.Hour,
+		},
+		LogForwarding: LogForwardingConfig{
+			Enabled:   false,
+			Interval:  10 * time.Second,
+			BatchSize: 500,
+			Settle:    30 * time.Second,
+			Name:      "default",
+			Sink: LogSinkConfig{
+				Type:     LogSinkSyslog,
+				Network:  "tcp",
+				Facility: 16,
+				AppName:  "clortho",
+				Timeout:  10 * time.Second,
+			},
+		},
+		LogPrivacy: LogPrivacyConfig{
+			LicenseKey:        "full",
+			RetentionInterval: time.Hour,
+			PolicyCacheTTL:    time.Minute,
+		},
+		SuspensionResumeInterval: time.Minute,
+		ShutdownTimeout:          30 * time.Second,
 	}
 }
 
@@ -119,6 +392,7 @@ func (c *Config) ensureKeys() error {
 
 	c.ResponseSigningPrivateKey = privBase64
 	c.ResponseSigningPublicKey = pubBase64
+	c.EphemeralSigningKeys = true
 
 	return nil
 }
@@ -139,4 +413,3 @@ func (c *Config) ensureAdminSecret() error {
 
 	return nil
 }
-
@@ -5,18 +5,26 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/exaring/otelpgx"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// SchemaVersion is the migration version this binary expects. Bump it with
+// every new file in migrations/.
+const SchemaVersion uint = 17
+
 func New(ctx context.Context, connectionString string) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database config: %w", err)
 	}
 
+	// Spans are only recorded once a tracer provider is installed
+	config.ConnConfig.Tracer = otelpgx.NewTracer()
+
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
@@ -44,3 +52,13 @@ func Migrate(connectionString, migrationsPath string) error {
 
 	return nil
 }
+
+// CurrentSchemaVersion reads the version recorded by golang-migrate.
+func CurrentSchemaVersion(ctx context.Context, pool *pgxpool.Pool) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	if err := pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty); err != nil {
+		return 0, false, fmt.Errorf("unable to read schema version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "clortho"
+
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	LicenseChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "license_checks_total",
+		Help:      "Number of /check requests by outcome.",
+	}, []string{"outcome"})
+
+	RateLimitRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Number of requests rejected by the rate limiter, by route.",
+	}, []string{"route"})
+
+	AsyncLogWriteFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "async_log_write_failures_total",
+		Help:      "Number of admin and license check log entries that failed to persist.",
+	}, []string{"log"})
+
+	AsyncLogDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "async_log_dropped_total",
+		Help:      "Number of log entries dropped because the async log queue was full.",
+	}, []string{"log"})
+
+	AsyncLogSpilledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "async_log_spilled_total",
+		Help:      "Number of log entries written to the on-disk spill file.",
+	}, []string{"log"})
+
+	AsyncLogQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "async_log_queue_depth",
+		Help:      "Number of log entries waiting to be written.",
+	}, []string{"log"})
+
+	LicenseCacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "license_cache_lookups_total",
+		Help:      "Number of license lookups by key served by the license cache, by result.",
+	}, []string{"result"})
+
+	LogForwardedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "log_forwarded_total",
+		Help:      "Number of log rows delivered to the log sink, by table.",
+	}, []string{"table"})
+
+	LogForwardFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "log_forward_failures_total",
+		Help:      "Number of failed deliveries of a batch of log rows to the log sink, by table.",
+	}, []string{"table"})
+
+	LicenseCheckStreamClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "license_check_stream_clients",
+		Help:      "Number of clients connected to the live license check stream.",
+	})
+
+	LicenseCheckStreamDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "license_check_stream_dropped_total",
+		Help:      "Number of license checks not sent to a stream client that fell behind.",
+	})
+)
+
+// License check outcomes used as the "outcome" label of LicenseChecksTotal.
+const (
+	CheckOutcomeValid        = "valid"
+	CheckOutcomeBadRequest   = "bad_request"
+	CheckOutcomeMissingKey   = "missing_key"
+	CheckOutcomeNotFound     = "not_found"
+	CheckOutcomeKeyRotated   = "key_rotated"
+	CheckOutcomeRevoked      = "revoked"
+	CheckOutcomeSuspended    = "suspended"
+	CheckOutcomeExpired      = "expired"
+	CheckOutcomeIPNotAllowed = "ip_not_allowed"
+	CheckOutcomeVersion      = "version"
+	CheckOutcomeFeature      = "feature"
+)
+
+// Log names used as the "log" label of the AsyncLog* metrics.
+const (
+	LogAdmin        = "admin"
+	LogLicenseCheck = "license_check"
+)
+
+// Cache lookup results used as the "result" label of LicenseCacheLookupsTotal.
+const (
+	CacheResultHit         = "hit"
+	CacheResultNegativeHit = "negative_hit"
+	CacheResultMiss        = "miss"
+)
+
+// RegisterPoolStats exposes the pgx pool statistics on the default registry.
+func RegisterPoolStats(pool *pgxpool.Pool) error {
+	return prometheus.Register(newPoolCollector(pool))
+}
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	idleConns            *prometheus.Desc
+	maxConns             *prometheus.Desc
+	totalConns           *prometheus.Desc
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		pool:                 pool,
+		acquireCount:         desc("acquire_total", "Cumulative count of successful connection acquires."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		acquiredConns:        desc("acquired_connections", "Connections currently in use."),
+		canceledAcquireCount: desc("canceled_acquire_total", "Cumulative count of acquires canceled by a context."),
+		constructingConns:    desc("constructing_connections", "Connections currently being established."),
+		emptyAcquireCount:    desc("empty_acquire_total", "Cumulative count of acquires that had to wait for a connection."),
+		idleConns:            desc("idle_connections", "Idle connections in the pool."),
+		maxConns:             desc("max_connections", "Maximum size of the pool."),
+		totalConns:           desc("total_connections", "Total connections in the pool."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.acquiredConns
+	ch <- c.canceledAcquireCount
+	ch <- c.constructingConns
+	ch <- c.emptyAcquireCount
+	ch <- c.idleConns
+	ch <- c.maxConns
+	ch <- c.totalConns
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(s.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+}
@@ -0,0 +1,51 @@
+package models
+
+import "github.com/google/uuid"
+
+// Levels a license setting is resolved from, most specific first.
+const (
+	PolicySourceRequest      = "request"
+	PolicySourcePlan         = "plan"
+	PolicySourceProduct      = "product"
+	PolicySourceProductGroup = "product_group"
+	PolicySourceDefault      = "default"
+)
+
+// LicensePolicy is the settings a license of a product is created with.
+type LicensePolicy struct {
+	LicensePrefix      string      `json:"license_prefix"`
+	LicenseSeparator   string      `json:"license_separator"`
+	LicenseCharset     string      `json:"license_charset"`
+	LicenseLength      int         `json:"license_length"`
+	LicenseType        LicenseType `json:"license_type,omitempty"`
+	LicenseDuration    string      `json:"license_duration,omitempty"`
+	AutoAllowedIP      bool        `json:"auto_allowed_ip"`
+	AutoAllowedIPLimit int         `json:"auto_allowed_ip_limit"`
+	DefaultFeatures    []string    `json:"default_features"`
+	DefaultReleases    []string    `json:"default_releases"`
+}
+
+// EffectiveLicensePolicy is the policy of a product once its group and the
+// defaults are applied. Sources maps each setting, by its JSON name, to the
+// level it was resolved from.
+type EffectiveLicensePolicy struct {
+	ProductID      uuid.UUID  `json:"product_id"`
+	ProductGroupID *uuid.UUID `json:"product_group_id,omitempty"`
+	LicensePolicy
+	Sources map[string]string `json:"sources"`
+}
+
+// LicensePolicyOverrides are the settings a request sets for one license.
+// Unset fields are resolved from the product.
+type LicensePolicyOverrides struct {
+	LicensePrefix      *string
+	LicenseLength      *int
+	LicenseType        *LicenseType
+	LicenseDuration    *string
+	AutoAllowedIP      *bool
+	AutoAllowedIPLimit *int
+	// DefaultFeatures and DefaultReleases replace the defaults when not nil,
+	// even when empty.
+	DefaultFeatures []string
+	DefaultReleases []string
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// How license keys are written to the check logs.
+const (
+	LogKeyFull = "full"
+	LogKeyHash = "hash"
+	LogKeyMask = "mask"
+)
+
+// hashedKeyPrefix marks a license key replaced by its hash in the check logs.
+const hashedKeyPrefix = "sha256:"
+
+// LogPrivacyPolicy controls what the license check logs keep about clients.
+// Products override the global policy field by field; unset fields fall back
+// to it.
+type LogPrivacyPolicy struct {
+	// AnonymizeIP zeroes the last octet of IPv4 addresses and all but the
+	// first 48 bits of IPv6 addresses.
+	AnonymizeIP *bool `json:"anonymize_ip,omitempty"`
+	// LicenseKey is LogKeyFull, LogKeyHash or LogKeyMask.
+	LicenseKey *string `json:"license_key,omitempty"`
+	// DropUserAgent leaves the user agent out.
+	DropUserAgent *bool `json:"drop_user_agent,omitempty"`
+	// RedactPayloads leaves the client fingerprint and the signed response
+	// token out of the payloads.
+	RedactPayloads *bool `json:"redact_payloads,omitempty"`
+	// RetentionDays is how many days checks are kept. 0 keeps them until
+	// their partition expires.
+	RetentionDays *int `json:"retention_days,omitempty"`
+}
+
+// Merge returns p with its unset fields taken from defaults.
+func (p LogPrivacyPolicy) Merge(defaults LogPrivacyPolicy) LogPrivacyPolicy {
+	if p.AnonymizeIP == nil {
+		p.AnonymizeIP = defaults.AnonymizeIP
+	}
+	if p.LicenseKey == nil {
+		p.LicenseKey = defaults.LicenseKey
+	}
+	if p.DropUserAgent == nil {
+		p.DropUserAgent = defaults.DropUserAgent
+	}
+	if p.RedactPayloads == nil {
+		p.RedactPayloads = defaults.RedactPayloads
+	}
+	if p.RetentionDays == nil {
+		p.RetentionDays = defaults.RetentionDays
+	}
+	return p
+}
+
+// StrictLogPrivacyPolicy keeps as little as possible. It is applied when the
+// policy of a product cannot be read.
+func StrictLogPrivacyPolicy() LogPrivacyPolicy {
+	yes, hash := true, LogKeyHash
+	return LogPrivacyPolicy{AnonymizeIP: &yes, LicenseKey: &hash, DropUserAgent: &yes, RedactPayloads: &yes}
+}
+
+// Apply rewrites log according to the policy. The payload maps are replaced
+// rather than modified, as they may be shared with the response.
+func (p LogPrivacyPolicy) Apply(log *LicenseCheckLog) {
+	if p.AnonymizeIP != nil && *p.AnonymizeIP {
+		log.IPAddress = AnonymizeIP(log.IPAddress)
+	}
+	if p.LicenseKey != nil && log.LicenseKey != "" {
+		switch *p.LicenseKey {
+		case LogKeyHash:
+			log.LicenseKey = HashLicenseKey(log.LicenseKey)
+		case LogKeyMask:
+			log.LicenseKey = MaskLicenseKey(log.LicenseKey)
+		}
+	}
+	if p.DropUserAgent != nil && *p.DropUserAgent {
+		log.UserAgent = ""
+	}
+	if p.RedactPayloads != nil && *p.RedactPayloads {
+		log.RequestPayload = withoutKeys(log.RequestPayload, "fingerprint")
+		log.ResponsePayload = withoutKeys(log.ResponsePayload, "fingerprint", "token")
+	}
+}
+
+func withoutKeys(m map[string]interface{}, keys ...string) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	for _, k := range keys {
+		delete(out, k)
+	}
+	return out
+}
+
+// AnonymizeIP zeroes the host part of an address: the last octet of IPv4
+// and all but the first 48 bits of IPv6. Anything else is dropped.
+func AnonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// HashLicenseKey returns the value a hashed key is logged as. Keys are
+// random, so the hash cannot be reversed, but checks of a known key can
+// still be found.
+func HashLicenseKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashedKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// MaskLicenseKey keeps the first and last 4 characters of a key.
+func MaskLicenseKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", len(key)-8) + key[len(key)-4:]
+}
+
+// LogErasureRequest selects the data subject whose license checks are
+// scrubbed: a license by ID or key, or every license of an owner.
+type LogErasureRequest struct {
+	LicenseID  *uuid.UUID `json:"license_id,omitempty"`
+	LicenseKey string     `json:"license_key,omitempty"`
+	OwnerID    string     `json:"owner_id,omitempty"`
+}
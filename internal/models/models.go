@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,8 +19,14 @@ type ProductGroup struct {
 	LicenseSeparator  string    `json:"license_separator,omitempty"`
 	LicenseCharset    string    `json:"license_charset,omitempty"`
 	LicenseLength     int       `json:"license_length,omitempty"`
+	LicenseType       LicenseType `json:"license_type,omitempty"`
+	LicenseDuration   string    `json:"license_duration,omitempty"`
 	AutoAllowedIP     bool      `json:"auto_allowed_ip,omitempty"`
 	AutoAllowedIPLimit int      `json:"auto_allowed_ip_limit,omitempty"`
+	// DefaultFeatures and DefaultReleases are linked to licenses of the
+	// group's products that list none.
+	DefaultFeatures   []string  `json:"default_features,omitempty"`
+	DefaultReleases   []string  `json:"default_releases,omitempty"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
@@ -35,11 +45,60 @@ type Product struct {
 	AutoAllowedIP     bool       `json:"auto_allowed_ip,omitempty"`
 	AutoAllowedIPLimit int       `json:"auto_allowed_ip_limit,omitempty"`
 	ProductGroupID    *uuid.UUID `json:"product_group_id,omitempty"`
+	// RateLimits overrides /check rate limit policies by policy name.
+	RateLimits        map[string]RateLimit `json:"rate_limits,omitempty"`
+	// AnomalyThresholds overrides the global anomaly detection settings.
+	AnomalyThresholds AnomalyThresholds `json:"anomaly_thresholds"`
+	// LogPrivacy overrides the global license check log privacy policy.
+	LogPrivacy        LogPrivacyPolicy `json:"log_privacy"`
+	// DefaultFeatures and DefaultReleases are linked to licenses that list
+	// none, overriding those of the group.
+	DefaultFeatures   []string   `json:"default_features,omitempty"`
+	DefaultReleases   []string   `json:"default_releases,omitempty"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+// AnomalyThresholds are the most distinct clients a license may be checked
+// from within the detection window. Unset fields fall back to the global
+// settings and 0 disables a threshold.
+type AnomalyThresholds struct {
+	DistinctIPs        *int  `json:"distinct_ips,omitempty"`
+	DistinctUserAgents *int  `json:"distinct_user_agents,omitempty"`
+	DistinctPrefixes   *int  `json:"distinct_prefixes,omitempty"`
+	AutoSuspend        *bool `json:"auto_suspend,omitempty"`
+}
+
+// Reasons a license is flagged by the anomaly detector.
+const (
+	FlagReasonDistinctIPs        = "distinct_ips"
+	FlagReasonDistinctUserAgents = "distinct_user_agents"
+	FlagReasonDistinctPrefixes   = "distinct_prefixes"
+)
 
+// LicenseFlag records that a license was checked from more distinct IPs,
+// user agents or /16 networks than its thresholds allow.
+type LicenseFlag struct {
+	LicenseID          uuid.UUID `json:"license_id"`
+	LicenseKey         string    `json:"license_key"`
+	ProductID          uuid.UUID `json:"product_id"`
+	OwnerID            *string   `json:"owner_id,omitempty"`
+	Reasons            []string  `json:"reasons"`
+	DistinctIPs        int       `json:"distinct_ips"`
+	DistinctUserAgents int       `json:"distinct_user_agents"`
+	DistinctPrefixes   int       `json:"distinct_prefixes"`
+	WindowSeconds      int       `json:"window_seconds"`
+	Suspended          bool      `json:"suspended"`
+	FirstDetectedAt    time.Time `json:"first_detected_at"`
+	LastDetectedAt     time.Time `json:"last_detected_at"`
+	// Thresholds are the limits in effect for the license when it was detected.
+	Thresholds *AnomalyThresholds `json:"thresholds,omitempty"`
+}
 
 type Feature struct {
 	ID             uuid.UUID  `json:"id"`
@@ -72,9 +131,10 @@ const (
 type LicenseStatus string
 
 const (
-	LicenseStatusActive  LicenseStatus = "active"
-	LicenseStatusRevoked LicenseStatus = "revoked"
-	LicenseStatusExpired LicenseStatus = "expired"
+	LicenseStatusActive    LicenseStatus = "active"
+	LicenseStatusRevoked   LicenseStatus = "revoked"
+	LicenseStatusExpired   LicenseStatus = "expired"
+	LicenseStatusSuspended LicenseStatus = "suspended"
 )
 
 type License struct {
@@ -88,13 +148,29 @@ type License struct {
 	ExpiresAt       *time.Time    `json:"expires_at,omitempty"`
 	AutoAllowedIP     bool          `json:"auto_allowed_ip,omitempty"`
 	AutoAllowedIPLimit int          `json:"auto_allowed_ip_limit,omitempty"`
+	PlanID          *uuid.UUID    `json:"plan_id,omitempty"`
 	Features        []string      `json:"features,omitempty"`
+	// FeatureValues holds the values of the features that have one, such as
+	// quotas set by the plan.
+	FeatureValues   map[string]interface{} `json:"feature_values,omitempty"`
 	Releases        []string      `json:"releases,omitempty"`
 	Status          LicenseStatus `json:"status"`
+	SuspensionReason *string      `json:"suspension_reason,omitempty"`
+	SuspendedUntil  *time.Time    `json:"suspended_until,omitempty"`
 	CreatedAt       time.Time     `json:"created_at"`
 	UpdatedAt       time.Time     `json:"updated_at"`
 }
 
+// IsSuspended reports whether the license is suspended at the given time. A
+// suspension whose resume time has passed no longer applies, even before the
+// status is reset.
+func (l *License) IsSuspended(now time.Time) bool {
+	if l.Status != LicenseStatusSuspended {
+		return false
+	}
+	return l.SuspendedUntil == nil || now.Before(*l.SuspendedUntil)
+}
+
 type LicenseCheckLog struct {
 	ID              uuid.UUID              `json:"id"`
 	ProductID       *uuid.UUID             `json:"product_id,omitempty"`
@@ -105,17 +181,164 @@ type LicenseCheckLog struct {
 	IPAddress       string                 `json:"ip_address"`
 	UserAgent       string                 `json:"user_agent"`
 	StatusCode      int                    `json:"status_code"`
+	Client          ClientMetadata         `json:"client"`
 	CreatedAt       time.Time              `json:"created_at"`
 }
 
+// Valid reports whether the check was answered 200 with a valid license.
+func (l *LicenseCheckLog) Valid() bool {
+	return l.StatusCode == 200 && l.ResponsePayload["valid"] == true
+}
+
+// ClientMetadata is the optional telemetry a client reports on a license
+// check. HostnameHash is hashed by the client; the hostname is never sent.
+type ClientMetadata struct {
+	AppVersion   string `json:"app_version,omitempty"`
+	OS           string `json:"os,omitempty"`
+	HostnameHash string `json:"hostname_hash,omitempty"`
+	SDKVersion   string `json:"sdk_version,omitempty"`
+}
+
+// LicenseCheckLogFilter selects license check logs. Empty fields match
+// everything and the time range is [Since, Until).
+type LicenseCheckLogFilter struct {
+	LicenseKey     string
+	ProductID      string
+	ProductGroupID string
+	// OwnerID matches the checks of the owner's products.
+	OwnerID string
+	// IPAddress is an address or a CIDR network.
+	IPAddress string
+	// UserAgent matches user agents containing it, ignoring case.
+	UserAgent  string
+	StatusCode *int
+	// Valid matches the checks answered 200 with a valid license, or all the
+	// others.
+	Valid *bool
+	// Reason matches the reason of an invalid answer or the error of a
+	// rejected check.
+	Reason           string
+	ClientAppVersion string
+	ClientOS         string
+	ClientSDKVersion string
+	Since            *time.Time
+	Until            *time.Time
+}
+
+// LogPageCursor is the position of the last log of a page. Logs are paged by
+// (created_at, id), so rows written meanwhile neither shift nor repeat pages.
+type LogPageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// String encodes the cursor as the opaque token handed to clients.
+func (c LogPageCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()))
+}
+
+var errInvalidLogPageCursor = errors.New("invalid cursor")
+
+// ParseLogPageCursor decodes a token returned by LogPageCursor.String.
+func ParseLogPageCursor(s string) (*LogPageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidLogPageCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errInvalidLogPageCursor
+	}
+	var cursor LogPageCursor
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, errInvalidLogPageCursor
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return nil, errInvalidLogPageCursor
+	}
+	return &cursor, nil
+}
+
+// LogPage is a page of logs, newest first. NextCursor is empty on the last
+// page.
+type LogPage[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
 type AdminLog struct {
 	ID         uuid.UUID              `json:"id"`
 	Action     string                 `json:"action"`
 	EntityType string                 `json:"entity_type"`
 	EntityID   *uuid.UUID             `json:"entity_id,omitempty"`
 	OwnerID    *string                `json:"owner_id,omitempty"`
+	Actor      *AdminActor            `json:"actor,omitempty"`
 	Details    map[string]interface{} `json:"details"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
 	CreatedAt  time.Time              `json:"created_at"`
+	// Seq, PrevHash and Hash place the entry in the admin log hash chain.
+	// Entries written before the chain was introduced have none.
+	Seq      *int64 `json:"seq,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// SystemActor is the subject of the actions taken by background jobs.
+const SystemActor = "system"
+
+// AdminActor is the principal behind an admin action: the subject and ID of
+// its admin token, and the client it acted from.
+type AdminActor struct {
+	Subject   string `json:"subject,omitempty"`
+	TokenID   string `json:"token_id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// FieldChange is the value of a field before and after an update.
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AdminLogCheckpoint is a signed statement of the admin log chain head:
+// the entry numbered Seq has hash Hash. PublicKey is the base64 Ed25519 key
+// that made Signature.
+type AdminLogCheckpoint struct {
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AdminLogChainReport is the outcome of walking the admin log hash chain.
+// When Valid is false, BrokenAt is the seq of the first broken link.
+type AdminLogChainReport struct {
+	Valid    bool  `json:"valid"`
+	Entries  int64 `json:"entries"`
+	FirstSeq int64 `json:"first_seq,omitempty"`
+	LastSeq  int64 `json:"last_seq,omitempty"`
+	// Checkpoints counts the checkpoints whose signature was verified;
+	// those signed with another key are counted in UnverifiedCheckpoints.
+	Checkpoints           int        `json:"checkpoints"`
+	UnverifiedCheckpoints int        `json:"unverified_checkpoints"`
+	BrokenAt              *int64     `json:"broken_at,omitempty"`
+	BrokenEntryID         *uuid.UUID `json:"broken_entry_id,omitempty"`
+	Reason                string     `json:"reason,omitempty"`
+}
+
+// AdminLogFilter narrows admin logs. Empty fields match everything; the time
+// range is [Since, Until).
+type AdminLogFilter struct {
+	OwnerID    *string
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   *uuid.UUID
+	Since      *time.Time
+	Until      *time.Time
 }
 
 type DashboardStats struct {
@@ -130,3 +353,103 @@ type DashboardStats struct {
 	TotalAdminActions  int `json:"total_admin_actions"`
 	RecentAdminLogs    []AdminLog `json:"recent_admin_logs"`
 }
+
+// LogPartition is the monthly partition of a log table holding the rows
+// created in [From, To).
+type LogPartition struct {
+	Table string
+	Name  string
+	From  time.Time
+	To    time.Time
+}
+
+// LogCursor is a position in a log table in the order rows were written:
+// just after the row written at IngestedAt with the given ID.
+type LogCursor struct {
+	IngestedAt time.Time
+	ID         uuid.UUID
+}
+
+// LogRecord is a row of a log table as forwarded to a log sink. Data is the
+// row as a JSON object.
+type LogRecord struct {
+	Table     string
+	Cursor    LogCursor
+	CreatedAt time.Time
+	Data      json.RawMessage
+}
+
+// Timeseries bucket sizes.
+const (
+	StatsIntervalHour = "hour"
+	StatsIntervalDay  = "day"
+	StatsIntervalWeek = "week"
+)
+
+// Top-N dimensions of license checks.
+const (
+	TopDimensionLicenses    = "licenses"
+	TopDimensionFailingKeys = "failing-keys"
+	TopDimensionUserAgents  = "user-agents"
+	TopDimensionVersions    = "versions"
+)
+
+// CheckStatsFilter narrows check analytics to a time range and, optionally,
+// to a product, a product group or the products of an owner.
+type CheckStatsFilter struct {
+	Since          time.Time
+	Until          time.Time
+	ProductID      *string
+	ProductGroupID *string
+	OwnerID        *string
+}
+
+// TimeseriesBucket holds the license checks of one interval, starting at
+// Start (UTC). Weeks start on Monday.
+type TimeseriesBucket struct {
+	Start            time.Time      `json:"start"`
+	Checks           int            `json:"checks"`
+	Failures         int            `json:"failures"`
+	FailuresByReason map[string]int `json:"failures_by_reason"`
+	UniqueLicenses   int            `json:"unique_licenses"`
+	// UniqueNetworks counts client IPs as networks, /24 for IPv4 and /48
+	// for IPv6, the way they are rolled up.
+	UniqueNetworks   int            `json:"unique_networks"`
+}
+
+type Timeseries struct {
+	Interval string             `json:"interval"`
+	Since    time.Time          `json:"since"`
+	Until    time.Time          `json:"until"`
+	Buckets  []TimeseriesBucket `json:"buckets"`
+}
+
+type TopEntry struct {
+	Value    string `json:"value"`
+	Checks   int    `json:"checks"`
+	Failures int    `json:"failures"`
+}
+
+// Client versions reported on license checks, used by adoption stats.
+const (
+	AdoptionDimensionAppVersion = "app_version"
+	AdoptionDimensionSDKVersion = "sdk_version"
+)
+
+// VersionAdoption counts the licenses whose latest check in a range reported
+// Version, and all the checks reporting it.
+type VersionAdoption struct {
+	Version  string    `json:"version"`
+	Licenses int       `json:"licenses"`
+	Share    float64   `json:"share"`
+	Checks   int       `json:"checks"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// ProductAdoption is the version adoption of the licenses of a product that
+// reported a version.
+type ProductAdoption struct {
+	ProductID uuid.UUID         `json:"product_id"`
+	Licenses  int               `json:"licenses"`
+	Versions  []VersionAdoption `json:"versions"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Plan is an edition of a product, or of every product of a group, such as
+// "Pro" or "Enterprise". Licenses on a plan get its features and releases,
+// and its seat limit, and follow them when the plan changes.
+type Plan struct {
+	ID             uuid.UUID  `json:"id"`
+	OwnerID        *string    `json:"owner_id,omitempty"`
+	ProductID      *uuid.UUID `json:"product_id,omitempty"`
+	ProductGroupID *uuid.UUID `json:"product_group_id,omitempty"`
+	Name           string     `json:"name"`
+	Code           string     `json:"code"`
+	Description    string     `json:"description,omitempty"`
+	// Features maps the feature codes of the plan to the value licenses get
+	// for them, such as true or a quota.
+	Features map[string]interface{} `json:"features"`
+	Releases []string               `json:"releases"`
+	// LicenseType and LicenseDuration are the term of new licenses on the
+	// plan.
+	LicenseType     LicenseType `json:"license_type,omitempty"`
+	LicenseDuration string      `json:"license_duration,omitempty"`
+	// SeatLimit is the auto_allowed_ip_limit of licenses on the plan: the
+	// client IPs they may auto-allow.
+	SeatLimit int       `json:"seat_limit,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeatureCodes returns the codes of the features of the plan, sorted.
+func (p *Plan) FeatureCodes() []string {
+	codes := make([]string, 0, len(p.Features))
+	for code := range p.Features {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Covers reports whether licenses of the product may be on the plan: the
+// plan belongs to the product or to its group.
+func (p *Plan) Covers(product *Product) bool {
+	if p.ProductID != nil {
+		return *p.ProductID == product.ID
+	}
+	return p.ProductGroupID != nil && product.ProductGroupID != nil && *p.ProductGroupID == *product.ProductGroupID
+}
+
+// ApplyTo puts license on the plan, replacing its features, releases and
+// auto_allowed_ip_limit. Whether it auto-allows IPs at all is left alone.
+func (p *Plan) ApplyTo(license *License) {
+	license.PlanID = &p.ID
+	license.Features = p.FeatureCodes()
+	license.FeatureValues = p.Features
+	license.Releases = append([]string{}, p.Releases...)
+	license.AutoAllowedIPLimit = p.SeatLimit
+}
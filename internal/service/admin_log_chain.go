@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// adminLogVerifyBatch is the number of entries read at a time when walking the chain.
+const adminLogVerifyBatch = 1000
+
+// AdminLogCheckpointMessage returns the bytes signed by a checkpoint of the
+// chain entry numbered seq with the given hash.
+func AdminLogCheckpointMessage(seq int64, hash string) []byte {
+	return []byte(fmt.Sprintf("clortho-admin-log-checkpoint:%d:%s", seq, hash))
+}
+
+// AdminLogCheckpointer periodically signs the head of the admin log chain so
+// that rewriting the chain up to a checkpoint needs the signing key.
+type AdminLogCheckpointer struct {
+	store      store.AdminLogChainStore
+	privateKey ed25519.PrivateKey
+	interval   time.Duration
+}
+
+func NewAdminLogCheckpointer(chainStore store.AdminLogChainStore, privateKeyBase64 string, interval time.Duration) (*AdminLogCheckpointer, error) {
+	privateKey, err := ParseSigningKey(privateKeyBase64)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &AdminLogCheckpointer{
+		store:      chainStore,
+		privateKey: privateKey,
+		interval:   interval,
+	}, nil
+}
+
+// Run signs a checkpoint every interval until ctx is done.
+func (c *AdminLogCheckpointer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.Checkpoint(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to checkpoint admin log chain", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Checkpoint signs the chain head if it moved since the latest checkpoint.
+// It returns the new checkpoint, or nil if there was nothing to sign.
+func (c *AdminLogCheckpointer) Checkpoint(ctx context.Context) (*models.AdminLogCheckpoint, error) {
+	seq, hash, err := c.store.AdminLogChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if seq == 0 {
+		return nil, nil
+	}
+	latest, err := c.store.LatestAdminLogCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Seq >= seq {
+		return nil, nil
+	}
+	return c.sign(ctx, seq, hash)
+}
+
+// CheckpointBefore signs the last chained entry created before the given
+// time, so the chain still verifies once the entries up to it are dropped.
+// It returns nil if there is no such entry or it is already signed.
+func (c *AdminLogCheckpointer) CheckpointBefore(ctx context.Context, before time.Time) (*models.AdminLogCheckpoint, error) {
+	seq, hash, err := c.store.LastChainedAdminLogBefore(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	if seq == 0 {
+		return nil, nil
+	}
+	return c.sign(ctx, seq, hash)
+}
+
+// sign stores a checkpoint of the entry numbered seq with the given hash. It
+// returns nil if that entry is already signed.
+func (c *AdminLogCheckpointer) sign(ctx context.Context, seq int64, hash string) (*models.AdminLogCheckpoint, error) {
+	checkpoint := &models.AdminLogCheckpoint{
+		Seq:       seq,
+		Hash:      hash,
+		PublicKey: base64.StdEncoding.EncodeToString(c.privateKey.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(c.privateKey, AdminLogCheckpointMessage(seq, hash))),
+	}
+	if err := c.store.CreateAdminLogCheckpoint(ctx, checkpoint); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	slog.Info("Signed admin log checkpoint", "seq", seq)
+	return checkpoint, nil
+}
+
+// VerifyAdminLogChain walks the admin log chain and reports the first broken
+// link: a missing entry, an entry whose content no longer matches its hash,
+// one that does not follow the previous hash, or a checkpoint that is badly
+// signed or disagrees with the entry it covers. Checkpoints are verified
+// with publicKey; those signed with another key are only counted.
+//
+// Entries dropped by log retention are not reported: the walk starts at the
+// oldest entry left, which must follow a signed checkpoint of its
+// predecessor. LogPartitionManager signs one before it drops a partition, so
+// any other missing prefix is reported as broken.
+func VerifyAdminLogChain(ctx context.Context, chainStore store.AdminLogChainStore, publicKey ed25519.PublicKey) (*models.AdminLogChainReport, error) {
+	// Entries chained after the head is read are left for the next run
+	headSeq, headHash, err := chainStore.AdminLogChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := chainStore.ListAdminLogCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.AdminLogChainReport{Valid: true}
+	broken := func(seq int64, reason string) (*models.AdminLogChainReport, error) {
+		report.Valid = false
+		report.BrokenAt = &seq
+		report.Reason = reason
+		return report, nil
+	}
+
+	keyBase64 := base64.StdEncoding.EncodeToString(publicKey)
+	signed := make(map[int64]string)
+	for _, cp := range checkpoints {
+		if cp.PublicKey != keyBase64 {
+			report.UnverifiedCheckpoints++
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(cp.Signature)
+		if err != nil || !ed25519.Verify(publicKey, AdminLogCheckpointMessage(cp.Seq, cp.Hash), signature) {
+			return broken(cp.Seq, "checkpoint signature is invalid")
+		}
+		signed[cp.Seq] = cp.Hash
+		report.Checkpoints++
+	}
+
+	var prevSeq int64
+	var prevHash string
+walk:
+	for {
+		logs, err := chainStore.ListChainedAdminLogs(ctx, prevSeq, adminLogVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range logs {
+			log := &logs[i]
+			seq := *log.Seq
+			if report.Entries > 0 && seq != prevSeq+1 {
+				return broken(prevSeq+1, "entry is missing")
+			}
+			if seq > headSeq {
+				break walk
+			}
+
+			expectedPrev := prevHash
+			if report.Entries == 0 {
+				// Older entries may only have been dropped by retention,
+				// which signs a checkpoint of the last one it drops
+				expectedPrev = store.GenesisHash
+				if seq > 1 {
+					var ok bool
+					if expectedPrev, ok = signed[seq-1]; !ok {
+						return broken(seq-1, "older entries are missing and no signed checkpoint covers them")
+					}
+				}
+			}
+			if log.PrevHash != expectedPrev {
+				return brokenEntry(report, log, "previous hash does not match the previous entry")
+			}
+
+			hash, err := store.AdminLogHash(log)
+			if err != nil {
+				return nil, err
+			}
+			if hash != log.Hash {
+				return brokenEntry(report, log, "content does not match its hash")
+			}
+			if signedHash, ok := signed[seq]; ok && signedHash != log.Hash {
+				return brokenEntry(report, log, "hash does not match its signed checkpoint")
+			}
+
+			if report.Entries == 0 {
+				report.FirstSeq = seq
+			}
+			report.Entries++
+			report.LastSeq = seq
+			prevSeq, prevHash = seq, log.Hash
+		}
+		if len(logs) < adminLogVerifyBatch {
+			break
+		}
+	}
+
+	if report.Entries == 0 {
+		// Every entry was dropped, which retention only does after signing
+		// a checkpoint of the head
+		if hash, ok := signed[headSeq]; headSeq > 0 && (!ok || hash != headHash) {
+			return broken(headSeq, "entries are missing and no signed checkpoint covers them")
+		}
+	} else {
+		if prevSeq < headSeq {
+			return broken(prevSeq+1, "entry is missing")
+		}
+		if prevHash != headHash {
+			return broken(headSeq, "last entry does not match the chain head")
+		}
+	}
+	for seq := range signed {
+		if seq > headSeq {
+			return broken(headSeq+1, "entries covered by a signed checkpoint are missing")
+		}
+	}
+	return report, nil
+}
+
+func brokenEntry(report *models.AdminLogChainReport, log *models.AdminLog, reason string) (*models.AdminLogChainReport, error) {
+	report.Valid = false
+	report.BrokenAt = log.Seq
+	report.BrokenEntryID = &log.ID
+	report.Reason = reason
+	return report, nil
+}
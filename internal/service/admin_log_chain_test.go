@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+type fakeAdminLogChainStore struct {
+	logs        []models.AdminLog
+	headSeq     int64
+	headHash    string
+	checkpoints []models.AdminLogCheckpoint
+}
+
+// append chains an entry the way PostgresLogStore does.
+func (f *fakeAdminLogChainStore) append(t *testing.T, action string) {
+	t.Helper()
+	f.appendAt(t, action, time.Date(2026, 10, 1, 12, 0, int(f.headSeq)+1, 0, time.UTC))
+}
+
+func (f *fakeAdminLogChainStore) appendAt(t *testing.T, action string, createdAt time.Time) {
+	t.Helper()
+	seq := f.headSeq + 1
+	log := models.AdminLog{
+		ID:         uuid.New(),
+		Action:     action,
+		EntityType: "LICENSE",
+		Actor:      &models.AdminActor{Subject: "admin"},
+		Details:    map[string]interface{}{"count": 1},
+		CreatedAt:  createdAt,
+		Seq:        &seq,
+		PrevHash:   f.headHash,
+	}
+	hash, err := store.AdminLogHash(&log)
+	if err != nil {
+		t.Fatalf("failed to hash entry: %v", err)
+	}
+	log.Hash = hash
+	f.logs = append(f.logs, log)
+	f.headSeq, f.headHash = seq, hash
+}
+
+func (f *fakeAdminLogChainStore) AdminLogChainHead(ctx context.Context) (int64, string, error) {
+	return f.headSeq, f.headHash, nil
+}
+
+func (f *fakeAdminLogChainStore) LastChainedAdminLogBefore(ctx context.Context, before time.Time) (int64, string, error) {
+	var seq int64
+	var hash string
+	for _, log := range f.logs {
+		if log.CreatedAt.Before(before) {
+			seq, hash = *log.Seq, log.Hash
+		}
+	}
+	return seq, hash, nil
+}
+
+func (f *fakeAdminLogChainStore) ListChainedAdminLogs(ctx context.Context, afterSeq int64, limit int) ([]models.AdminLog, error) {
+	var logs []models.AdminLog
+	for _, log := range f.logs {
+		if *log.Seq > afterSeq && len(logs) < limit {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (f *fakeAdminLogChainStore) ListAdminLogCheckpoints(ctx context.Context) ([]models.AdminLogCheckpoint, error) {
+	return f.checkpoints, nil
+}
+
+func (f *fakeAdminLogChainStore) LatestAdminLogCheckpoint(ctx context.Context) (*models.AdminLogCheckpoint, error) {
+	if len(f.checkpoints) == 0 {
+		return nil, nil
+	}
+	return &f.checkpoints[len(f.checkpoints)-1], nil
+}
+
+func (f *fakeAdminLogChainStore) CreateAdminLogCheckpoint(ctx context.Context, checkpoint *models.AdminLogCheckpoint) error {
+	f.checkpoints = append(f.checkpoints, *checkpoint)
+	return nil
+}
+
+func newChainFixture(t *testing.T, entries int) (*fakeAdminLogChainStore, *AdminLogCheckpointer, ed25519.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	chain := &fakeAdminLogChainStore{headHash: store.GenesisHash}
+	for i := 0; i < entries; i++ {
+		chain.append(t, "UPDATE")
+	}
+	checkpointer, err := NewAdminLogCheckpointer(chain, base64.StdEncoding.EncodeToString(priv), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create checkpointer: %v", err)
+	}
+	return chain, checkpointer, pub
+}
+
+func verifyChain(t *testing.T, chain *fakeAdminLogChainStore, pub ed25519.PublicKey) *models.AdminLogChainReport {
+	t.Helper()
+	report, err := VerifyAdminLogChain(context.Background(), chain, pub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return report
+}
+
+func assertBrokenAt(t *testing.T, report *models.AdminLogChainReport, seq int64) {
+	t.Helper()
+	if report.Valid {
+		t.Fatalf("expected the chain to be broken at %d", seq)
+	}
+	if report.BrokenAt == nil || *report.BrokenAt != seq {
+		t.Fatalf("expected the chain to be broken at %d, got %v (%s)", seq, report.BrokenAt, report.Reason)
+	}
+}
+
+func TestAdminLogCheckpointer_SignsNewHeads(t *testing.T) {
+	chain, checkpointer, pub := newChainFixture(t, 3)
+	ctx := context.Background()
+
+	checkpoint, err := checkpointer.Checkpoint(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkpoint == nil || checkpoint.Seq != 3 || checkpoint.Hash != chain.headHash {
+		t.Fatalf("expected a checkpoint of seq 3, got %+v", checkpoint)
+	}
+	signature, _ := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if !ed25519.Verify(pub, AdminLogCheckpointMessage(3, chain.headHash), signature) {
+		t.Error("expected a valid signature")
+	}
+
+	// Nothing new to sign
+	if checkpoint, _ := checkpointer.Checkpoint(ctx); checkpoint != nil {
+		t.Errorf("expected no checkpoint, got %+v", checkpoint)
+	}
+
+	chain.append(t, "CREATE")
+	if checkpoint, _ := checkpointer.Checkpoint(ctx); checkpoint == nil || checkpoint.Seq != 4 {
+		t.Errorf("expected a checkpoint of seq 4, got %+v", checkpoint)
+	}
+}
+
+func TestVerifyAdminLogChain(t *testing.T) {
+	t.Run("Intact", func(t *testing.T) {
+		chain, checkpointer, pub := newChainFixture(t, 5)
+		checkpointer.Checkpoint(context.Background())
+
+		report := verifyChain(t, chain, pub)
+		if !report.Valid || report.Entries != 5 || report.FirstSeq != 1 || report.LastSeq != 5 || report.Checkpoints != 1 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("EditedEntry", func(t *testing.T) {
+		chain, _, pub := newChainFixture(t, 5)
+		chain.logs[2].Details = map[string]interface{}{"count": 2}
+
+		report := verifyChain(t, chain, pub)
+		assertBrokenAt(t, report, 3)
+		if report.BrokenEntryID == nil || *report.BrokenEntryID != chain.logs[2].ID {
+			t.Errorf("expected the edited entry to be reported, got %v", report.BrokenEntryID)
+		}
+	})
+
+	t.Run("DeletedEntry", func(t *testing.T) {
+		chain, _, pub := newChainFixture(t, 5)
+		chain.logs = append(chain.logs[:1], chain.logs[2:]...)
+
+		assertBrokenAt(t, verifyChain(t, chain, pub), 2)
+	})
+
+	t.Run("TruncatedTail", func(t *testing.T) {
+		chain, checkpointer, pub := newChainFixture(t, 5)
+		checkpointer.Checkpoint(context.Background())
+
+		// Rolling the head back with the entries still leaves the checkpoint
+		chain.logs = chain.logs[:3]
+		chain.headSeq, chain.headHash = 3, chain.logs[2].Hash
+
+		assertBrokenAt(t, verifyChain(t, chain, pub), 4)
+	})
+
+	t.Run("RewrittenChain", func(t *testing.T) {
+		chain, checkpointer, pub := newChainFixture(t, 3)
+		checkpointer.Checkpoint(context.Background())
+
+		// Rehashing from an edited entry keeps the chain consistent but not
+		// with the signed checkpoint
+		chain.logs, chain.headSeq, chain.headHash = chain.logs[:1], 1, chain.logs[0].Hash
+		chain.append(t, "DELETE")
+		chain.append(t, "UPDATE")
+
+		assertBrokenAt(t, verifyChain(t, chain, pub), 3)
+	})
+
+	t.Run("ForgedCheckpoint", func(t *testing.T) {
+		chain, checkpointer, pub := newChainFixture(t, 3)
+		checkpointer.Checkpoint(context.Background())
+		chain.checkpoints[0].Hash = chain.logs[1].Hash
+
+		assertBrokenAt(t, verifyChain(t, chain, pub), 3)
+	})
+
+	t.Run("OtherKey", func(t *testing.T) {
+		chain, checkpointer, _ := newChainFixture(t, 3)
+		checkpointer.Checkpoint(context.Background())
+		otherPub, _, _ := ed25519.GenerateKey(nil)
+
+		report := verifyChain(t, chain, otherPub)
+		if !report.Valid || report.Checkpoints != 0 || report.UnverifiedCheckpoints != 1 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("DroppedByRetention", func(t *testing.T) {
+		chain, checkpointer, pub := newChainFixture(t, 5)
+		// Retention signs the last entry it drops
+		if _, err := checkpointer.CheckpointBefore(context.Background(), chain.logs[2].CreatedAt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		chain.logs = chain.logs[2:]
+
+		report := verifyChain(t, chain, pub)
+		if !report.Valid || report.FirstSeq != 3 || report.Entries != 3 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("DeletedPrefix", func(t *testing.T) {
+		chain, checkpointer, pub := newChainFixture(t, 5)
+		checkpointer.CheckpointBefore(context.Background(), chain.logs[1].CreatedAt)
+		chain.logs = chain.logs[2:]
+
+		assertBrokenAt(t, verifyChain(t, chain, pub), 2)
+	})
+
+	t.Run("AllDroppedByRetention", func(t *testing.T) {
+		chain, checkpointer, pub := newChainFixture(t, 3)
+		checkpointer.CheckpointBefore(context.Background(), chain.logs[2].CreatedAt.Add(time.Second))
+		chain.logs = nil
+
+		report := verifyChain(t, chain, pub)
+		if !report.Valid || report.Entries != 0 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("AllDeleted", func(t *testing.T) {
+		chain, _, pub := newChainFixture(t, 3)
+		chain.logs = nil
+
+		assertBrokenAt(t, verifyChain(t, chain, pub), 3)
+	})
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// AnomalyDetector periodically looks for licenses checked from unusually many
+// distinct IPs, user agents or networks, which usually means the key leaked or
+// is shared. Each new detection is recorded as a flag and an admin log entry,
+// and the license is suspended when its thresholds ask for it.
+type AnomalyDetector struct {
+	flagStore    store.FlagStore
+	licenseStore store.LicenseStore
+	logStore     store.LogStore
+	cfg          config.AnomalyDetectionConfig
+}
+
+func NewAnomalyDetector(flagStore store.FlagStore, licenseStore store.LicenseStore, logStore store.LogStore, cfg config.AnomalyDetectionConfig) *AnomalyDetector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 24 * time.Hour
+	}
+	return &AnomalyDetector{
+		flagStore:    flagStore,
+		licenseStore: licenseStore,
+		logStore:     logStore,
+		cfg:          cfg,
+	}
+}
+
+// Run detects anomalies every interval until ctx is done.
+func (d *AnomalyDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Detect(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Anomaly detection failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Detect runs a single detection pass over the configured window.
+func (d *AnomalyDetector) Detect(ctx context.Context) error {
+	now := time.Now()
+	defaults := models.AnomalyThresholds{
+		DistinctIPs:        &d.cfg.DistinctIPs,
+		DistinctUserAgents: &d.cfg.DistinctUserAgents,
+		DistinctPrefixes:   &d.cfg.DistinctPrefixes,
+		AutoSuspend:        &d.cfg.AutoSuspend,
+	}
+
+	flags, err := d.flagStore.FindAnomalousLicenses(ctx, now.Add(-d.cfg.Window), defaults)
+	if err != nil {
+		return err
+	}
+
+	for i := range flags {
+		flag := &flags[i]
+		flag.Reasons = flagReasons(flag)
+		flag.WindowSeconds = int(d.cfg.Window.Seconds())
+
+		previous, err := d.flagStore.UpsertLicenseFlag(ctx, flag)
+		if err != nil {
+			return err
+		}
+		// A license that stays anomalous is only reported again once a whole
+		// window passed without it being detected.
+		if previous != nil && previous.After(now.Add(-d.cfg.Window)) {
+			continue
+		}
+
+		if flag.Thresholds != nil && flag.Thresholds.AutoSuspend != nil && *flag.Thresholds.AutoSuspend {
+			if err := d.suspend(ctx, flag); err != nil {
+				slog.Error("Failed to suspend flagged license", "error", err, "license_id", flag.LicenseID)
+			}
+		}
+
+		slog.Warn("License flagged as possibly leaked", "license_id", flag.LicenseID, "reasons", flag.Reasons, "suspended", flag.Suspended)
+		AsyncLogAdminAction(ctx, d.logStore, &models.AdminLog{
+			Action:     "FLAG_LICENSE",
+			EntityType: "LICENSE",
+			EntityID:   &flag.LicenseID,
+			OwnerID:    flag.OwnerID,
+			Actor:      &models.AdminActor{Subject: models.SystemActor},
+			Details: map[string]interface{}{
+				"reasons":              flag.Reasons,
+				"distinct_ips":         flag.DistinctIPs,
+				"distinct_user_agents": flag.DistinctUserAgents,
+				"distinct_prefixes":    flag.DistinctPrefixes,
+				"window_seconds":       flag.WindowSeconds,
+				"suspended":            flag.Suspended,
+			},
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return nil
+}
+
+func flagReasons(flag *models.LicenseFlag) []string {
+	exceeds := func(count int, max *int) bool {
+		return max != nil && *max > 0 && count > *max
+	}
+
+	var reasons []string
+	if flag.Thresholds == nil {
+		return reasons
+	}
+	if exceeds(flag.DistinctIPs, flag.Thresholds.DistinctIPs) {
+		reasons = append(reasons, models.FlagReasonDistinctIPs)
+	}
+	if exceeds(flag.DistinctUserAgents, flag.Thresholds.DistinctUserAgents) {
+		reasons = append(reasons, models.FlagReasonDistinctUserAgents)
+	}
+	if exceeds(flag.DistinctPrefixes, flag.Thresholds.DistinctPrefixes) {
+		reasons = append(reasons, models.FlagReasonDistinctPrefixes)
+	}
+	return reasons
+}
+
+// suspend suspends an active license. Revoked, expired and already suspended
+// licenses are left alone.
+func (d *AnomalyDetector) suspend(ctx context.Context, flag *models.LicenseFlag) error {
+	license, err := d.licenseStore.GetLicense(ctx, flag.LicenseID.String())
+	if err != nil {
+		return err
+	}
+	if license.Status != models.LicenseStatusActive {
+		return nil
+	}
+
+	reason := "Anomalous usage: " + strings.Join(flag.Reasons, ", ")
+	license.Status = models.LicenseStatusSuspended
+	license.SuspensionReason = &reason
+	license.SuspendedUntil = nil
+	license.UpdatedAt = time.Now()
+	if err := d.licenseStore.UpdateLicense(ctx, license); err != nil {
+		return fmt.Errorf("failed to suspend license: %w", err)
+	}
+	if err := d.flagStore.SetLicenseFlagSuspended(ctx, flag.LicenseID.String()); err != nil {
+		return err
+	}
+	flag.Suspended = true
+	return nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+type fakeFlagStore struct {
+	store.FlagStore
+
+	found     []models.LicenseFlag
+	last      map[uuid.UUID]time.Time
+	suspended map[string]bool
+}
+
+func (f *fakeFlagStore) FindAnomalousLicenses(ctx context.Context, since time.Time, defaults models.AnomalyThresholds) ([]models.LicenseFlag, error) {
+	out := make([]models.LicenseFlag, len(f.found))
+	copy(out, f.found)
+	return out, nil
+}
+
+func (f *fakeFlagStore) UpsertLicenseFlag(ctx context.Context, flag *models.LicenseFlag) (*time.Time, error) {
+	var previous *time.Time
+	if t, ok := f.last[flag.LicenseID]; ok {
+		previous = &t
+	}
+	f.last[flag.LicenseID] = time.Now()
+	return previous, nil
+}
+
+func (f *fakeFlagStore) SetLicenseFlagSuspended(ctx context.Context, licenseID string) error {
+	f.suspended[licenseID] = true
+	return nil
+}
+
+type fakeLicenseStore struct {
+	store.LicenseStore
+
+	license *models.License
+	updates int
+}
+
+func (f *fakeLicenseStore) GetLicense(ctx context.Context, id string) (*models.License, error) {
+	l := *f.license
+	return &l, nil
+}
+
+func (f *fakeLicenseStore) UpdateLicense(ctx context.Context, license *models.License) error {
+	f.updates++
+	f.license = license
+	return nil
+}
+
+type recordingLogStore struct {
+	store.LogStore
+
+	mu     sync.Mutex
+	admins []*models.AdminLog
+}
+
+func (r *recordingLogStore) CreateAdminLog(ctx context.Context, log *models.AdminLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.admins = append(r.admins, log)
+	return nil
+}
+
+func (r *recordingLogStore) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.admins)
+}
+
+func TestAnomalyDetector_FlagsAndSuspendsOnce(t *testing.T) {
+	licenseID := uuid.New()
+	maxIPs, maxUAs, maxPrefixes, autoSuspend := 10, 0, 5, true
+	flags := &fakeFlagStore{
+		found: []models.LicenseFlag{{
+			LicenseID:          licenseID,
+			DistinctIPs:        40,
+			DistinctUserAgents: 3,
+			DistinctPrefixes:   12,
+			Thresholds: &models.AnomalyThresholds{
+				DistinctIPs:        &maxIPs,
+				DistinctUserAgents: &maxUAs,
+				DistinctPrefixes:   &maxPrefixes,
+				AutoSuspend:        &autoSuspend,
+			},
+		}},
+		last:      map[uuid.UUID]time.Time{},
+		suspended: map[string]bool{},
+	}
+	licenses := &fakeLicenseStore{license: &models.License{ID: licenseID, Status: models.LicenseStatusActive}}
+	logs := &recordingLogStore{}
+
+	d := NewAnomalyDetector(flags, licenses, logs, config.AnomalyDetectionConfig{Window: time.Hour})
+	if err := d.Detect(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if licenses.license.Status != models.LicenseStatusSuspended {
+		t.Errorf("expected license to be suspended, got %s", licenses.license.Status)
+	}
+	if !flags.suspended[licenseID.String()] {
+		t.Error("expected flag to be marked suspended")
+	}
+
+	// Still anomalous on the next pass: no second event or suspension
+	if err := d.Detect(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if licenses.updates != 1 {
+		t.Errorf("expected a single suspension, got %d", licenses.updates)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for logs.count() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := logs.count(); n != 1 {
+		t.Fatalf("expected 1 admin log, got %d", n)
+	}
+	logs.mu.Lock()
+	entry := logs.admins[0]
+	logs.mu.Unlock()
+	if entry.Action != "FLAG_LICENSE" || entry.Details["suspended"] != true {
+		t.Errorf("unexpected admin log: %+v", entry)
+	}
+	reasons := entry.Details["reasons"].([]string)
+	if len(reasons) != 2 || reasons[0] != models.FlagReasonDistinctIPs || reasons[1] != models.FlagReasonDistinctPrefixes {
+		t.Errorf("unexpected reasons: %v", reasons)
+	}
+}
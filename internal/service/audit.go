@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"clortho/internal/models"
+)
+
+// auditIgnoredFields change on every update and are left out of diffs.
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// AuditChanges returns the fields of an entity that differ between before
+// and after, keyed by their JSON names, or nil if none did.
+func AuditChanges(before, after interface{}) (map[string]models.FieldChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes map[string]models.FieldChange
+	record := func(field string) {
+		if auditIgnoredFields[field] || reflect.DeepEqual(beforeFields[field], afterFields[field]) {
+			return
+		}
+		if changes == nil {
+			changes = map[string]models.FieldChange{}
+		}
+		changes[field] = models.FieldChange{Before: beforeFields[field], After: afterFields[field]}
+	}
+	for field := range beforeFields {
+		record(field)
+	}
+	for field := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			record(field)
+		}
+	}
+	return changes, nil
+}
+
+// auditFields returns the JSON fields of v as they are logged.
+func auditFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audited entity: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audited entity: %w", err)
+	}
+	return fields, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/models"
+)
+
+func TestAuditChanges(t *testing.T) {
+	expiresAt := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	before := models.License{
+		ID:         uuid.New(),
+		Status:     models.LicenseStatusActive,
+		Features:   []string{"sso"},
+		AllowedIPs: []string{"10.0.0.1"},
+		UpdatedAt:  time.Now().Add(-time.Hour),
+	}
+	after := before
+	after.Status = models.LicenseStatusRevoked
+	after.Features = []string{"sso", "audit"}
+	after.ExpiresAt = &expiresAt
+	after.AllowedIPs = []string{"10.0.0.1"}
+	after.UpdatedAt = time.Now()
+
+	changes, err := AuditChanges(before, after)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %v", changes)
+	}
+	if c := changes["status"]; c.Before != "active" || c.After != "revoked" {
+		t.Errorf("unexpected status change: %+v", c)
+	}
+	if c := changes["features"]; len(c.Before.([]interface{})) != 1 || len(c.After.([]interface{})) != 2 {
+		t.Errorf("unexpected features change: %+v", c)
+	}
+	if c := changes["expires_at"]; c.Before != nil || c.After != "2026-12-31T00:00:00Z" {
+		t.Errorf("unexpected expires_at change: %+v", c)
+	}
+
+	changes, err = AuditChanges(before, before)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changes != nil {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/store"
+)
+
+// checkRollupChunk bounds the hours rolled up in one transaction.
+const checkRollupChunk = 24 * time.Hour
+
+// CheckRollupJob keeps the check rollups up to date. Each pass rolls up the
+// hours between the watermark and the last hour that ended at least Delay ago;
+// before anything was rolled up it starts at the oldest check.
+type CheckRollupJob struct {
+	store store.CheckRollupStore
+	cfg   config.CheckRollupConfig
+	now   func() time.Time
+}
+
+func NewCheckRollupJob(rollupStore store.CheckRollupStore, cfg config.CheckRollupConfig) *CheckRollupJob {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.Delay < 0 {
+		cfg.Delay = 0
+	}
+	return &CheckRollupJob{store: rollupStore, cfg: cfg, now: time.Now}
+}
+
+// Run rolls up checks every interval until ctx is done.
+func (j *CheckRollupJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Rollup(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Check rollup failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rollup catches the rollups up once.
+func (j *CheckRollupJob) Rollup(ctx context.Context) error {
+	from, err := j.store.CheckRollupWatermark(ctx)
+	if err != nil {
+		return err
+	}
+	if from == nil {
+		if from, err = j.store.EarliestCheckLog(ctx); err != nil || from == nil {
+			return err
+		}
+	}
+
+	to := j.now().Add(-j.cfg.Delay).UTC().Truncate(time.Hour)
+	return BackfillCheckRollups(ctx, j.store, *from, to, nil)
+}
+
+// BackfillCheckRollups rebuilds the rollups of [from, to) one chunk at a time,
+// calling progress after each chunk when it is not nil.
+func BackfillCheckRollups(ctx context.Context, rollupStore store.CheckRollupStore, from, to time.Time, progress func(from, to time.Time)) error {
+	from = from.UTC().Truncate(time.Hour)
+	for from.Before(to) {
+		end := from.Add(checkRollupChunk)
+		if end.After(to) {
+			end = to
+		}
+		if err := rollupStore.RollupCheckLogs(ctx, from, end); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(from, end)
+		}
+		from = end
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/store"
+)
+
+type fakeRollupStore struct {
+	store.CheckRollupStore
+
+	watermark *time.Time
+	earliest  *time.Time
+	ranges    [][2]time.Time
+}
+
+func (f *fakeRollupStore) CheckRollupWatermark(ctx context.Context) (*time.Time, error) {
+	return f.watermark, nil
+}
+
+func (f *fakeRollupStore) EarliestCheckLog(ctx context.Context) (*time.Time, error) {
+	return f.earliest, nil
+}
+
+func (f *fakeRollupStore) RollupCheckLogs(ctx context.Context, from, to time.Time) error {
+	f.ranges = append(f.ranges, [2]time.Time{from, to})
+	to = to.UTC()
+	f.watermark = &to
+	return nil
+}
+
+func TestCheckRollupJob(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 3, 0, 0, time.UTC)
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	t.Run("StartsAtOldestCheckInChunks", func(t *testing.T) {
+		earliest := time.Date(2026, 3, 8, 7, 42, 0, 0, time.UTC)
+		rollups := &fakeRollupStore{earliest: &earliest}
+		job := NewCheckRollupJob(rollups, config.CheckRollupConfig{Delay: 5 * time.Minute})
+		job.now = func() time.Time { return now }
+
+		if err := job.Rollup(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// 12:03 minus the delay is still within 11:00-12:00, which is not over
+		want := [][2]time.Time{{at(8, 7), at(9, 7)}, {at(9, 7), at(10, 7)}, {at(10, 7), at(10, 11)}}
+		if len(rollups.ranges) != len(want) {
+			t.Fatalf("expected %v, got %v", want, rollups.ranges)
+		}
+		for i := range want {
+			if !rollups.ranges[i][0].Equal(want[i][0]) || !rollups.ranges[i][1].Equal(want[i][1]) {
+				t.Errorf("chunk %d: expected %v, got %v", i, want[i], rollups.ranges[i])
+			}
+		}
+	})
+
+	t.Run("ContinuesFromWatermark", func(t *testing.T) {
+		watermark := at(10, 9)
+		rollups := &fakeRollupStore{watermark: &watermark}
+		job := NewCheckRollupJob(rollups, config.CheckRollupConfig{})
+		job.now = func() time.Time { return now }
+
+		if err := job.Rollup(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(rollups.ranges) != 1 || !rollups.ranges[0][0].Equal(at(10, 9)) || !rollups.ranges[0][1].Equal(at(10, 12)) {
+			t.Errorf("unexpected ranges: %v", rollups.ranges)
+		}
+
+		// Caught up: nothing to do until the next hour is over
+		rollups.ranges = nil
+		if err := job.Rollup(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(rollups.ranges) != 0 {
+			t.Errorf("expected no rollups, got %v", rollups.ranges)
+		}
+	})
+
+	t.Run("NoChecks", func(t *testing.T) {
+		rollups := &fakeRollupStore{}
+		if err := NewCheckRollupJob(rollups, config.CheckRollupConfig{}).Rollup(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(rollups.ranges) != 0 {
+			t.Errorf("expected no rollups, got %v", rollups.ranges)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"sync"
+
+	"clortho/internal/metrics"
+	"clortho/internal/models"
+)
+
+// licenseCheckFeedBuffer is how many checks a subscriber may fall behind
+// before further checks are dropped for it.
+const licenseCheckFeedBuffer = 256
+
+// LicenseCheckFeed fans out license checks to live subscribers as they are
+// logged. Publishing never blocks: a subscriber that does not keep up misses
+// checks, which are counted so it can tell its client.
+type LicenseCheckFeed struct {
+	mu     sync.RWMutex
+	subs   map[*LicenseCheckSubscription]struct{}
+	closed bool
+}
+
+func NewLicenseCheckFeed() *LicenseCheckFeed {
+	return &LicenseCheckFeed{subs: make(map[*LicenseCheckSubscription]struct{})}
+}
+
+// LicenseCheckSubscription receives the published checks its match function
+// accepts on C. C is closed when the feed is closed.
+type LicenseCheckSubscription struct {
+	C <-chan models.LicenseCheckLog
+
+	c     chan models.LicenseCheckLog
+	match func(*models.LicenseCheckLog) bool
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// Subscribe starts receiving the checks match accepts, or every check if
+// match is nil. match is called on the publishing goroutine and must be cheap.
+func (f *LicenseCheckFeed) Subscribe(match func(*models.LicenseCheckLog) bool) *LicenseCheckSubscription {
+	c := make(chan models.LicenseCheckLog, licenseCheckFeedBuffer)
+	sub := &LicenseCheckSubscription{C: c, c: c, match: match}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		close(c)
+		return sub
+	}
+	f.subs[sub] = struct{}{}
+	metrics.LicenseCheckStreamClients.Inc()
+	return sub
+}
+
+// Unsubscribe stops sub from receiving checks.
+func (f *LicenseCheckFeed) Unsubscribe(sub *LicenseCheckSubscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subs[sub]; ok {
+		delete(f.subs, sub)
+		metrics.LicenseCheckStreamClients.Dec()
+	}
+}
+
+// Publish sends a copy of log to every matching subscriber that has room.
+func (f *LicenseCheckFeed) Publish(log *models.LicenseCheckLog) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for sub := range f.subs {
+		if sub.match != nil && !sub.match(log) {
+			continue
+		}
+		select {
+		case sub.c <- *log:
+		default:
+			sub.mu.Lock()
+			sub.dropped++
+			sub.mu.Unlock()
+			metrics.LicenseCheckStreamDroppedTotal.Inc()
+		}
+	}
+}
+
+// Close ends every subscription and ignores later subscribers, so streams
+// finish when the server shuts down.
+func (f *LicenseCheckFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for sub := range f.subs {
+		close(sub.c)
+		delete(f.subs, sub)
+		metrics.LicenseCheckStreamClients.Dec()
+	}
+}
+
+// Dropped returns the number of checks missed since the last call.
+func (s *LicenseCheckSubscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.dropped
+	s.dropped = 0
+	return n
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+)
+
+func TestLicenseCheckFeed_FansOutToMatchingSubscribers(t *testing.T) {
+	feed := NewLicenseCheckFeed()
+	all := feed.Subscribe(nil)
+	keyA := feed.Subscribe(func(log *models.LicenseCheckLog) bool { return log.LicenseKey == "A" })
+
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "A"})
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "B"})
+
+	if len(all.C) != 2 {
+		t.Errorf("expected 2 checks for the unfiltered subscriber, got %d", len(all.C))
+	}
+	if len(keyA.C) != 1 || (<-keyA.C).LicenseKey != "A" {
+		t.Error("expected only the check of key A")
+	}
+
+	feed.Unsubscribe(keyA)
+	feed.Publish(&models.LicenseCheckLog{LicenseKey: "A"})
+	if len(keyA.C) != 0 {
+		t.Error("expected no checks after unsubscribing")
+	}
+}
+
+func TestLicenseCheckFeed_DropsForSlowSubscribers(t *testing.T) {
+	feed := NewLicenseCheckFeed()
+	sub := feed.Subscribe(nil)
+
+	for i := 0; i < licenseCheckFeedBuffer+3; i++ {
+		feed.Publish(&models.LicenseCheckLog{StatusCode: 200})
+	}
+	if n := sub.Dropped(); n != 3 {
+		t.Errorf("expected 3 dropped checks, got %d", n)
+	}
+	if n := sub.Dropped(); n != 0 {
+		t.Errorf("expected the count to reset, got %d", n)
+	}
+}
+
+func TestLicenseCheckFeed_CloseEndsSubscriptions(t *testing.T) {
+	feed := NewLicenseCheckFeed()
+	sub := feed.Subscribe(nil)
+	feed.Close()
+
+	if _, ok := <-sub.C; ok {
+		t.Error("expected the subscription to be closed")
+	}
+	if _, ok := <-feed.Subscribe(nil).C; ok {
+		t.Error("expected subscriptions after close to be closed")
+	}
+	// Checks logged while shutting down are ignored
+	feed.Publish(&models.LicenseCheckLog{})
+}
+
+func TestLogWriter_PublishesLicenseChecks(t *testing.T) {
+	w := NewLogWriter(&fakeLogStore{}, config.AsyncLogConfig{QueueSize: 10})
+	sub := w.LicenseChecks().Subscribe(nil)
+
+	AsyncLogLicenseCheck(context.Background(), w, &models.LicenseCheckLog{LicenseKey: "KEY"}, true, "")
+
+	select {
+	case log := <-sub.C:
+		if log.LicenseKey != "KEY" {
+			t.Errorf("unexpected check %+v", log)
+		}
+	default:
+		t.Fatal("expected the check to be published when queued")
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// Settings used when neither a product nor its group sets them.
+const (
+	DefaultLicensePrefix    = "LICENSE"
+	DefaultLicenseSeparator = "-"
+	DefaultLicenseCharset   = "a-z,A-Z,0-9"
+	DefaultLicenseLength    = 12
+)
+
+// ErrProductNotFound is returned when the product of a policy cannot be read.
+var ErrProductNotFound = errors.New("product not found")
+
+// ErrPlanNotCovered is returned for a plan of another product or group.
+var ErrPlanNotCovered = errors.New("plan does not cover the product")
+
+// LicensePolicyResolver resolves the settings licenses of a product are
+// created with. Each setting comes from the first level that sets it: the
+// request, the plan of the license, the product, its group, then the
+// defaults. Zero values are unset,
+// so a product cannot turn off auto_allowed_ip enabled on its group; a
+// request can.
+type LicensePolicyResolver struct {
+	productStore      store.ProductStore
+	productGroupStore store.ProductGroupStore
+}
+
+func NewLicensePolicyResolver(productStore store.ProductStore, productGroupStore store.ProductGroupStore) *LicensePolicyResolver {
+	return &LicensePolicyResolver{productStore: productStore, productGroupStore: productGroupStore}
+}
+
+// Resolve returns the effective policy of a product with overrides applied.
+func (r *LicensePolicyResolver) Resolve(ctx context.Context, productID string, overrides models.LicensePolicyOverrides) (*models.EffectiveLicensePolicy, error) {
+	return r.ResolvePlan(ctx, productID, nil, overrides)
+}
+
+// ResolvePlan is Resolve for a license on plan, which may be nil. It returns
+// ErrPlanNotCovered if the plan is not one of the product or its group.
+func (r *LicensePolicyResolver) ResolvePlan(ctx context.Context, productID string, plan *models.Plan, overrides models.LicensePolicyOverrides) (*models.EffectiveLicensePolicy, error) {
+	product, err := r.productStore.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProductNotFound, err)
+	}
+
+	var group *models.ProductGroup
+	if product.ProductGroupID != nil {
+		group, err = r.productGroupStore.GetProductGroup(ctx, product.ProductGroupID.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product group: %w", err)
+		}
+	}
+
+	if plan != nil && !plan.Covers(product) {
+		return nil, ErrPlanNotCovered
+	}
+
+	return ResolveLicensePolicy(product, group, plan, overrides), nil
+}
+
+// ResolveLicensePolicy merges the settings of overrides, plan, product and
+// group (plan and group may be nil) and fills in the defaults.
+func ResolveLicensePolicy(product *models.Product, group *models.ProductGroup, plan *models.Plan, overrides models.LicensePolicyOverrides) *models.EffectiveLicensePolicy {
+	if group == nil {
+		group = &models.ProductGroup{}
+	}
+	// A plan always sets its features, releases and seat limit, even to none
+	var planFeatures, planReleases []string
+	var planSeatLimit *int
+	if plan != nil {
+		planSeatLimit = &plan.SeatLimit
+		planFeatures = plan.FeatureCodes()
+		planReleases = append([]string{}, plan.Releases...)
+	} else {
+		plan = &models.Plan{}
+	}
+	p := &models.EffectiveLicensePolicy{
+		ProductID:      product.ID,
+		ProductGroupID: product.ProductGroupID,
+		Sources:        make(map[string]string),
+	}
+
+	p.LicensePrefix = resolveSetting(p.Sources, "license_prefix", overrides.LicensePrefix, "", product.LicensePrefix, group.LicensePrefix, DefaultLicensePrefix)
+	p.LicenseSeparator = resolveSetting(p.Sources, "license_separator", nil, "", product.LicenseSeparator, group.LicenseSeparator, DefaultLicenseSeparator)
+	p.LicenseCharset = resolveSetting(p.Sources, "license_charset", nil, "", product.LicenseCharset, group.LicenseCharset, DefaultLicenseCharset)
+	p.LicenseLength = resolveSetting(p.Sources, "license_length", overrides.LicenseLength, 0, product.LicenseLength, group.LicenseLength, DefaultLicenseLength)
+	p.LicenseType = resolveSetting(p.Sources, "license_type", overrides.LicenseType, plan.LicenseType, product.LicenseType, group.LicenseType, "")
+	p.LicenseDuration = resolveSetting(p.Sources, "license_duration", overrides.LicenseDuration, plan.LicenseDuration, product.LicenseDuration, group.LicenseDuration, "")
+	p.AutoAllowedIP = resolveSetting(p.Sources, "auto_allowed_ip", overrides.AutoAllowedIP, plan.SeatLimit > 0, product.AutoAllowedIP, group.AutoAllowedIP, false)
+	if overrides.AutoAllowedIPLimit == nil && planSeatLimit != nil {
+		p.AutoAllowedIPLimit = *planSeatLimit
+		p.Sources["auto_allowed_ip_limit"] = models.PolicySourcePlan
+	} else {
+		p.AutoAllowedIPLimit = resolveSetting(p.Sources, "auto_allowed_ip_limit", overrides.AutoAllowedIPLimit, 0, product.AutoAllowedIPLimit, group.AutoAllowedIPLimit, 0)
+	}
+	p.DefaultFeatures = resolveList(p.Sources, "default_features", overrides.DefaultFeatures, planFeatures, product.DefaultFeatures, group.DefaultFeatures)
+	p.DefaultReleases = resolveList(p.Sources, "default_releases", overrides.DefaultReleases, planReleases, product.DefaultReleases, group.DefaultReleases)
+	return p
+}
+
+// resolveSetting returns the first of override, plan, product and group that
+// is set, or def, and records where it came from in sources. A request may
+// set a zero value; the other levels may not.
+func resolveSetting[T comparable](sources map[string]string, name string, override *T, plan, product, group, def T) T {
+	var zero T
+	switch {
+	case override != nil:
+		sources[name] = models.PolicySourceRequest
+		return *override
+	case plan != zero:
+		sources[name] = models.PolicySourcePlan
+		return plan
+	case product != zero:
+		sources[name] = models.PolicySourceProduct
+		return product
+	case group != zero:
+		sources[name] = models.PolicySourceProductGroup
+		return group
+	default:
+		sources[name] = models.PolicySourceDefault
+		return def
+	}
+}
+
+// resolveList is resolveSetting for lists, which are unset when empty at
+// the product and group levels and when nil in a request or plan.
+func resolveList(sources map[string]string, name string, override, plan, product, group []string) []string {
+	switch {
+	case override != nil:
+		sources[name] = models.PolicySourceRequest
+		return override
+	case plan != nil:
+		sources[name] = models.PolicySourcePlan
+		return plan
+	case len(product) > 0:
+		sources[name] = models.PolicySourceProduct
+		return product
+	case len(group) > 0:
+		sources[name] = models.PolicySourceProductGroup
+		return group
+	default:
+		sources[name] = models.PolicySourceDefault
+		return []string{}
+	}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// fakeProductGroupStore serves product groups from a map.
+type fakeProductGroupStore struct {
+	store.ProductGroupStore
+
+	groups map[string]*models.ProductGroup
+}
+
+func (f *fakeProductGroupStore) GetProductGroup(ctx context.Context, id string) (*models.ProductGroup, error) {
+	if g, ok := f.groups[id]; ok {
+		return g, nil
+	}
+	return nil, errors.New("product group not found")
+}
+
+func TestResolveLicensePolicy_Provenance(t *testing.T) {
+	groupID := uuid.New()
+	group := &models.ProductGroup{
+		ID:                 groupID,
+		LicensePrefix:      "GRP",
+		LicenseSeparator:   "_",
+		LicenseType:        models.LicenseTypeTimed,
+		LicenseDuration:    "1y",
+		AutoAllowedIP:      true,
+		AutoAllowedIPLimit: 10,
+		DefaultFeatures:    []string{"sso"},
+		DefaultReleases:    []string{"1.0.0"},
+	}
+	product := &models.Product{
+		ID:               uuid.New(),
+		ProductGroupID:   &groupID,
+		LicensePrefix:    "PROD",
+		LicenseSeparator: "-",
+		LicenseLength:    20,
+		LicenseDuration:  "30d",
+		DefaultReleases:  []string{"2.0.0"},
+	}
+	limit := 0
+
+	p := ResolveLicensePolicy(product, group, nil, models.LicensePolicyOverrides{AutoAllowedIPLimit: &limit})
+
+	want := models.LicensePolicy{
+		LicensePrefix:      "PROD",
+		LicenseSeparator:   "-",
+		LicenseCharset:     DefaultLicenseCharset,
+		LicenseLength:      20,
+		LicenseType:        models.LicenseTypeTimed,
+		LicenseDuration:    "30d",
+		AutoAllowedIP:      true,
+		AutoAllowedIPLimit: 0,
+		DefaultFeatures:    []string{"sso"},
+		DefaultReleases:    []string{"2.0.0"},
+	}
+	if !reflect.DeepEqual(p.LicensePolicy, want) {
+		t.Errorf("unexpected policy %+v", p.LicensePolicy)
+	}
+	wantSources := map[string]string{
+		"license_prefix":        models.PolicySourceProduct,
+		"license_separator":     models.PolicySourceProduct,
+		"license_charset":       models.PolicySourceDefault,
+		"license_length":        models.PolicySourceProduct,
+		"license_type":          models.PolicySourceProductGroup,
+		"license_duration":      models.PolicySourceProduct,
+		"auto_allowed_ip":       models.PolicySourceProductGroup,
+		"auto_allowed_ip_limit": models.PolicySourceRequest,
+		"default_features":      models.PolicySourceProductGroup,
+		"default_releases":      models.PolicySourceProduct,
+	}
+	for name, source := range wantSources {
+		if p.Sources[name] != source {
+			t.Errorf("expected %s from %s, got %s", name, source, p.Sources[name])
+		}
+	}
+	if len(p.Sources) != len(wantSources) {
+		t.Errorf("unexpected sources %v", p.Sources)
+	}
+}
+
+func TestResolveLicensePolicy_Defaults(t *testing.T) {
+	p := ResolveLicensePolicy(&models.Product{ID: uuid.New()}, nil, nil, models.LicensePolicyOverrides{})
+
+	if p.LicensePrefix != DefaultLicensePrefix || p.LicenseSeparator != DefaultLicenseSeparator || p.LicenseLength != DefaultLicenseLength {
+		t.Errorf("unexpected defaults %+v", p.LicensePolicy)
+	}
+	if p.DefaultFeatures == nil || len(p.DefaultFeatures) != 0 {
+		t.Errorf("expected no default features, got %v", p.DefaultFeatures)
+	}
+	if p.LicenseType != "" || p.Sources["license_type"] != models.PolicySourceDefault {
+		t.Errorf("expected no default license type, got %q", p.LicenseType)
+	}
+	charset, err := ParseCharset(p.LicenseCharset)
+	if err != nil || charset != defaultCharset {
+		t.Errorf("expected the default charset, got %q (%v)", charset, err)
+	}
+}
+
+func TestResolveLicensePolicy_Plan(t *testing.T) {
+	groupID := uuid.New()
+	product := &models.Product{
+		ID:              uuid.New(),
+		ProductGroupID:  &groupID,
+		LicenseType:     models.LicenseTypePerpetual,
+		DefaultFeatures: []string{"basic"},
+		DefaultReleases: []string{"1.0.0"},
+	}
+	plan := &models.Plan{
+		ID:              uuid.New(),
+		ProductGroupID:  &groupID,
+		Features:        map[string]interface{}{"sso": true, "api_calls": 10000.0},
+		Releases:        []string{},
+		LicenseType:     models.LicenseTypeTimed,
+		LicenseDuration: "1y",
+		SeatLimit:       5,
+	}
+	duration := "30d"
+
+	p := ResolveLicensePolicy(product, nil, plan, models.LicensePolicyOverrides{LicenseDuration: &duration})
+
+	if p.LicenseType != models.LicenseTypeTimed || p.Sources["license_type"] != models.PolicySourcePlan {
+		t.Errorf("expected the type of the plan, got %q from %s", p.LicenseType, p.Sources["license_type"])
+	}
+	if p.LicenseDuration != "30d" || p.Sources["license_duration"] != models.PolicySourceRequest {
+		t.Errorf("expected the requested duration, got %q from %s", p.LicenseDuration, p.Sources["license_duration"])
+	}
+	if !p.AutoAllowedIP || p.AutoAllowedIPLimit != 5 || p.Sources["auto_allowed_ip_limit"] != models.PolicySourcePlan {
+		t.Errorf("expected the seat limit of the plan, got %v/%d", p.AutoAllowedIP, p.AutoAllowedIPLimit)
+	}
+	if !reflect.DeepEqual(p.DefaultFeatures, []string{"api_calls", "sso"}) {
+		t.Errorf("expected the features of the plan, got %v", p.DefaultFeatures)
+	}
+	// A plan without a seat limit sets none rather than that of the product
+	product.AutoAllowedIPLimit = 10
+	plan.SeatLimit = 0
+	if p := ResolveLicensePolicy(product, nil, plan, models.LicensePolicyOverrides{}); p.AutoAllowedIPLimit != 0 || p.Sources["auto_allowed_ip_limit"] != models.PolicySourcePlan {
+		t.Errorf("expected no seat limit from the plan, got %d from %s", p.AutoAllowedIPLimit, p.Sources["auto_allowed_ip_limit"])
+	}
+	// A plan without releases gives none rather than those of the product
+	if len(p.DefaultReleases) != 0 || p.Sources["default_releases"] != models.PolicySourcePlan {
+		t.Errorf("expected no releases from the plan, got %v from %s", p.DefaultReleases, p.Sources["default_releases"])
+	}
+}
+
+func TestLicensePolicyResolver_Errors(t *testing.T) {
+	groupID := uuid.New()
+	orphan := &models.Product{ID: uuid.New(), ProductGroupID: &groupID}
+	resolver := NewLicensePolicyResolver(
+		&fakeProductStore{products: map[string]*models.Product{orphan.ID.String(): orphan}},
+		&fakeProductGroupStore{},
+	)
+
+	if _, err := resolver.Resolve(context.Background(), uuid.New().String(), models.LicensePolicyOverrides{}); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("expected ErrProductNotFound, got %v", err)
+	}
+	// A group that cannot be read is not silently skipped
+	if _, err := resolver.Resolve(context.Background(), orphan.ID.String(), models.LicensePolicyOverrides{}); err == nil || errors.Is(err, ErrProductNotFound) {
+		t.Errorf("expected a product group error, got %v", err)
+	}
+
+	other := &models.Product{ID: uuid.New()}
+	resolver = NewLicensePolicyResolver(
+		&fakeProductStore{products: map[string]*models.Product{other.ID.String(): other}},
+		&fakeProductGroupStore{},
+	)
+	plan := &models.Plan{ID: uuid.New(), ProductID: &orphan.ID}
+	if _, err := resolver.ResolvePlan(context.Background(), other.ID.String(), plan, models.LicensePolicyOverrides{}); !errors.Is(err, ErrPlanNotCovered) {
+		t.Errorf("expected ErrPlanNotCovered, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/metrics"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// LogSink receives forwarded log rows.
+type LogSink interface {
+	// Send delivers the records in order. On error any of them may or may
+	// not have been delivered; they are all sent again.
+	Send(ctx context.Context, records []models.LogRecord) error
+	Close() error
+}
+
+// LogForwarder ships the rows written to the log tables to a LogSink. The
+// last row delivered from each table is saved in Postgres after every batch,
+// so rows are delivered at least once: a batch that fails, or whose cursor
+// could not be saved, is sent again.
+type LogForwarder struct {
+	store store.LogForwardStore
+	sink  LogSink
+	cfg   config.LogForwardingConfig
+	now   func() time.Time
+}
+
+func NewLogForwarder(forwardStore store.LogForwardStore, sink LogSink, cfg config.LogForwardingConfig) *LogForwarder {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if len(cfg.Tables) == 0 {
+		cfg.Tables = []string{store.LicenseCheckLogsTable, store.AdminLogsTable}
+	}
+	if cfg.Name == "" {
+		cfg.Name = "default"
+	}
+	return &LogForwarder{store: forwardStore, sink: sink, cfg: cfg, now: time.Now}
+}
+
+// Run forwards new rows every interval until ctx is done, then closes the sink.
+func (f *LogForwarder) Run(ctx context.Context) {
+	defer f.sink.Close()
+
+	ticker := time.NewTicker(f.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Forward(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Log forwarding failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Forward delivers the rows of every table written since its cursor and at
+// least settle ago. Without a cursor, a table is forwarded from now on.
+func (f *LogForwarder) Forward(ctx context.Context) error {
+	before := f.now().Add(-f.cfg.Settle)
+	for _, table := range f.cfg.Tables {
+		if err := f.forwardTable(ctx, table, before); err != nil {
+			return fmt.Errorf("failed to forward %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (f *LogForwarder) forwardTable(ctx context.Context, table string, before time.Time) error {
+	name := f.cfg.Name + ":" + table
+	cursor, err := f.store.LogForwardCursor(ctx, name)
+	if err != nil {
+		return err
+	}
+	if cursor == nil {
+		cursor = &models.LogCursor{IngestedAt: before}
+		if err := f.store.SaveLogForwardCursor(ctx, name, *cursor); err != nil {
+			return err
+		}
+		slog.Info("Started log forwarding", "cursor", name)
+		return nil
+	}
+
+	for {
+		records, err := f.store.ListLogRecords(ctx, table, *cursor, before, f.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		if err := f.sink.Send(ctx, records); err != nil {
+			metrics.LogForwardFailuresTotal.WithLabelValues(table).Inc()
+			return err
+		}
+		metrics.LogForwardedTotal.WithLabelValues(table).Add(float64(len(records)))
+
+		*cursor = records[len(records)-1].Cursor
+		if err := f.store.SaveLogForwardCursor(ctx, name, *cursor); err != nil {
+			return err
+		}
+		if len(records) < f.cfg.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+type fakeLogForwardStore struct {
+	records map[string][]models.LogRecord
+	cursors map[string]models.LogCursor
+}
+
+func (f *fakeLogForwardStore) LogForwardCursor(ctx context.Context, name string) (*models.LogCursor, error) {
+	cursor, ok := f.cursors[name]
+	if !ok {
+		return nil, nil
+	}
+	return &cursor, nil
+}
+
+func (f *fakeLogForwardStore) SaveLogForwardCursor(ctx context.Context, name string, cursor models.LogCursor) error {
+	f.cursors[name] = cursor
+	return nil
+}
+
+func (f *fakeLogForwardStore) ListLogRecords(ctx context.Context, table string, after models.LogCursor, before time.Time, limit int) ([]models.LogRecord, error) {
+	var records []models.LogRecord
+	for _, r := range f.records[table] {
+		c := r.Cursor
+		afterCursor := c.IngestedAt.After(after.IngestedAt) || (c.IngestedAt.Equal(after.IngestedAt) && strings.Compare(c.ID.String(), after.ID.String()) > 0)
+		if afterCursor && c.IngestedAt.Before(before) && len(records) < limit {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+// add writes a row to table at the given time.
+func (f *fakeLogForwardStore) add(table string, at time.Time) models.LogRecord {
+	id := uuid.New()
+	record := models.LogRecord{
+		Table:     table,
+		Cursor:    models.LogCursor{IngestedAt: at, ID: id},
+		CreatedAt: at,
+		Data:      json.RawMessage(fmt.Sprintf(`{"id":"%s"}`, id)),
+	}
+	f.records[table] = append(f.records[table], record)
+	return record
+}
+
+type fakeLogSink struct {
+	sent []models.LogRecord
+	err  error
+}
+
+func (s *fakeLogSink) Send(ctx context.Context, records []models.LogRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, records...)
+	return nil
+}
+
+func (s *fakeLogSink) Close() error { return nil }
+
+func TestLogForwarder_DeliversNewRowsAtLeastOnce(t *testing.T) {
+	forwardStore := &fakeLogForwardStore{records: map[string][]models.LogRecord{}, cursors: map[string]models.LogCursor{}}
+	sink := &fakeLogSink{}
+	f := NewLogForwarder(forwardStore, sink, config.LogForwardingConfig{
+		BatchSize: 2,
+		Settle:    time.Minute,
+		Tables:    []string{store.LicenseCheckLogsTable},
+	})
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Rows from before the first run are not forwarded
+	forwardStore.add(store.LicenseCheckLogsTable, now.Add(-time.Hour))
+	if err := f.Forward(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sink.sent) != 0 {
+		t.Fatalf("expected nothing to be sent, got %d rows", len(sink.sent))
+	}
+
+	var written []models.LogRecord
+	for i := 0; i < 3; i++ {
+		written = append(written, forwardStore.add(store.LicenseCheckLogsTable, now.Add(time.Duration(i)*time.Second)))
+	}
+	// Too recent, left to settle
+	recent := forwardStore.add(store.LicenseCheckLogsTable, now.Add(2*time.Minute-time.Second))
+
+	// A failed delivery keeps the cursor
+	now = now.Add(2 * time.Minute)
+	sink.err = errors.New("connection refused")
+	if err := f.Forward(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	sink.err = nil
+	if err := f.Forward(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sink.sent) != 3 {
+		t.Fatalf("expected 3 rows over two batches, got %d", len(sink.sent))
+	}
+	for i, r := range written {
+		if sink.sent[i].Cursor != r.Cursor {
+			t.Errorf("row %d: expected %v, got %v", i, r.Cursor, sink.sent[i].Cursor)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if err := f.Forward(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sink.sent) != 4 || sink.sent[3].Cursor != recent.Cursor {
+		t.Fatalf("expected the settled row to be sent once, got %d rows", len(sink.sent))
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			// Octet counting: "<length> <message>"
+			lengthStr, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(lengthStr))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	sink, err := NewLogSink(config.LogSinkConfig{Type: config.LogSinkSyslog, Network: "tcp", Address: ln.Addr().String(), Facility: 16, AppName: "clortho"})
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	record := models.LogRecord{
+		Table:     store.AdminLogsTable,
+		CreatedAt: time.Date(2026, 10, 1, 12, 0, 0, 123456789, time.UTC),
+		Data:      json.RawMessage(`{"action":"CREATE_PRODUCT"}`),
+	}
+	if err := sink.Send(context.Background(), []models.LogRecord{record, record}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-received:
+			fields := strings.SplitN(msg, " ", 8)
+			if len(fields) != 8 {
+				t.Fatalf("malformed message %q", msg)
+			}
+			// local0.info, version 1
+			if fields[0] != "<134>1" || fields[1] != "2026-10-01T12:00:00.123456Z" || fields[3] != "clortho" ||
+				fields[5] != "admin_logs" || fields[6] != "-" || fields[7] != `{"action":"CREATE_PRODUCT"}` {
+				t.Errorf("unexpected message %q", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a message")
+		}
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var body, table, auth string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, table, auth = string(data), r.Header.Get("X-Clortho-Log-Table"), r.Header.Get("Authorization")
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink, err := NewLogSink(config.LogSinkConfig{Type: config.LogSinkHTTP, URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	records := []models.LogRecord{
+		{Table: store.LicenseCheckLogsTable, Data: json.RawMessage(`{"n":1}`)},
+		{Table: store.LicenseCheckLogsTable, Data: json.RawMessage(`{"n":2}`)},
+	}
+	if err := sink.Send(context.Background(), records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body != "{\"n\":1}\n{\"n\":2}\n" || table != store.LicenseCheckLogsTable || auth != "Bearer token" {
+		t.Errorf("unexpected request: body %q, table %q, auth %q", body, table, auth)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := sink.Send(context.Background(), records); err == nil {
+		t.Error("expected an error on 503")
+	}
+}
+
+func TestNewLogSink_Validates(t *testing.T) {
+	for _, cfg := range []config.LogSinkConfig{
+		{Type: config.LogSinkSyslog},
+		{Type: config.LogSinkSyslog, Address: "localhost:514", Network: "sctp"},
+		{Type: config.LogSinkHTTP},
+		{Type: "kafka"},
+	} {
+		if _, err := NewLogSink(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// LogPartitionManager keeps the monthly partitions of the log tables: it
+// creates the partitions of the coming months and, when enabled, drops those
+// past their table's retention, archiving them first when configured.
+// Neither table has a default partition, so partitions are created even when
+// disabled. Admin log partitions are only dropped after a checkpoint of their
+// last entry is signed, which keeps the rest of the chain verifiable.
+type LogPartitionManager struct {
+	store        store.LogPartitionStore
+	cfg          config.LogPartitionConfig
+	checkpointer *AdminLogCheckpointer
+	now          func() time.Time
+}
+
+func NewLogPartitionManager(partitionStore store.LogPartitionStore, cfg config.LogPartitionConfig) *LogPartitionManager {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.PremakeMonths < 1 {
+		cfg.PremakeMonths = 1
+	}
+	return &LogPartitionManager{store: partitionStore, cfg: cfg, now: time.Now}
+}
+
+// UseCheckpointer sets the checkpointer that signs the last entry of an admin
+// log partition before it is dropped. Without one, expired admin log
+// partitions are kept.
+func (m *LogPartitionManager) UseCheckpointer(c *AdminLogCheckpointer) {
+	m.checkpointer = c
+}
+
+// Run maintains the partitions every interval until ctx is done.
+func (m *LogPartitionManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Maintain(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Log partition maintenance failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Maintain runs a single maintenance pass over both log tables.
+func (m *LogPartitionManager) Maintain(ctx context.Context) error {
+	tables := []struct {
+		name      string
+		retention config.LogRetentionConfig
+	}{
+		{store.LicenseCheckLogsTable, m.cfg.LicenseCheckLogs},
+		{store.AdminLogsTable, m.cfg.AdminLogs},
+	}
+
+	month := store.LogPartitionFor("", m.now()).From
+	for _, t := range tables {
+		for i := 0; i <= m.cfg.PremakeMonths; i++ {
+			if err := m.store.CreateLogPartition(ctx, t.name, month.AddDate(0, i, 0)); err != nil {
+				return err
+			}
+		}
+
+		if !m.cfg.Enabled || t.retention.RetentionMonths <= 0 {
+			continue
+		}
+		if err := m.expire(ctx, t.name, t.retention, month.AddDate(0, -t.retention.RetentionMonths, 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expire drops the partitions of table holding only rows from before cutoff.
+func (m *LogPartitionManager) expire(ctx context.Context, table string, retention config.LogRetentionConfig, cutoff time.Time) error {
+	partitions, err := m.store.ListLogPartitions(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		if p.To.After(cutoff) {
+			continue
+		}
+		if retention.Archive {
+			path, err := m.archive(ctx, p)
+			if err != nil {
+				// Keep the rows until they are safely archived
+				return fmt.Errorf("failed to archive partition %s: %w", p.Name, err)
+			}
+			slog.Info("Archived log partition", "partition", p.Name, "path", path)
+		}
+		if table == store.AdminLogsTable {
+			if m.checkpointer == nil {
+				return fmt.Errorf("admin log partition %s is kept: dropping it needs a signing key to checkpoint the chain", p.Name)
+			}
+			if _, err := m.checkpointer.CheckpointBefore(ctx, p.To); err != nil {
+				return fmt.Errorf("failed to checkpoint partition %s: %w", p.Name, err)
+			}
+		}
+		if err := m.store.DropLogPartition(ctx, p); err != nil {
+			return err
+		}
+		slog.Info("Dropped expired log partition", "partition", p.Name)
+	}
+	return nil
+}
+
+// archive writes a partition to <archive_dir>/<table>/<partition>.ndjson.gz
+// and returns the path. The file only appears once complete.
+func (m *LogPartitionManager) archive(ctx context.Context, p models.LogPartition) (string, error) {
+	dir := filepath.Join(m.cfg.ArchiveDir, p.Table)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, p.Name+".ndjson.gz")
+
+	f, err := os.CreateTemp(dir, p.Name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if err := m.store.ExportLogPartition(ctx, p, zw); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
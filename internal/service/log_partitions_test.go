@@ -0,0 +1,217 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+type fakeLogPartitionStore struct {
+	partitions map[string][]models.LogPartition
+	created    map[string]bool
+	dropped    []string
+	exportErr  error
+	onDrop     func(partition models.LogPartition)
+}
+
+func (f *fakeLogPartitionStore) ListLogPartitions(ctx context.Context, table string) ([]models.LogPartition, error) {
+	return f.partitions[table], nil
+}
+
+func (f *fakeLogPartitionStore) CreateLogPartition(ctx context.Context, table string, t time.Time) error {
+	f.created[store.LogPartitionFor(table, t).Name] = true
+	return nil
+}
+
+func (f *fakeLogPartitionStore) ExportLogPartition(ctx context.Context, partition models.LogPartition, w io.Writer) error {
+	if f.exportErr != nil {
+		return f.exportErr
+	}
+	_, err := io.WriteString(w, `{"partition":"`+partition.Name+`"}`+"\n")
+	return err
+}
+
+func (f *fakeLogPartitionStore) DropLogPartition(ctx context.Context, partition models.LogPartition) error {
+	if f.onDrop != nil {
+		f.onDrop(partition)
+	}
+	f.dropped = append(f.dropped, partition.Name)
+	return nil
+}
+
+func newPartitionFixture(exportErr error) *fakeLogPartitionStore {
+	f := &fakeLogPartitionStore{
+		partitions: map[string][]models.LogPartition{},
+		created:    map[string]bool{},
+		exportErr:  exportErr,
+	}
+	for _, table := range []string{store.LicenseCheckLogsTable, store.AdminLogsTable} {
+		for m := time.January; m <= time.June; m++ {
+			f.partitions[table] = append(f.partitions[table], store.LogPartitionFor(table, time.Date(2026, m, 1, 0, 0, 0, 0, time.UTC)))
+		}
+	}
+	return f
+}
+
+func TestLogPartitionManager_PremakesAndExpires(t *testing.T) {
+	partitions := newPartitionFixture(nil)
+	archiveDir := t.TempDir()
+	m := NewLogPartitionManager(partitions, config.LogPartitionConfig{
+		Enabled:          true,
+		PremakeMonths:    2,
+		ArchiveDir:       archiveDir,
+		LicenseCheckLogs: config.LogRetentionConfig{RetentionMonths: 3, Archive: true},
+	})
+	m.now = func() time.Time { return time.Date(2026, time.June, 18, 12, 0, 0, 0, time.UTC) }
+
+	if err := m.Maintain(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{
+		"license_check_logs_p202606", "license_check_logs_p202607", "license_check_logs_p202608",
+		"admin_logs_p202606", "admin_logs_p202607", "admin_logs_p202608",
+	} {
+		if !partitions.created[name] {
+			t.Errorf("expected %s to be created", name)
+		}
+	}
+	if len(partitions.created) != 6 {
+		t.Errorf("expected 6 partitions to be created, got %d", len(partitions.created))
+	}
+
+	// Retention of 3 months from June keeps March onwards; admin logs are kept forever
+	want := []string{"license_check_logs_p202601", "license_check_logs_p202602"}
+	if len(partitions.dropped) != len(want) || partitions.dropped[0] != want[0] || partitions.dropped[1] != want[1] {
+		t.Fatalf("expected %v to be dropped, got %v", want, partitions.dropped)
+	}
+
+	f, err := os.Open(filepath.Join(archiveDir, store.LicenseCheckLogsTable, "license_check_logs_p202601.ndjson.gz"))
+	if err != nil {
+		t.Fatalf("expected archive file: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("invalid gzip archive: %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if string(content) != `{"partition":"license_check_logs_p202601"}`+"\n" {
+		t.Errorf("unexpected archive content: %q", content)
+	}
+}
+
+func TestLogPartitionManager_KeepsPartitionWhenArchiveFails(t *testing.T) {
+	partitions := newPartitionFixture(errors.New("connection lost"))
+	archiveDir := t.TempDir()
+	m := NewLogPartitionManager(partitions, config.LogPartitionConfig{
+		Enabled:    true,
+		ArchiveDir: archiveDir,
+		AdminLogs:  config.LogRetentionConfig{RetentionMonths: 1, Archive: true},
+	})
+	m.now = func() time.Time { return time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC) }
+
+	if err := m.Maintain(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(partitions.dropped) != 0 {
+		t.Errorf("expected no partition to be dropped, got %v", partitions.dropped)
+	}
+
+	// No partial archive is left behind
+	entries, err := os.ReadDir(filepath.Join(archiveDir, store.AdminLogsTable))
+	if err != nil {
+		t.Fatalf("failed to read archive dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected an empty archive dir, got %d entries", len(entries))
+	}
+}
+
+func TestLogPartitionManager_CheckpointsAdminLogsBeforeDrop(t *testing.T) {
+	chain, checkpointer, pub := newChainFixture(t, 0)
+	for _, m := range []time.Month{time.January, time.February, time.March, time.June} {
+		chain.appendAt(t, "UPDATE", time.Date(2026, m, 15, 0, 0, 0, 0, time.UTC))
+	}
+
+	partitions := newPartitionFixture(nil)
+	partitions.onDrop = func(p models.LogPartition) {
+		// The chain must already be signed up to the partition's last entry
+		var kept []models.AdminLog
+		var last int64
+		for _, log := range chain.logs {
+			if log.CreatedAt.Before(p.To) {
+				last = *log.Seq
+			} else {
+				kept = append(kept, log)
+			}
+		}
+		if latest, _ := chain.LatestAdminLogCheckpoint(context.Background()); latest == nil || latest.Seq < last {
+			t.Errorf("%s dropped before its last entry was signed, latest checkpoint %+v", p.Name, latest)
+		}
+		chain.logs = kept
+	}
+
+	cfg := config.LogPartitionConfig{
+		Enabled:   true,
+		AdminLogs: config.LogRetentionConfig{RetentionMonths: 3},
+	}
+	m := NewLogPartitionManager(partitions, cfg)
+	m.now = func() time.Time { return time.Date(2026, time.June, 18, 12, 0, 0, 0, time.UTC) }
+
+	t.Run("WithoutCheckpointer", func(t *testing.T) {
+		if err := m.Maintain(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(partitions.dropped) != 0 {
+			t.Errorf("expected no partition to be dropped, got %v", partitions.dropped)
+		}
+	})
+
+	t.Run("WithCheckpointer", func(t *testing.T) {
+		m.UseCheckpointer(checkpointer)
+		if err := m.Maintain(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []string{"admin_logs_p202601", "admin_logs_p202602"}
+		if len(partitions.dropped) != len(want) || partitions.dropped[0] != want[0] || partitions.dropped[1] != want[1] {
+			t.Fatalf("expected %v to be dropped, got %v", want, partitions.dropped)
+		}
+
+		report := verifyChain(t, chain, pub)
+		if !report.Valid || report.FirstSeq != 3 || report.Entries != 2 || report.Checkpoints != 2 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+}
+
+func TestLogPartitionManager_DisabledOnlyPremakes(t *testing.T) {
+	partitions := newPartitionFixture(nil)
+	m := NewLogPartitionManager(partitions, config.LogPartitionConfig{
+		PremakeMonths:    1,
+		LicenseCheckLogs: config.LogRetentionConfig{RetentionMonths: 1},
+	})
+	m.now = func() time.Time { return time.Date(2026, time.June, 18, 12, 0, 0, 0, time.UTC) }
+
+	if err := m.Maintain(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !partitions.created["license_check_logs_p202607"] || !partitions.created["admin_logs_p202607"] {
+		t.Errorf("expected next month's partitions to be created, got %v", partitions.created)
+	}
+	if len(partitions.dropped) != 0 {
+		t.Errorf("expected no partition to be dropped, got %v", partitions.dropped)
+	}
+}
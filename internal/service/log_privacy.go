@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// LogPrivacy applies the privacy policy of their product to license checks
+// before they are logged. Product policies are cached for the configured TTL.
+type LogPrivacy struct {
+	productStore store.ProductStore
+	defaults     models.LogPrivacyPolicy
+	ttl          time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	policies map[uuid.UUID]cachedLogPrivacyPolicy
+}
+
+type cachedLogPrivacyPolicy struct {
+	policy  models.LogPrivacyPolicy
+	expires time.Time
+}
+
+func NewLogPrivacy(productStore store.ProductStore, cfg config.LogPrivacyConfig) *LogPrivacy {
+	if cfg.PolicyCacheTTL <= 0 {
+		cfg.PolicyCacheTTL = time.Minute
+	}
+	if cfg.LicenseKey == "" {
+		cfg.LicenseKey = models.LogKeyFull
+	}
+	return &LogPrivacy{
+		productStore: productStore,
+		defaults: models.LogPrivacyPolicy{
+			AnonymizeIP:    &cfg.AnonymizeIP,
+			LicenseKey:     &cfg.LicenseKey,
+			DropUserAgent:  &cfg.DropUserAgent,
+			RedactPayloads: &cfg.RedactPayloads,
+			RetentionDays:  &cfg.RetentionDays,
+		},
+		ttl:      cfg.PolicyCacheTTL,
+		now:      time.Now,
+		policies: make(map[uuid.UUID]cachedLogPrivacyPolicy),
+	}
+}
+
+// PolicyFor returns the policy of a product, or the global policy for checks
+// without one. If the product cannot be read the strict policy is returned,
+// so a lookup failure never logs more than configured.
+func (p *LogPrivacy) PolicyFor(ctx context.Context, productID *uuid.UUID) models.LogPrivacyPolicy {
+	if productID == nil {
+		return p.defaults
+	}
+
+	now := p.now()
+	p.mu.Lock()
+	cached, ok := p.policies[*productID]
+	p.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.policy
+	}
+
+	product, err := p.productStore.GetProduct(ctx, productID.String())
+	if err != nil {
+		slog.Warn("Failed to read log privacy policy, applying the strict policy", "product_id", productID, "error", err)
+		return models.StrictLogPrivacyPolicy()
+	}
+
+	policy := product.LogPrivacy.Merge(p.defaults)
+	p.mu.Lock()
+	p.policies[*productID] = cachedLogPrivacyPolicy{policy: policy, expires: now.Add(p.ttl)}
+	p.mu.Unlock()
+	return policy
+}
+
+// Apply rewrites log according to the policy of its product.
+func (p *LogPrivacy) Apply(ctx context.Context, log *models.LicenseCheckLog) {
+	p.PolicyFor(ctx, log.ProductID).Apply(log)
+}
+
+// LogRetentionJob deletes license checks past the retention of their product.
+type LogRetentionJob struct {
+	store store.LogPrivacyStore
+	cfg   config.LogPrivacyConfig
+	now   func() time.Time
+}
+
+func NewLogRetentionJob(privacyStore store.LogPrivacyStore, cfg config.LogPrivacyConfig) *LogRetentionJob {
+	if cfg.RetentionInterval <= 0 {
+		cfg.RetentionInterval = time.Hour
+	}
+	return &LogRetentionJob{store: privacyStore, cfg: cfg, now: time.Now}
+}
+
+// Run deletes expired checks every interval until ctx is done.
+func (j *LogRetentionJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.RetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Expire(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to delete expired license check logs", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Expire deletes the checks that are past their retention.
+func (j *LogRetentionJob) Expire(ctx context.Context) error {
+	deleted, err := j.store.DeleteExpiredLicenseCheckLogs(ctx, j.cfg.RetentionDays, j.now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		slog.Info("Deleted expired license check logs", "count", deleted)
+	}
+	return nil
+}
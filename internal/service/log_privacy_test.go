@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// fakeProductStore serves products from a map and counts the lookups.
+type fakeProductStore struct {
+	store.ProductStore
+
+	products map[string]*models.Product
+	lookups  int
+}
+
+func (f *fakeProductStore) GetProduct(ctx context.Context, id string) (*models.Product, error) {
+	f.lookups++
+	if p, ok := f.products[id]; ok {
+		return p, nil
+	}
+	return nil, errors.New("product not found")
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.42":        "203.0.113.0",
+		"::ffff:203.0.113.42": "203.0.113.0",
+		"2001:db8:abcd:12::1": "2001:db8:abcd::",
+		"not-an-ip":           "",
+		"":                    "",
+	}
+	for in, want := range tests {
+		if got := models.AnonymizeIP(in); got != want {
+			t.Errorf("AnonymizeIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMaskLicenseKey(t *testing.T) {
+	if got := models.MaskLicenseKey("ABCD-1234-EFGH"); got != "ABCD******EFGH" {
+		t.Errorf("unexpected mask %q", got)
+	}
+	if got := models.MaskLicenseKey("SHORT"); got != "*****" {
+		t.Errorf("expected short keys to be fully masked, got %q", got)
+	}
+}
+
+func TestLogPrivacy_AppliesProductPolicy(t *testing.T) {
+	mask, no := models.LogKeyMask, false
+	productID, unknownID := uuid.New(), uuid.New()
+	products := &fakeProductStore{products: map[string]*models.Product{
+		productID.String(): {ID: productID, LogPrivacy: models.LogPrivacyPolicy{LicenseKey: &mask, AnonymizeIP: &no}},
+	}}
+	privacy := NewLogPrivacy(products, config.LogPrivacyConfig{
+		AnonymizeIP:    true,
+		LicenseKey:     models.LogKeyHash,
+		DropUserAgent:  true,
+		RedactPayloads: true,
+		PolicyCacheTTL: time.Minute,
+	})
+
+	newLog := func(productID *uuid.UUID) *models.LicenseCheckLog {
+		return &models.LicenseCheckLog{
+			LicenseKey:      "ABCD-1234-EFGH",
+			ProductID:       productID,
+			IPAddress:       "203.0.113.42",
+			UserAgent:       "client/1.0",
+			RequestPayload:  map[string]interface{}{"key": "ABCD-1234-EFGH", "fingerprint": "fp"},
+			ResponsePayload: map[string]interface{}{"valid": true, "token": "signed"},
+		}
+	}
+
+	// The product overrides the key and IP settings and inherits the rest
+	log := newLog(&productID)
+	response := log.ResponsePayload
+	privacy.Apply(context.Background(), log)
+	if log.LicenseKey != "ABCD******EFGH" || log.IPAddress != "203.0.113.42" || log.UserAgent != "" {
+		t.Errorf("unexpected product policy result %+v", log)
+	}
+	if _, ok := log.RequestPayload["fingerprint"]; ok {
+		t.Error("expected the fingerprint to be redacted")
+	}
+	if _, ok := log.ResponsePayload["token"]; ok {
+		t.Error("expected the token to be redacted")
+	}
+	if _, ok := response["token"]; !ok {
+		t.Error("expected the original response to be left alone")
+	}
+
+	// Product policies are cached
+	privacy.Apply(context.Background(), newLog(&productID))
+	if products.lookups != 1 {
+		t.Errorf("expected 1 product lookup, got %d", products.lookups)
+	}
+
+	// Checks without a product get the global policy
+	log = newLog(nil)
+	privacy.Apply(context.Background(), log)
+	if log.LicenseKey != models.HashLicenseKey("ABCD-1234-EFGH") || log.IPAddress != "203.0.113.0" {
+		t.Errorf("unexpected global policy result %+v", log)
+	}
+
+	// A product that cannot be read gets the strict policy
+	privacy.defaults = models.LogPrivacyPolicy{}
+	log = newLog(&unknownID)
+	privacy.Apply(context.Background(), log)
+	if log.LicenseKey != models.HashLicenseKey("ABCD-1234-EFGH") || log.IPAddress != "203.0.113.0" || log.UserAgent != "" {
+		t.Errorf("expected the strict policy, got %+v", log)
+	}
+}
+
+func TestLogWriter_AppliesPrivacyBeforePublishing(t *testing.T) {
+	w := NewLogWriter(&fakeLogStore{}, config.AsyncLogConfig{QueueSize: 10})
+	w.UsePrivacy(NewLogPrivacy(&fakeProductStore{}, config.LogPrivacyConfig{AnonymizeIP: true}))
+	sub := w.LicenseChecks().Subscribe(nil)
+
+	w.CreateLicenseCheckLog(context.Background(), &models.LicenseCheckLog{LicenseKey: "KEY", IPAddress: "10.1.2.3"})
+
+	if log := <-sub.C; log.IPAddress != "10.1.2.0" || log.LicenseKey != "KEY" {
+		t.Errorf("unexpected published check %+v", log)
+	}
+}
+
+// fakeLogPrivacyStore records the retention the job asks for.
+type fakeLogPrivacyStore struct {
+	store.LogPrivacyStore
+
+	defaultDays int
+	now         time.Time
+}
+
+func (f *fakeLogPrivacyStore) DeleteExpiredLicenseCheckLogs(ctx context.Context, defaultDays int, now time.Time) (int64, error) {
+	f.defaultDays, f.now = defaultDays, now
+	return 3, nil
+}
+
+func TestLogRetentionJob_Expire(t *testing.T) {
+	fake := &fakeLogPrivacyStore{}
+	job := NewLogRetentionJob(fake, config.LogPrivacyConfig{RetentionDays: 30})
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	if err := job.Expire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.defaultDays != 30 || !fake.now.Equal(now) {
+		t.Errorf("unexpected retention %d at %v", fake.defaultDays, fake.now)
+	}
+}
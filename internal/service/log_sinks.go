@@ -0,0 +1,177 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+)
+
+const (
+	syslogSeverityInfo = 6
+	// syslogTimeFormat is RFC 3339 limited to the microseconds RFC 5424 allows.
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// NewLogSink returns the sink described by cfg.
+func NewLogSink(cfg config.LogSinkConfig) (LogSink, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	switch cfg.Type {
+	case config.LogSinkSyslog:
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("syslog sink address is required")
+		}
+		switch cfg.Network {
+		case "":
+			cfg.Network = "tcp"
+		case "tcp", "tls", "udp":
+		default:
+			return nil, fmt.Errorf("unknown syslog network %q", cfg.Network)
+		}
+		if cfg.AppName == "" {
+			cfg.AppName = "clortho"
+		}
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = "-"
+		}
+		return &syslogSink{cfg: cfg, hostname: hostname, procID: os.Getpid()}, nil
+	case config.LogSinkHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("http sink url is required")
+		}
+		return &httpSink{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}, nil
+	default:
+		return nil, fmt.Errorf("unknown log sink type %q", cfg.Type)
+	}
+}
+
+// syslogSink sends each record as an RFC 5424 message whose MSGID is the
+// table and MSG the row as JSON. Over TCP and TLS messages are framed by
+// octet counting (RFC 6587). The connection is reopened after a failure.
+type syslogSink struct {
+	cfg      config.LogSinkConfig
+	hostname string
+	procID   int
+	conn     net.Conn
+}
+
+func (s *syslogSink) Send(ctx context.Context, records []models.LogRecord) error {
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog sink: %w", err)
+		}
+		s.conn = conn
+	}
+
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := s.conn.SetWriteDeadline(deadline); err != nil {
+		return s.fail(err)
+	}
+
+	for _, record := range records {
+		msg := formatSyslogMessage(s.cfg.Facility, s.hostname, s.cfg.AppName, s.procID, record)
+		if s.cfg.Network != "udp" {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+		if _, err := s.conn.Write(msg); err != nil {
+			return s.fail(err)
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	if s.cfg.Network == "tls" {
+		return (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", s.cfg.Address)
+	}
+	return dialer.DialContext(ctx, s.cfg.Network, s.cfg.Address)
+}
+
+func (s *syslogSink) fail(err error) error {
+	s.conn.Close()
+	s.conn = nil
+	return fmt.Errorf("failed to write to syslog sink: %w", err)
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// formatSyslogMessage returns the RFC 5424 message of a record:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func formatSyslogMessage(facility int, hostname, appName string, procID int, record models.LogRecord) []byte {
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		facility*8+syslogSeverityInfo,
+		record.CreatedAt.UTC().Format(syslogTimeFormat),
+		hostname,
+		appName,
+		procID,
+		record.Table,
+		record.Data,
+	))
+}
+
+// httpSink POSTs each batch as NDJSON, one row per line, with the table in
+// the X-Clortho-Log-Table header. Any status other than 2xx is a failure.
+type httpSink struct {
+	cfg    config.LogSinkConfig
+	client *http.Client
+}
+
+func (s *httpSink) Send(ctx context.Context, records []models.LogRecord) error {
+	var body bytes.Buffer
+	for _, record := range records {
+		body.Write(record.Data)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("X-Clortho-Log-Table", records[0].Table)
+	for name, value := range s.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to http sink: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http sink answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
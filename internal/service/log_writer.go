@@ -0,0 +1,349 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/metrics"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+const (
+	logWriteAttempts = 3
+	logWriteTimeout  = 10 * time.Second
+)
+
+// LogWriter is a store.LogStore whose Create methods are backed by bounded
+// queues that are flushed in batches with COPY. Reads go straight to the
+// wrapped store. Start must be called before use and Close on shutdown so
+// queued entries are flushed. License checks are also published to a
+// LicenseCheckFeed as they are queued, after the privacy policy is applied.
+type LogWriter struct {
+	store.LogStore
+
+	checks    *logBatcher[*models.LicenseCheckLog]
+	admins    *logBatcher[*models.AdminLog]
+	checkFeed *LicenseCheckFeed
+	privacy   *LogPrivacy
+}
+
+func NewLogWriter(logStore store.LogStore, cfg config.AsyncLogConfig) *LogWriter {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = config.AsyncLogOverflowBlock
+	}
+
+	return &LogWriter{
+		LogStore:  logStore,
+		checks:    newLogBatcher(metrics.LogLicenseCheck, cfg, logStore.CopyLicenseCheckLogs),
+		admins:    newLogBatcher(metrics.LogAdmin, cfg, logStore.CopyAdminLogs),
+		checkFeed: NewLicenseCheckFeed(),
+	}
+}
+
+// Start replays entries spilled to disk by a previous run and starts the flushers.
+func (w *LogWriter) Start() {
+	w.checks.start()
+	w.admins.start()
+}
+
+// Close stops accepting entries and flushes what is queued, giving up when ctx is done.
+func (w *LogWriter) Close(ctx context.Context) error {
+	w.checks.close()
+	w.admins.close()
+
+	done := make(chan struct{})
+	go func() {
+		w.checks.wg.Wait()
+		w.admins.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("log writer did not flush before shutdown: %w", ctx.Err())
+	}
+}
+
+// CreateLicenseCheckLog queues the entry. The ID and creation time are assigned when it is written.
+func (w *LogWriter) CreateLicenseCheckLog(ctx context.Context, log *models.LicenseCheckLog) error {
+	if w.privacy != nil {
+		w.privacy.Apply(ctx, log)
+	}
+	w.checkFeed.Publish(log)
+	w.checks.enqueue(log)
+	return nil
+}
+
+// CreateAdminLog queues the entry. The ID is assigned when it is written.
+func (w *LogWriter) CreateAdminLog(ctx context.Context, log *models.AdminLog) error {
+	w.admins.enqueue(log)
+	return nil
+}
+
+// UsePrivacy applies privacy to license checks before they are published
+// and queued. It must be called before Start.
+func (w *LogWriter) UsePrivacy(privacy *LogPrivacy) {
+	w.privacy = privacy
+}
+
+// LicenseChecks returns the feed of the license checks written.
+func (w *LogWriter) LicenseChecks() *LicenseCheckFeed {
+	return w.checkFeed
+}
+
+// QueueDepth returns the number of entries waiting to be written.
+func (w *LogWriter) QueueDepth() int {
+	return len(w.checks.queue) + len(w.admins.queue)
+}
+
+type logBatcher[T any] struct {
+	name  string
+	cfg   config.AsyncLogConfig
+	write func(context.Context, []T) error
+
+	queue chan T
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+
+	spillMu sync.Mutex
+}
+
+func newLogBatcher[T any](name string, cfg config.AsyncLogConfig, write func(context.Context, []T) error) *logBatcher[T] {
+	return &logBatcher[T]{
+		name:  name,
+		cfg:   cfg,
+		write: write,
+		queue: make(chan T, cfg.QueueSize),
+	}
+}
+
+func (b *logBatcher[T]) start() {
+	b.replaySpill()
+
+	b.wg.Add(1)
+	go b.run()
+}
+
+func (b *logBatcher[T]) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+}
+
+func (b *logBatcher[T]) enqueue(entry T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		// Late entries after shutdown started are written directly
+		b.flush([]T{entry})
+		return
+	}
+
+	switch b.cfg.Overflow {
+	case config.AsyncLogOverflowDrop:
+		select {
+		case b.queue <- entry:
+		default:
+			metrics.AsyncLogDroppedTotal.WithLabelValues(b.name).Inc()
+		}
+	case config.AsyncLogOverflowSpill:
+		select {
+		case b.queue <- entry:
+		default:
+			b.spill([]T{entry})
+		}
+	default:
+		b.queue <- entry
+	}
+	metrics.AsyncLogQueueDepth.WithLabelValues(b.name).Set(float64(len(b.queue)))
+}
+
+func (b *logBatcher[T]) run() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, b.cfg.BatchSize)
+	for {
+		select {
+		case entry, ok := <-b.queue:
+			if !ok {
+				b.flush(batch)
+				metrics.AsyncLogQueueDepth.WithLabelValues(b.name).Set(0)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= b.cfg.BatchSize {
+				b.flush(batch)
+				batch = make([]T, 0, b.cfg.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.flush(batch)
+				batch = make([]T, 0, b.cfg.BatchSize)
+			}
+		}
+		metrics.AsyncLogQueueDepth.WithLabelValues(b.name).Set(float64(len(b.queue)))
+	}
+}
+
+// flush writes a batch, retrying transient failures. Batches that still fail
+// are spilled to disk when spilling is enabled and counted as failures otherwise.
+func (b *logBatcher[T]) flush(batch []T) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var err error
+	backoff := 100 * time.Millisecond
+	for attempt := 1; attempt <= logWriteAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), logWriteTimeout)
+		err = b.write(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt < logWriteAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	if b.cfg.Overflow == config.AsyncLogOverflowSpill {
+		slog.Warn("Failed to write log batch, spilling to disk", "error", err, "log", b.name, "entries", len(batch))
+		b.spill(batch)
+		return
+	}
+
+	metrics.AsyncLogWriteFailuresTotal.WithLabelValues(b.name).Add(float64(len(batch)))
+	slog.Error("Failed to write log batch", "error", err, "log", b.name, "entries", len(batch))
+}
+
+func (b *logBatcher[T]) spillPath() string {
+	return filepath.Join(b.cfg.SpillDir, b.name+".ndjson")
+}
+
+func (b *logBatcher[T]) spill(entries []T) {
+	b.spillMu.Lock()
+	defer b.spillMu.Unlock()
+
+	if err := os.MkdirAll(b.cfg.SpillDir, 0o750); err != nil {
+		metrics.AsyncLogWriteFailuresTotal.WithLabelValues(b.name).Add(float64(len(entries)))
+		slog.Error("Failed to create spill directory", "error", err, "dir", b.cfg.SpillDir)
+		return
+	}
+
+	f, err := os.OpenFile(b.spillPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		metrics.AsyncLogWriteFailuresTotal.WithLabelValues(b.name).Add(float64(len(entries)))
+		slog.Error("Failed to open spill file", "error", err, "path", b.spillPath())
+		return
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			metrics.AsyncLogWriteFailuresTotal.WithLabelValues(b.name).Inc()
+			slog.Error("Failed to spill log entry", "error", err, "log", b.name)
+			continue
+		}
+		metrics.AsyncLogSpilledTotal.WithLabelValues(b.name).Inc()
+	}
+}
+
+// replaySpill writes back entries spilled by a previous run. The file is only
+// removed once every batch was written, so a failed replay is retried on the
+// next start (entries may then be written twice).
+func (b *logBatcher[T]) replaySpill() {
+	if b.cfg.Overflow != config.AsyncLogOverflowSpill {
+		return
+	}
+
+	b.spillMu.Lock()
+	defer b.spillMu.Unlock()
+
+	f, err := os.Open(b.spillPath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Error("Failed to open spill file for replay", "error", err, "path", b.spillPath())
+		}
+		return
+	}
+	defer f.Close()
+
+	replayed := 0
+	batch := make([]T, 0, b.cfg.BatchSize)
+	writeBatch := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), logWriteTimeout)
+		defer cancel()
+		if err := b.write(ctx, batch); err != nil {
+			return err
+		}
+		replayed += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var entry T
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Error("Skipping unreadable spilled log entry", "error", err, "log", b.name)
+			continue
+		}
+		batch = append(batch, entry)
+		if len(batch) >= b.cfg.BatchSize {
+			if err := writeBatch(); err != nil {
+				slog.Error("Failed to replay spilled logs", "error", err, "log", b.name)
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Error("Failed to read spill file", "error", err, "path", b.spillPath())
+		return
+	}
+	if len(batch) > 0 {
+		if err := writeBatch(); err != nil {
+			slog.Error("Failed to replay spilled logs", "error", err, "log", b.name)
+			return
+		}
+	}
+
+	f.Close()
+	if err := os.Remove(b.spillPath()); err != nil {
+		slog.Error("Failed to remove replayed spill file", "error", err, "path", b.spillPath())
+		return
+	}
+	slog.Info("Replayed spilled logs", "log", b.name, "entries", replayed)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// fakeLogStore records batches written through the COPY methods.
+type fakeLogStore struct {
+	store.LogStore
+
+	mu     sync.Mutex
+	checks []*models.LicenseCheckLog
+	admins []*models.AdminLog
+	fail   bool
+}
+
+func (f *fakeLogStore) CopyLicenseCheckLogs(ctx context.Context, logs []*models.LicenseCheckLog) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("database unavailable")
+	}
+	f.checks = append(f.checks, logs...)
+	return nil
+}
+
+func (f *fakeLogStore) CopyAdminLogs(ctx context.Context, logs []*models.AdminLog) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("database unavailable")
+	}
+	f.admins = append(f.admins, logs...)
+	return nil
+}
+
+func (f *fakeLogStore) counts() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.checks), len(f.admins)
+}
+
+func TestLogWriter_FlushOnClose(t *testing.T) {
+	fake := &fakeLogStore{}
+	w := NewLogWriter(fake, config.AsyncLogConfig{
+		QueueSize:     100,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Overflow:      config.AsyncLogOverflowBlock,
+	})
+	w.Start()
+
+	for i := 0; i < 25; i++ {
+		AsyncLogLicenseCheck(context.Background(), w, &models.LicenseCheckLog{LicenseKey: "KEY"}, true, "")
+	}
+	AsyncLogAdminAction(context.Background(), w, &models.AdminLog{Action: "TEST"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checks, admins := fake.counts()
+	if checks != 25 || admins != 1 {
+		t.Errorf("expected 25 checks and 1 admin log, got %d and %d", checks, admins)
+	}
+}
+
+func TestLogWriter_DropOverflow(t *testing.T) {
+	fake := &fakeLogStore{}
+	w := NewLogWriter(fake, config.AsyncLogConfig{
+		QueueSize:     2,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Overflow:      config.AsyncLogOverflowDrop,
+	})
+
+	// Not started, so nothing drains the queue
+	for i := 0; i < 5; i++ {
+		w.CreateLicenseCheckLog(context.Background(), &models.LicenseCheckLog{})
+	}
+	if depth := w.QueueDepth(); depth != 2 {
+		t.Errorf("expected queue depth 2, got %d", depth)
+	}
+}
+
+func TestLogWriter_SpillAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.AsyncLogConfig{
+		QueueSize:     100,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Overflow:      config.AsyncLogOverflowSpill,
+		SpillDir:      dir,
+	}
+
+	failing := &fakeLogStore{fail: true}
+	w := NewLogWriter(failing, cfg)
+	w.Start()
+	w.CreateAdminLog(context.Background(), &models.AdminLog{Action: "SPILLED"})
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "admin.ndjson")); err != nil {
+		t.Fatalf("expected spill file: %v", err)
+	}
+
+	healthy := &fakeLogStore{}
+	w = NewLogWriter(healthy, cfg)
+	w.Start()
+	defer w.Close(context.Background())
+
+	_, admins := healthy.counts()
+	if admins != 1 || healthy.admins[0].Action != "SPILLED" {
+		t.Errorf("expected spilled admin log to be replayed, got %d entries", admins)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "admin.ndjson")); !os.IsNotExist(err) {
+		t.Errorf("expected spill file to be removed after replay, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 
+	"clortho/internal/metrics"
 	"clortho/internal/models"
 	"clortho/internal/store"
 )
@@ -16,8 +17,17 @@ func AsyncLogAdminAction(ctx context.Context, logStore store.LogStore, entry *mo
 		"owner_id", entry.OwnerID,
 	)
 
+	// The log writer queues the entry itself, bounded by its queue size
+	if w, ok := logStore.(*LogWriter); ok {
+		w.CreateAdminLog(ctx, entry)
+		return
+	}
+
+	// Keep the trace context of the request but not its cancellation
+	logCtx := context.WithoutCancel(ctx)
 	go func() {
-		if err := logStore.CreateAdminLog(context.Background(), entry); err != nil {
+		if err := logStore.CreateAdminLog(logCtx, entry); err != nil {
+			metrics.AsyncLogWriteFailuresTotal.WithLabelValues(metrics.LogAdmin).Inc()
 			slog.Error("Failed to create admin log", "error", err, "action", entry.Action)
 		}
 	}()
@@ -33,8 +43,15 @@ func AsyncLogLicenseCheck(ctx context.Context, logStore store.LogStore, entry *m
 		"status", entry.StatusCode,
 	)
 
+	if w, ok := logStore.(*LogWriter); ok {
+		w.CreateLicenseCheckLog(ctx, entry)
+		return
+	}
+
+	logCtx := context.WithoutCancel(ctx)
 	go func() {
-		if err := logStore.CreateLicenseCheckLog(context.Background(), entry); err != nil {
+		if err := logStore.CreateLicenseCheckLog(logCtx, entry); err != nil {
+			metrics.AsyncLogWriteFailuresTotal.WithLabelValues(metrics.LogLicenseCheck).Inc()
 			slog.Error("Failed to create license check log", "error", err, "key", entry.LicenseKey)
 		}
 	}()
@@ -11,21 +11,17 @@ import (
 
 // SignLicense generates a JWT containing license claims for offline verification.
 func SignLicense(privateKeyBase64 string, key string, expiresAt *time.Time, valid bool, features []string) (string, error) {
-	if privateKeyBase64 == "" {
-		return "", fmt.Errorf("private key is empty")
-	}
+	return SignCheckResult(privateKeyBase64, key, expiresAt, valid, features, nil, "")
+}
 
-	privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKeyBase64)
+// SignCheckResult generates a JWT like SignLicense that also carries the
+// result of each requested feature and the client fingerprint, when set.
+func SignCheckResult(privateKeyBase64 string, key string, expiresAt *time.Time, valid bool, features []string, featureResults map[string]bool, fingerprint string) (string, error) {
+	privateKey, err := ParseSigningKey(privateKeyBase64)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode private key: %w", err)
+		return "", err
 	}
 
-	if len(privateKeyBytes) != ed25519.PrivateKeySize {
-		return "", fmt.Errorf("invalid private key size: %d", len(privateKeyBytes))
-	}
-
-	privateKey := ed25519.PrivateKey(privateKeyBytes)
-
 	claims := jwt.MapClaims{
 		"sub":      key,
 		"iss":      "clortho",
@@ -36,7 +32,49 @@ func SignLicense(privateKeyBase64 string, key string, expiresAt *time.Time, vali
 	if expiresAt != nil {
 		claims["exp"] = expiresAt.Unix()
 	}
+	if featureResults != nil {
+		claims["feature_results"] = featureResults
+	}
+	if fingerprint != "" {
+		claims["fingerprint"] = fingerprint
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
 	return token.SignedString(privateKey)
 }
+
+// ParseSigningKey decodes a base64 encoded Ed25519 private key.
+func ParseSigningKey(privateKeyBase64 string) (ed25519.PrivateKey, error) {
+	if privateKeyBase64 == "" {
+		return nil, fmt.Errorf("private key is empty")
+	}
+
+	privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+
+	if len(privateKeyBytes) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: %d", len(privateKeyBytes))
+	}
+
+	return ed25519.PrivateKey(privateKeyBytes), nil
+}
+
+// ParseVerifyingKey decodes a base64 encoded Ed25519 public key.
+func ParseVerifyingKey(publicKeyBase64 string) (ed25519.PublicKey, error) {
+	if publicKeyBase64 == "" {
+		return nil, fmt.Errorf("public key is empty")
+	}
+
+	publicKeyBytes, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+
+	if len(publicKeyBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: %d", len(publicKeyBytes))
+	}
+
+	return ed25519.PublicKey(publicKeyBytes), nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"clortho/internal/models"
+	"clortho/internal/store"
+)
+
+// SuspensionResumer lifts suspensions whose resume time has passed. /check
+// already ignores such suspensions; this brings the stored status in line and
+// records the resumption in the admin log.
+type SuspensionResumer struct {
+	licenseStore store.LicenseStore
+	logStore     store.LogStore
+	interval     time.Duration
+}
+
+func NewSuspensionResumer(licenseStore store.LicenseStore, logStore store.LogStore, interval time.Duration) *SuspensionResumer {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &SuspensionResumer{
+		licenseStore: licenseStore,
+		logStore:     logStore,
+		interval:     interval,
+	}
+}
+
+// Run resumes due licenses every interval until ctx is done.
+func (r *SuspensionResumer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Resume(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to resume suspended licenses", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resume lifts every suspension that is due.
+func (r *SuspensionResumer) Resume(ctx context.Context) error {
+	resumed, err := r.licenseStore.ResumeExpiredSuspensions(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range resumed {
+		license := &resumed[i]
+		slog.Info("License resumed", "key", license.Key, "suspended_until", license.SuspendedUntil)
+		AsyncLogAdminAction(ctx, r.logStore, &models.AdminLog{
+			Action:     "RESUME_LICENSE",
+			EntityType: "LICENSE",
+			EntityID:   &license.ID,
+			OwnerID:    license.OwnerID,
+			Actor:      &models.AdminActor{Subject: models.SystemActor},
+			Details: map[string]interface{}{
+				"key":               license.Key,
+				"suspension_reason": license.SuspensionReason,
+				"suspended_until":   license.SuspendedUntil,
+				"automatic":         true,
+			},
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/models"
+)
+
+func (f *fakeLicenseStore) ResumeExpiredSuspensions(ctx context.Context, now time.Time) ([]models.License, error) {
+	if f.license.Status != models.LicenseStatusSuspended || f.license.SuspendedUntil == nil || f.license.SuspendedUntil.After(now) {
+		return nil, nil
+	}
+	resumed := *f.license
+	f.license.Status = models.LicenseStatusActive
+	f.license.SuspensionReason = nil
+	f.license.SuspendedUntil = nil
+	return []models.License{resumed}, nil
+}
+
+func TestSuspensionResumer_ResumesDueLicenses(t *testing.T) {
+	reason := "chargeback dispute"
+	until := time.Now().Add(-time.Second)
+	licenses := &fakeLicenseStore{license: &models.License{
+		ID:               uuid.New(),
+		Status:           models.LicenseStatusSuspended,
+		SuspensionReason: &reason,
+		SuspendedUntil:   &until,
+	}}
+	logs := &recordingLogStore{}
+
+	r := NewSuspensionResumer(licenses, logs, time.Minute)
+	if err := r.Resume(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if licenses.license.Status != models.LicenseStatusActive {
+		t.Errorf("expected license to be active, got %s", licenses.license.Status)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for logs.count() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := logs.count(); n != 1 {
+		t.Fatalf("expected 1 admin log, got %d", n)
+	}
+	logs.mu.Lock()
+	entry := logs.admins[0]
+	logs.mu.Unlock()
+	if entry.Action != "RESUME_LICENSE" || entry.Details["automatic"] != true || entry.Details["suspension_reason"] != &reason {
+		t.Errorf("unexpected admin log: %+v", entry)
+	}
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"clortho/internal/models"
+)
+
+// GenesisHash is the previous hash of the first entry of the admin log chain.
+var GenesisHash = strings.Repeat("0", 64)
+
+// AdminLogChainStore reads the admin log hash chain and keeps its signed
+// checkpoints. Entries are chained as they are written by PostgresLogStore.
+type AdminLogChainStore interface {
+	// AdminLogChainHead returns the seq and hash of the last chained entry,
+	// 0 and GenesisHash when there is none.
+	AdminLogChainHead(ctx context.Context) (int64, string, error)
+	// LastChainedAdminLogBefore returns the seq and hash of the last chained
+	// entry created before the given time, 0 and "" when there is none.
+	LastChainedAdminLogBefore(ctx context.Context, before time.Time) (int64, string, error)
+	// ListChainedAdminLogs returns up to limit chained entries numbered
+	// after afterSeq, in chain order.
+	ListChainedAdminLogs(ctx context.Context, afterSeq int64, limit int) ([]models.AdminLog, error)
+	// ListAdminLogCheckpoints returns every checkpoint in chain order.
+	ListAdminLogCheckpoints(ctx context.Context) ([]models.AdminLogCheckpoint, error)
+	// LatestAdminLogCheckpoint returns the checkpoint with the highest seq,
+	// or nil if there is none.
+	LatestAdminLogCheckpoint(ctx context.Context) (*models.AdminLogCheckpoint, error)
+	CreateAdminLogCheckpoint(ctx context.Context, checkpoint *models.AdminLogCheckpoint) error
+}
+
+// AdminLogHash returns the chain hash of log: the hex SHA-256 of its
+// previous hash followed by a canonical JSON encoding of its seq and
+// content. Details and changes are encoded as they read back from JSONB so
+// the hash of a stored entry can be recomputed.
+func AdminLogHash(log *models.AdminLog) (string, error) {
+	if log.Seq == nil {
+		return "", fmt.Errorf("admin log %s is not chained", log.ID)
+	}
+	details, err := canonicalJSON(log.Details)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode details: %w", err)
+	}
+	changes, err := canonicalJSON(log.Changes)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode changes: %w", err)
+	}
+
+	content, err := json.Marshal(struct {
+		Seq        int64           `json:"seq"`
+		ID         uuid.UUID       `json:"id"`
+		Action     string          `json:"action"`
+		EntityType string          `json:"entity_type"`
+		EntityID   *uuid.UUID      `json:"entity_id"`
+		OwnerID    *string         `json:"owner_id"`
+		Actor      [4]*string      `json:"actor"`
+		Details    json.RawMessage `json:"details"`
+		Changes    json.RawMessage `json:"changes"`
+		CreatedAt  string          `json:"created_at"`
+	}{
+		Seq:        *log.Seq,
+		ID:         log.ID,
+		Action:     log.Action,
+		EntityType: log.EntityType,
+		EntityID:   log.EntityID,
+		OwnerID:    log.OwnerID,
+		Actor:      adminActorColumns(log.Actor),
+		Details:    details,
+		Changes:    changes,
+		CreatedAt:  log.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(log.PrevHash))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// canonicalJSON encodes v the same way whether it holds the original Go
+// values or the ones decoded back from its JSON.
+func canonicalJSON(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
+
+// insertAdminLogs chains logs after the current head and writes them in one
+// transaction. IDs, creation times and chain fields are set on the entries.
+func (s *PostgresLogStore) insertAdminLogs(ctx context.Context, logs []*models.AdminLog) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var seq int64
+	var prevHash string
+	if err := tx.QueryRow(ctx, `SELECT seq, hash FROM admin_log_chain FOR UPDATE`).Scan(&seq, &prevHash); err != nil {
+		return fmt.Errorf("failed to lock admin log chain: %w", err)
+	}
+
+	rows := make([][]any, len(logs))
+	for i, log := range logs {
+		if log.ID == uuid.Nil {
+			log.ID = uuid.New()
+		}
+		log.CreatedAt = createdAtOrNow(log.CreatedAt).UTC().Truncate(time.Microsecond)
+		seq++
+		entrySeq := seq
+		log.Seq = &entrySeq
+		log.PrevHash = prevHash
+		if log.Hash, err = AdminLogHash(log); err != nil {
+			return err
+		}
+		prevHash = log.Hash
+
+		detailsJSON, err := json.Marshal(log.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal details: %w", err)
+		}
+		changesJSON, err := marshalChanges(log.Changes)
+		if err != nil {
+			return err
+		}
+		actor := adminActorColumns(log.Actor)
+		rows[i] = []any{
+			log.ID,
+			log.Action,
+			log.EntityType,
+			log.EntityID,
+			log.OwnerID,
+			detailsJSON,
+			changesJSON,
+			actor[0],
+			actor[1],
+			actor[2],
+			actor[3],
+			log.CreatedAt,
+			log.Seq,
+			log.PrevHash,
+			log.Hash,
+		}
+	}
+
+	columns := []string{"id", "action", "entity_type", "entity_id", "owner_id", "details", "changes",
+		"actor", "actor_token_id", "actor_ip", "actor_user_agent", "created_at", "seq", "prev_hash", "hash"}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"admin_logs"}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to copy admin logs: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE admin_log_chain SET seq = $1, hash = $2`, seq, prevHash); err != nil {
+		return fmt.Errorf("failed to advance admin log chain: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresLogStore) AdminLogChainHead(ctx context.Context) (int64, string, error) {
+	var seq int64
+	var hash string
+	err := s.DB.QueryRow(ctx, `SELECT seq, hash FROM admin_log_chain`).Scan(&seq, &hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, GenesisHash, nil
+		}
+		return 0, "", fmt.Errorf("failed to read admin log chain head: %w", err)
+	}
+	return seq, hash, nil
+}
+
+func (s *PostgresLogStore) LastChainedAdminLogBefore(ctx context.Context, before time.Time) (int64, string, error) {
+	var seq int64
+	var hash string
+	err := s.DB.QueryRow(ctx, `SELECT seq, hash FROM admin_logs WHERE seq IS NOT NULL AND created_at < $1 ORDER BY seq DESC LIMIT 1`, before).Scan(&seq, &hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, "", nil
+		}
+		return 0, "", fmt.Errorf("failed to read last chained admin log: %w", err)
+	}
+	return seq, hash, nil
+}
+
+func (s *PostgresLogStore) ListChainedAdminLogs(ctx context.Context, afterSeq int64, limit int) ([]models.AdminLog, error) {
+	rows, err := s.DB.Query(ctx, `SELECT `+adminLogColumns+` FROM admin_logs WHERE seq > $1 ORDER BY seq LIMIT $2`, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chained admin logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []models.AdminLog
+	for rows.Next() {
+		log, err := scanAdminLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+	return logs, rows.Err()
+}
+
+const adminLogCheckpointColumns = `seq, hash, public_key, signature, created_at`
+
+func scanAdminLogCheckpoint(row pgx.Row) (models.AdminLogCheckpoint, error) {
+	var checkpoint models.AdminLogCheckpoint
+	err := row.Scan(&checkpoint.Seq, &checkpoint.Hash, &checkpoint.PublicKey, &checkpoint.Signature, &checkpoint.CreatedAt)
+	return checkpoint, err
+}
+
+func (s *PostgresLogStore) ListAdminLogCheckpoints(ctx context.Context) ([]models.AdminLogCheckpoint, error) {
+	rows, err := s.DB.Query(ctx, `SELECT `+adminLogCheckpointColumns+` FROM admin_log_checkpoints ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admin log checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []models.AdminLogCheckpoint
+	for rows.Next() {
+		checkpoint, err := scanAdminLogCheckpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan admin log checkpoint: %w", err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, rows.Err()
+}
+
+func (s *PostgresLogStore) LatestAdminLogCheckpoint(ctx context.Context) (*models.AdminLogCheckpoint, error) {
+	checkpoint, err := scanAdminLogCheckpoint(s.DB.QueryRow(ctx, `SELECT `+adminLogCheckpointColumns+` FROM admin_log_checkpoints ORDER BY seq DESC LIMIT 1`))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read latest admin log checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+func (s *PostgresLogStore) CreateAdminLogCheckpoint(ctx context.Context, checkpoint *models.AdminLogCheckpoint) error {
+	query := `
+		INSERT INTO admin_log_checkpoints (seq, hash, public_key, signature)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (seq) DO NOTHING
+		RETURNING created_at`
+
+	err := s.DB.QueryRow(ctx, query, checkpoint.Seq, checkpoint.Hash, checkpoint.PublicKey, checkpoint.Signature).Scan(&checkpoint.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another instance signed the same head
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/models"
+)
+
+func TestAdminLogHash_MatchesStoredEntry(t *testing.T) {
+	seq := int64(7)
+	entityID := uuid.New()
+	written := models.AdminLog{
+		ID:         uuid.New(),
+		Action:     "UPDATE",
+		EntityType: "LICENSE",
+		EntityID:   &entityID,
+		Actor:      &models.AdminActor{Subject: "admin", IPAddress: "10.0.0.1"},
+		Details:    map[string]interface{}{"key": "<abc>", "max_activations": 5, "expires_at": time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Changes:    map[string]models.FieldChange{"status": {Before: "active", After: "revoked"}},
+		CreatedAt:  time.Date(2026, 10, 1, 12, 0, 0, 123456789, time.FixedZone("UTC+2", 2*3600)),
+		Seq:        &seq,
+		PrevHash:   GenesisHash,
+	}
+	hash, err := AdminLogHash(&written)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// The entry as read back: JSON decoded details and changes, microsecond
+	// UTC time, empty actor fields as NULL
+	var read models.AdminLog
+	data, err := json.Marshal(written)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &read))
+	read.CreatedAt = written.CreatedAt.UTC().Truncate(time.Microsecond)
+	readHash, err := AdminLogHash(&read)
+	require.NoError(t, err)
+	assert.Equal(t, hash, readHash)
+
+	// Every field and the previous hash are covered
+	for name, edit := range map[string]func(*models.AdminLog){
+		"action":    func(l *models.AdminLog) { l.Action = "DELETE" },
+		"details":   func(l *models.AdminLog) { l.Details["max_activations"] = 6 },
+		"changes":   func(l *models.AdminLog) { l.Changes = nil },
+		"actor":     func(l *models.AdminLog) { l.Actor = nil },
+		"time":      func(l *models.AdminLog) { l.CreatedAt = l.CreatedAt.Add(time.Microsecond) },
+		"prev_hash": func(l *models.AdminLog) { l.PrevHash = hash },
+	} {
+		edited := read
+		edited.Details = map[string]interface{}{}
+		for k, v := range read.Details {
+			edited.Details[k] = v
+		}
+		edit(&edited)
+		editedHash, err := AdminLogHash(&edited)
+		require.NoError(t, err)
+		assert.NotEqual(t, hash, editedHash, name)
+	}
+}
+
+func TestAdminLogHash_RequiresSeq(t *testing.T) {
+	_, err := AdminLogHash(&models.AdminLog{ID: uuid.New()})
+	assert.Error(t, err)
+}
@@ -1,9 +1,32 @@
 package store
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // Common store errors for use with errors.Is()
 var (
 	ErrNotFound  = errors.New("not found")
 	ErrDuplicate = errors.New("duplicate entry")
 )
+
+// UnknownCatalogError is returned when a license or plan refers to feature
+// codes or release versions that are defined neither for its product, nor
+// for its group, nor globally.
+type UnknownCatalogError struct {
+	FeatureCodes    []string
+	ReleaseVersions []string
+}
+
+func (e *UnknownCatalogError) Error() string {
+	var parts []string
+	if len(e.FeatureCodes) > 0 {
+		parts = append(parts, "unknown feature codes: "+strings.Join(e.FeatureCodes, ", "))
+	}
+	if len(e.ReleaseVersions) > 0 {
+		parts = append(parts, "unknown release versions: "+strings.Join(e.ReleaseVersions, ", "))
+	}
+	return fmt.Sprintf("catalog check failed: %s", strings.Join(parts, "; "))
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clortho/internal/models"
+)
+
+type FlagStore interface {
+	// FindAnomalousLicenses returns the licenses checked since the given time
+	// from more distinct clients than their product's thresholds allow, with
+	// defaults standing in for thresholds the product does not set.
+	FindAnomalousLicenses(ctx context.Context, since time.Time, defaults models.AnomalyThresholds) ([]models.LicenseFlag, error)
+	// UpsertLicenseFlag records a detection and returns when the license was
+	// last detected before it, or nil if it was never flagged.
+	UpsertLicenseFlag(ctx context.Context, flag *models.LicenseFlag) (*time.Time, error)
+	SetLicenseFlagSuspended(ctx context.Context, licenseID string) error
+	ListLicenseFlags(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.LicenseFlag, int, error)
+}
+
+type PostgresFlagStore struct {
+	DB *pgxpool.Pool
+}
+
+func NewPostgresFlagStore(db *pgxpool.Pool) *PostgresFlagStore {
+	return &PostgresFlagStore{DB: db}
+}
+
+func (s *PostgresFlagStore) FindAnomalousLicenses(ctx context.Context, since time.Time, defaults models.AnomalyThresholds) ([]models.LicenseFlag, error) {
+	// IPv4 addresses are grouped by /16, IPv6 addresses by /32
+	query := `
+		WITH counts AS (
+			SELECT
+				license_id,
+				count(DISTINCT ip_address) AS ips,
+				count(DISTINCT user_agent) AS user_agents,
+				count(DISTINCT network(set_masklen(NULLIF(ip_address, '')::inet, CASE WHEN family(NULLIF(ip_address, '')::inet) = 4 THEN 16 ELSE 32 END))) AS prefixes
+			FROM license_check_logs
+			WHERE created_at >= $1 AND license_id IS NOT NULL
+			GROUP BY license_id
+		), thresholds AS (
+			SELECT
+				c.*, l.key, l.product_id, l.owner_id,
+				COALESCE((p.anomaly_thresholds->>'distinct_ips')::int, $2) AS max_ips,
+				COALESCE((p.anomaly_thresholds->>'distinct_user_agents')::int, $3) AS max_user_agents,
+				COALESCE((p.anomaly_thresholds->>'distinct_prefixes')::int, $4) AS max_prefixes,
+				COALESCE((p.anomaly_thresholds->>'auto_suspend')::boolean, $5) AS auto_suspend
+			FROM counts c
+			JOIN licenses l ON l.id = c.license_id
+			JOIN products p ON p.id = l.product_id
+		)
+		SELECT license_id, key, product_id, owner_id, ips, user_agents, prefixes, max_ips, max_user_agents, max_prefixes, auto_suspend
+		FROM thresholds
+		WHERE (max_ips > 0 AND ips > max_ips)
+			OR (max_user_agents > 0 AND user_agents > max_user_agents)
+			OR (max_prefixes > 0 AND prefixes > max_prefixes)
+		ORDER BY ips DESC
+	`
+	rows, err := s.DB.Query(ctx, query, since, intOrZero(defaults.DistinctIPs), intOrZero(defaults.DistinctUserAgents), intOrZero(defaults.DistinctPrefixes), defaults.AutoSuspend != nil && *defaults.AutoSuspend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find anomalous licenses: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []models.LicenseFlag
+	for rows.Next() {
+		var f models.LicenseFlag
+		var maxIPs, maxUserAgents, maxPrefixes int
+		var autoSuspend bool
+		if err := rows.Scan(&f.LicenseID, &f.LicenseKey, &f.ProductID, &f.OwnerID, &f.DistinctIPs, &f.DistinctUserAgents, &f.DistinctPrefixes, &maxIPs, &maxUserAgents, &maxPrefixes, &autoSuspend); err != nil {
+			return nil, fmt.Errorf("failed to scan anomalous license: %w", err)
+		}
+		f.Thresholds = &models.AnomalyThresholds{
+			DistinctIPs:        &maxIPs,
+			DistinctUserAgents: &maxUserAgents,
+			DistinctPrefixes:   &maxPrefixes,
+			AutoSuspend:        &autoSuspend,
+		}
+		flags = append(flags, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating anomalous licenses: %w", err)
+	}
+
+	return flags, nil
+}
+
+func intOrZero(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func (s *PostgresFlagStore) UpsertLicenseFlag(ctx context.Context, flag *models.LicenseFlag) (*time.Time, error) {
+	query := `
+		WITH previous AS (
+			SELECT last_detected_at FROM license_flags WHERE license_id = $1
+		)
+		INSERT INTO license_flags (license_id, reasons, distinct_ips, distinct_user_agents, distinct_prefixes, window_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (license_id) DO UPDATE SET
+			reasons = EXCLUDED.reasons,
+			distinct_ips = EXCLUDED.distinct_ips,
+			distinct_user_agents = EXCLUDED.distinct_user_agents,
+			distinct_prefixes = EXCLUDED.distinct_prefixes,
+			window_seconds = EXCLUDED.window_seconds,
+			last_detected_at = NOW()
+		RETURNING first_detected_at, last_detected_at, suspended, (SELECT last_detected_at FROM previous)
+	`
+	var previous *time.Time
+	err := s.DB.QueryRow(ctx, query, flag.LicenseID, flag.Reasons, flag.DistinctIPs, flag.DistinctUserAgents, flag.DistinctPrefixes, flag.WindowSeconds).
+		Scan(&flag.FirstDetectedAt, &flag.LastDetectedAt, &flag.Suspended, &previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record license flag: %w", err)
+	}
+	return previous, nil
+}
+
+func (s *PostgresFlagStore) SetLicenseFlagSuspended(ctx context.Context, licenseID string) error {
+	tag, err := s.DB.Exec(ctx, `UPDATE license_flags SET suspended = true WHERE license_id = $1`, licenseID)
+	if err != nil {
+		return fmt.Errorf("failed to mark license flag suspended: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: license flag", ErrNotFound)
+	}
+	return nil
+}
+
+func (s *PostgresFlagStore) ListLicenseFlags(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.LicenseFlag, int, error) {
+	query := `
+		SELECT f.license_id, l.key, l.product_id, l.owner_id, f.reasons, f.distinct_ips, f.distinct_user_agents, f.distinct_prefixes, f.window_seconds, f.suspended, f.first_detected_at, f.last_detected_at
+		FROM license_flags f
+		JOIN licenses l ON l.id = f.license_id
+	`
+	countQuery := `SELECT count(*) FROM license_flags f JOIN licenses l ON l.id = f.license_id`
+	var args []interface{}
+	if ownerID != nil {
+		query += ` WHERE l.owner_id = $1`
+		countQuery += ` WHERE l.owner_id = $1`
+		args = append(args, ownerID)
+	}
+	query += ` ORDER BY f.last_detected_at DESC`
+
+	limit := pagination.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	page := pagination.Page
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	var totalCount int
+	if err := s.DB.QueryRow(ctx, countQuery, args[:len(args)-2]...).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to get total count of license flags: %w", err)
+	}
+
+	rows, err := s.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list license flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []models.LicenseFlag
+	for rows.Next() {
+		var f models.LicenseFlag
+		if err := rows.Scan(&f.LicenseID, &f.LicenseKey, &f.ProductID, &f.OwnerID, &f.Reasons, &f.DistinctIPs, &f.DistinctUserAgents, &f.DistinctPrefixes, &f.WindowSeconds, &f.Suspended, &f.FirstDetectedAt, &f.LastDetectedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan license flag: %w", err)
+		}
+		flags = append(flags, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating license flags: %w", err)
+	}
+
+	return flags, totalCount, nil
+}
@@ -0,0 +1,426 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clortho/internal/config"
+	"clortho/internal/metrics"
+	"clortho/internal/models"
+)
+
+// LicenseCacheChannel is the Postgres NOTIFY channel used to share cache
+// invalidations between instances.
+const LicenseCacheChannel = "clortho_license_cache"
+
+const licenseCacheReconnectDelay = 5 * time.Second
+
+type licenseCacheMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys,omitempty"`
+	Purge  bool     `json:"purge,omitempty"`
+}
+
+// LicenseCache holds licenses looked up by key, and the keys that were not
+// found, for a bounded time. Invalidations are published over LISTEN/NOTIFY
+// when a database is given so every instance drops the same entries.
+type LicenseCache struct {
+	licenses *expirable.LRU[string, *models.License]
+	missing  *expirable.LRU[string, struct{}]
+	rotated  *expirable.LRU[string, struct{}]
+
+	db     *pgxpool.Pool
+	origin string
+
+	// generation is bumped on every invalidation so a lookup that raced with
+	// one does not store what it read before the change was committed.
+	generation atomic.Uint64
+}
+
+func NewLicenseCache(cfg config.LicenseCacheConfig, db *pgxpool.Pool) *LicenseCache {
+	size := cfg.Size
+	if size <= 0 {
+		size = 10000
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	negativeTTL := cfg.NegativeTTL
+	if negativeTTL <= 0 {
+		negativeTTL = 10 * time.Second
+	}
+
+	c := &LicenseCache{
+		licenses: expirable.NewLRU[string, *models.License](size, nil, ttl),
+		missing:  expirable.NewLRU[string, struct{}](size, nil, negativeTTL),
+		rotated:  expirable.NewLRU[string, struct{}](size, nil, negativeTTL),
+		origin:   newCacheOrigin(),
+	}
+	if cfg.Notify {
+		c.db = db
+	}
+	return c
+}
+
+func newCacheOrigin() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Invalidate drops the given keys on this instance and every listening one.
+func (c *LicenseCache) Invalidate(ctx context.Context, keys ...string) {
+	c.invalidateLocal(keys...)
+	c.publish(ctx, licenseCacheMessage{Origin: c.origin, Keys: keys})
+}
+
+// Purge drops every entry on this instance and every listening one.
+func (c *LicenseCache) Purge(ctx context.Context) {
+	c.purgeLocal()
+	c.publish(ctx, licenseCacheMessage{Origin: c.origin, Purge: true})
+}
+
+func (c *LicenseCache) invalidateLocal(keys ...string) {
+	c.generation.Add(1)
+	for _, key := range keys {
+		c.licenses.Remove(key)
+		c.missing.Remove(key)
+		c.rotated.Remove(key)
+	}
+}
+
+func (c *LicenseCache) purgeLocal() {
+	c.generation.Add(1)
+	c.licenses.Purge()
+	c.missing.Purge()
+	c.rotated.Purge()
+}
+
+func (c *LicenseCache) publish(ctx context.Context, msg licenseCacheMessage) {
+	if c.db == nil {
+		return
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to encode license cache invalidation", "error", err)
+		return
+	}
+	if _, err := c.db.Exec(context.WithoutCancel(ctx), `SELECT pg_notify($1, $2)`, LicenseCacheChannel, string(payload)); err != nil {
+		slog.Error("Failed to publish license cache invalidation", "error", err)
+	}
+}
+
+// handleNotification applies an invalidation published by another instance.
+func (c *LicenseCache) handleNotification(payload string) {
+	var msg licenseCacheMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		slog.Warn("Ignoring malformed license cache invalidation", "error", err)
+		return
+	}
+	if msg.Origin == c.origin {
+		return
+	}
+	if msg.Purge {
+		c.purgeLocal()
+		return
+	}
+	c.invalidateLocal(msg.Keys...)
+}
+
+// Listen applies invalidations published by other instances until ctx is
+// done. The cache is purged after every reconnect since notifications sent
+// while disconnected are lost.
+func (c *LicenseCache) Listen(ctx context.Context) {
+	if c.db == nil {
+		return
+	}
+
+	for {
+		err := c.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("License cache listener disconnected, retrying", "error", err)
+		c.purgeLocal()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(licenseCacheReconnectDelay):
+		}
+	}
+}
+
+func (c *LicenseCache) listen(ctx context.Context) error {
+	conn, err := c.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// The connection stays in LISTEN mode, so it is not handed back to the pool
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+LicenseCacheChannel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	for {
+		n, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		c.handleNotification(n.Payload)
+	}
+}
+
+// CachedLicenseStore is a LicenseStore that serves key lookups from a
+// LicenseCache and invalidates it on every mutation.
+type CachedLicenseStore struct {
+	LicenseStore
+
+	cache *LicenseCache
+}
+
+func NewCachedLicenseStore(licenseStore LicenseStore, cache *LicenseCache) *CachedLicenseStore {
+	return &CachedLicenseStore{LicenseStore: licenseStore, cache: cache}
+}
+
+func (s *CachedLicenseStore) GetLicenseByKey(ctx context.Context, key string) (*models.License, error) {
+	if l, ok := s.cache.licenses.Get(key); ok {
+		metrics.LicenseCacheLookupsTotal.WithLabelValues(metrics.CacheResultHit).Inc()
+		return cloneLicense(l), nil
+	}
+	if _, ok := s.cache.missing.Get(key); ok {
+		metrics.LicenseCacheLookupsTotal.WithLabelValues(metrics.CacheResultNegativeHit).Inc()
+		return nil, fmt.Errorf("%w: license", ErrNotFound)
+	}
+	metrics.LicenseCacheLookupsTotal.WithLabelValues(metrics.CacheResultMiss).Inc()
+
+	generation := s.cache.generation.Load()
+	l, err := s.LicenseStore.GetLicenseByKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) && s.cache.generation.Load() == generation {
+			s.cache.missing.Add(key, struct{}{})
+		}
+		return nil, err
+	}
+	if s.cache.generation.Load() == generation {
+		s.cache.licenses.Add(key, cloneLicense(l))
+	}
+	return l, nil
+}
+
+// GetLicenseByRotatedKey is only consulted for unknown keys, so only misses are cached.
+func (s *CachedLicenseStore) GetLicenseByRotatedKey(ctx context.Context, key string) (*models.License, error) {
+	if _, ok := s.cache.rotated.Get(key); ok {
+		return nil, fmt.Errorf("%w: license key alias", ErrNotFound)
+	}
+
+	generation := s.cache.generation.Load()
+	l, err := s.LicenseStore.GetLicenseByRotatedKey(ctx, key)
+	if err != nil && errors.Is(err, ErrNotFound) && s.cache.generation.Load() == generation {
+		s.cache.rotated.Add(key, struct{}{})
+	}
+	return l, err
+}
+
+func (s *CachedLicenseStore) CreateLicense(ctx context.Context, license *models.License) error {
+	if err := s.LicenseStore.CreateLicense(ctx, license); err != nil {
+		return err
+	}
+	s.cache.Invalidate(ctx, license.Key)
+	return nil
+}
+
+func (s *CachedLicenseStore) CreateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error) {
+	features, releases, err := s.LicenseStore.CreateLicenseWithMissingCatalog(ctx, license)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.cache.Invalidate(ctx, license.Key)
+	return features, releases, nil
+}
+
+func (s *CachedLicenseStore) UpdateLicense(ctx context.Context, license *models.License) error {
+	err := s.LicenseStore.UpdateLicense(ctx, license)
+	s.cache.Invalidate(ctx, license.Key)
+	return err
+}
+
+func (s *CachedLicenseStore) UpdateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error) {
+	features, releases, err := s.LicenseStore.UpdateLicenseWithMissingCatalog(ctx, license)
+	s.cache.Invalidate(ctx, license.Key)
+	return features, releases, err
+}
+
+func (s *CachedLicenseStore) DeleteLicense(ctx context.Context, key string) error {
+	err := s.LicenseStore.DeleteLicense(ctx, key)
+	s.cache.Invalidate(ctx, key)
+	return err
+}
+
+func (s *CachedLicenseStore) AddAutoAllowedIP(ctx context.Context, key, ip string) (bool, error) {
+	added, err := s.LicenseStore.AddAutoAllowedIP(ctx, key, ip)
+	if added {
+		s.cache.Invalidate(ctx, key)
+	}
+	return added, err
+}
+
+func (s *CachedLicenseStore) RotateLicenseKey(ctx context.Context, oldKey, newKey string) error {
+	err := s.LicenseStore.RotateLicenseKey(ctx, oldKey, newKey)
+	s.cache.Invalidate(ctx, oldKey, newKey)
+	return err
+}
+
+func (s *CachedLicenseStore) ResumeExpiredSuspensions(ctx context.Context, now time.Time) ([]models.License, error) {
+	resumed, err := s.LicenseStore.ResumeExpiredSuspensions(ctx, now)
+	if len(resumed) > 0 {
+		keys := make([]string, len(resumed))
+		for i, l := range resumed {
+			keys[i] = l.Key
+		}
+		s.cache.Invalidate(ctx, keys...)
+	}
+	return resumed, err
+}
+
+func cloneLicense(l *models.License) *models.License {
+	c := *l
+	c.AllowedIPs = append([]string(nil), l.AllowedIPs...)
+	c.AllowedNetworks = append([]string(nil), l.AllowedNetworks...)
+	c.Features = append([]string(nil), l.Features...)
+	c.FeatureValues = maps.Clone(l.FeatureValues)
+	c.Releases = append([]string(nil), l.Releases...)
+	return &c
+}
+
+// Catalog changes reach cached licenses through their feature and release
+// codes or through cascading deletes, so they purge the whole cache.
+
+type CacheInvalidatingProductStore struct {
+	ProductStore
+
+	cache *LicenseCache
+}
+
+func NewCacheInvalidatingProductStore(productStore ProductStore, cache *LicenseCache) *CacheInvalidatingProductStore {
+	return &CacheInvalidatingProductStore{ProductStore: productStore, cache: cache}
+}
+
+func (s *CacheInvalidatingProductStore) UpdateProduct(ctx context.Context, product *models.Product) error {
+	err := s.ProductStore.UpdateProduct(ctx, product)
+	s.cache.Purge(ctx)
+	return err
+}
+
+func (s *CacheInvalidatingProductStore) DeleteProduct(ctx context.Context, id string) error {
+	err := s.ProductStore.DeleteProduct(ctx, id)
+	s.cache.Purge(ctx)
+	return err
+}
+
+type CacheInvalidatingProductGroupStore struct {
+	ProductGroupStore
+
+	cache *LicenseCache
+}
+
+func NewCacheInvalidatingProductGroupStore(productGroupStore ProductGroupStore, cache *LicenseCache) *CacheInvalidatingProductGroupStore {
+	return &CacheInvalidatingProductGroupStore{ProductGroupStore: productGroupStore, cache: cache}
+}
+
+func (s *CacheInvalidatingProductGroupStore) UpdateProductGroup(ctx context.Context, group *models.ProductGroup) error {
+	err := s.ProductGroupStore.UpdateProductGroup(ctx, group)
+	s.cache.Purge(ctx)
+	return err
+}
+
+func (s *CacheInvalidatingProductGroupStore) DeleteProductGroup(ctx context.Context, id string) error {
+	err := s.ProductGroupStore.DeleteProductGroup(ctx, id)
+	s.cache.Purge(ctx)
+	return err
+}
+
+type CacheInvalidatingFeatureStore struct {
+	FeatureStore
+
+	cache *LicenseCache
+}
+
+func NewCacheInvalidatingFeatureStore(featureStore FeatureStore, cache *LicenseCache) *CacheInvalidatingFeatureStore {
+	return &CacheInvalidatingFeatureStore{FeatureStore: featureStore, cache: cache}
+}
+
+func (s *CacheInvalidatingFeatureStore) UpdateFeature(ctx context.Context, feature *models.Feature) error {
+	err := s.FeatureStore.UpdateFeature(ctx, feature)
+	s.cache.Purge(ctx)
+	return err
+}
+
+func (s *CacheInvalidatingFeatureStore) DeleteFeature(ctx context.Context, featureID string) error {
+	err := s.FeatureStore.DeleteFeature(ctx, featureID)
+	s.cache.Purge(ctx)
+	return err
+}
+
+type CacheInvalidatingReleaseStore struct {
+	ReleaseStore
+
+	cache *LicenseCache
+}
+
+func NewCacheInvalidatingReleaseStore(releaseStore ReleaseStore, cache *LicenseCache) *CacheInvalidatingReleaseStore {
+	return &CacheInvalidatingReleaseStore{ReleaseStore: releaseStore, cache: cache}
+}
+
+func (s *CacheInvalidatingReleaseStore) UpdateRelease(ctx context.Context, release *models.Release) error {
+	err := s.ReleaseStore.UpdateRelease(ctx, release)
+	s.cache.Purge(ctx)
+	return err
+}
+
+func (s *CacheInvalidatingReleaseStore) DeleteRelease(ctx context.Context, releaseID string) error {
+	err := s.ReleaseStore.DeleteRelease(ctx, releaseID)
+	s.cache.Purge(ctx)
+	return err
+}
+
+// Plan changes rewrite the licenses on the plan, so they purge the cache too.
+
+type CacheInvalidatingPlanStore struct {
+	PlanStore
+
+	cache *LicenseCache
+}
+
+func NewCacheInvalidatingPlanStore(planStore PlanStore, cache *LicenseCache) *CacheInvalidatingPlanStore {
+	return &CacheInvalidatingPlanStore{PlanStore: planStore, cache: cache}
+}
+
+func (s *CacheInvalidatingPlanStore) UpdatePlan(ctx context.Context, plan *models.Plan) (int64, error) {
+	updated, err := s.PlanStore.UpdatePlan(ctx, plan)
+	s.cache.Purge(ctx)
+	return updated, err
+}
+
+func (s *CacheInvalidatingPlanStore) DeletePlan(ctx context.Context, id string) error {
+	err := s.PlanStore.DeletePlan(ctx, id)
+	s.cache.Purge(ctx)
+	return err
+}
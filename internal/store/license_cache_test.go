@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/config"
+	"clortho/internal/models"
+)
+
+// countingLicenseStore serves licenses from a map and counts key lookups.
+type countingLicenseStore struct {
+	LicenseStore
+
+	licenses map[string]*models.License
+	lookups  int
+}
+
+func (s *countingLicenseStore) GetLicenseByKey(ctx context.Context, key string) (*models.License, error) {
+	s.lookups++
+	l, ok := s.licenses[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: license", ErrNotFound)
+	}
+	c := *l
+	return &c, nil
+}
+
+func (s *countingLicenseStore) UpdateLicense(ctx context.Context, license *models.License) error {
+	c := *license
+	s.licenses[license.Key] = &c
+	return nil
+}
+
+func (s *countingLicenseStore) CreateLicense(ctx context.Context, license *models.License) error {
+	return s.UpdateLicense(ctx, license)
+}
+
+type noopFeatureStore struct {
+	FeatureStore
+}
+
+func (noopFeatureStore) UpdateFeature(ctx context.Context, feature *models.Feature) error {
+	return nil
+}
+
+func newTestLicenseCache() *LicenseCache {
+	return NewLicenseCache(config.LicenseCacheConfig{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute}, nil)
+}
+
+func TestCachedLicenseStore_Hit(t *testing.T) {
+	backend := &countingLicenseStore{licenses: map[string]*models.License{
+		"KEY": {Key: "KEY", Features: []string{"a"}},
+	}}
+	s := NewCachedLicenseStore(backend, newTestLicenseCache())
+
+	l, err := s.GetLicenseByKey(context.Background(), "KEY")
+	require.NoError(t, err)
+	l.Features = append(l.Features, "mutated")
+
+	l, err = s.GetLicenseByKey(context.Background(), "KEY")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, l.Features, "callers must not be able to modify cached entries")
+	assert.Equal(t, 1, backend.lookups)
+}
+
+func TestCachedLicenseStore_NegativeCaching(t *testing.T) {
+	backend := &countingLicenseStore{licenses: map[string]*models.License{}}
+	s := NewCachedLicenseStore(backend, newTestLicenseCache())
+
+	for i := 0; i < 3; i++ {
+		_, err := s.GetLicenseByKey(context.Background(), "UNKNOWN")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	}
+	assert.Equal(t, 1, backend.lookups)
+
+	// Creating the license must clear the negative entry
+	require.NoError(t, s.CreateLicense(context.Background(), &models.License{Key: "UNKNOWN"}))
+	_, err := s.GetLicenseByKey(context.Background(), "UNKNOWN")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.lookups)
+}
+
+func TestCachedLicenseStore_InvalidateOnUpdate(t *testing.T) {
+	backend := &countingLicenseStore{licenses: map[string]*models.License{
+		"KEY": {Key: "KEY", Status: models.LicenseStatusActive},
+	}}
+	s := NewCachedLicenseStore(backend, newTestLicenseCache())
+
+	_, err := s.GetLicenseByKey(context.Background(), "KEY")
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpdateLicense(context.Background(), &models.License{Key: "KEY", Status: models.LicenseStatusRevoked}))
+
+	l, err := s.GetLicenseByKey(context.Background(), "KEY")
+	require.NoError(t, err)
+	assert.Equal(t, models.LicenseStatusRevoked, l.Status)
+	assert.Equal(t, 2, backend.lookups)
+}
+
+func TestLicenseCache_CatalogChangePurges(t *testing.T) {
+	cache := newTestLicenseCache()
+	backend := &countingLicenseStore{licenses: map[string]*models.License{"KEY": {Key: "KEY"}}}
+	s := NewCachedLicenseStore(backend, cache)
+
+	_, err := s.GetLicenseByKey(context.Background(), "KEY")
+	require.NoError(t, err)
+
+	features := NewCacheInvalidatingFeatureStore(noopFeatureStore{}, cache)
+	require.NoError(t, features.UpdateFeature(context.Background(), &models.Feature{}))
+
+	_, err = s.GetLicenseByKey(context.Background(), "KEY")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.lookups)
+}
+
+func TestLicenseCache_HandleNotification(t *testing.T) {
+	cache := newTestLicenseCache()
+	cache.licenses.Add("A", &models.License{Key: "A"})
+	cache.licenses.Add("B", &models.License{Key: "B"})
+
+	// Own notifications were already applied locally
+	cache.handleNotification(`{"origin":"` + cache.origin + `","purge":true}`)
+	assert.Equal(t, 2, cache.licenses.Len())
+
+	cache.handleNotification(`{"origin":"other","keys":["A"]}`)
+	assert.False(t, cache.licenses.Contains("A"))
+	assert.True(t, cache.licenses.Contains("B"))
+
+	cache.handleNotification(`{"origin":"other","purge":true}`)
+	assert.Equal(t, 0, cache.licenses.Len())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -17,7 +18,24 @@ type LicenseStore interface {
 	GetLicenseByKey(ctx context.Context, key string) (*models.License, error)
 	GetLicense(ctx context.Context, id string) (*models.License, error)
 	DeleteLicense(ctx context.Context, key string) error
+	RotateLicenseKey(ctx context.Context, oldKey, newKey string) error
+	GetLicenseByRotatedKey(ctx context.Context, key string) (*models.License, error)
 	ListLicenses(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.License, int, error)
+	// AddAutoAllowedIP adds ip to the allowed IPs of the license if it
+	// auto-allows IPs and is below its limit, and reports whether ip is
+	// allowed now.
+	AddAutoAllowedIP(ctx context.Context, key, ip string) (bool, error)
+	// ResumeExpiredSuspensions reactivates suspended licenses whose resume
+	// time is not after now and returns them as they were before resuming.
+	ResumeExpiredSuspensions(ctx context.Context, now time.Time) ([]models.License, error)
+	// CreateLicenseWithMissingCatalog is CreateLicense that first creates,
+	// in the same transaction, the features and releases the license cannot
+	// link. It returns the codes and versions it created.
+	CreateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error)
+	// UpdateLicenseWithMissingCatalog is UpdateLicense that first creates,
+	// in the same transaction, the features and releases the license cannot
+	// link. It returns the codes and versions it created.
+	UpdateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error)
 }
 
 type PostgresLicenseStore struct {
@@ -29,17 +47,26 @@ func NewPostgresLicenseStore(db *pgxpool.Pool) *PostgresLicenseStore {
 }
 
 func (s *PostgresLicenseStore) CreateLicense(ctx context.Context, license *models.License) error {
+	_, _, err := s.createLicense(ctx, license, false)
+	return err
+}
+
+func (s *PostgresLicenseStore) CreateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error) {
+	return s.createLicense(ctx, license, true)
+}
+
+func (s *PostgresLicenseStore) createLicense(ctx context.Context, license *models.License, createMissing bool) ([]string, []string, error) {
 	tx, err := s.DB.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	query := `
 		INSERT INTO licenses (
-			id, key, owner_id, type, product_id, allowed_ips, allowed_networks, expires_at, created_at, updated_at, status, auto_allowed_ip, auto_allowed_ip_limit
+			id, key, owner_id, type, product_id, allowed_ips, allowed_networks, expires_at, created_at, updated_at, status, auto_allowed_ip, auto_allowed_ip_limit, suspension_reason, suspended_until, plan_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		)
 	`
 	_, err = tx.Exec(ctx, query,
@@ -56,50 +83,44 @@ func (s *PostgresLicenseStore) CreateLicense(ctx context.Context, license *model
 		license.Status,
 		license.AutoAllowedIP,
 		license.AutoAllowedIPLimit,
+		license.SuspensionReason,
+		license.SuspendedUntil,
+		license.PlanID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create license: %w", err)
+		return nil, nil, fmt.Errorf("failed to create license: %w", err)
 	}
 
-	if len(license.Features) > 0 {
-		fQuery := `
-			INSERT INTO license_features (license_id, feature_id)
-			SELECT $1, f.id 
-			FROM features f
-			JOIN products p ON p.id = $2
-			WHERE (f.product_id = $2 OR (f.product_group_id = p.product_group_id AND f.product_group_id IS NOT NULL))
-			AND f.code = ANY($3)
-		`
-		if _, err := tx.Exec(ctx, fQuery, license.ID, license.ProductID, license.Features); err != nil {
-			return fmt.Errorf("failed to link features: %w", err)
+	var createdFeatures, createdReleases []string
+	if createMissing {
+		if createdFeatures, createdReleases, err = createMissingCatalogEntries(ctx, tx, license); err != nil {
+			return nil, nil, err
 		}
 	}
-
-	if len(license.Releases) > 0 {
-		rQuery := `
-			INSERT INTO license_releases (license_id, release_id)
-			SELECT $1, r.id 
-			FROM releases r
-			JOIN products p ON p.id = $2
-			WHERE (r.product_id = $2 OR (r.product_group_id = p.product_group_id AND r.product_group_id IS NOT NULL))
-			AND r.version = ANY($3)
-		`
-		if _, err := tx.Exec(ctx, rQuery, license.ID, license.ProductID, license.Releases); err != nil {
-			return fmt.Errorf("failed to link releases: %w", err)
-		}
+	if err := linkLicenseCatalog(ctx, tx, license); err != nil {
+		return nil, nil, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	return createdFeatures, createdReleases, nil
 }
 
 func (s *PostgresLicenseStore) UpdateLicense(ctx context.Context, license *models.License) error {
+	_, _, err := s.updateLicense(ctx, license, false)
+	return err
+}
+
+func (s *PostgresLicenseStore) UpdateLicenseWithMissingCatalog(ctx context.Context, license *models.License) ([]string, []string, error) {
+	return s.updateLicense(ctx, license, true)
+}
+
+func (s *PostgresLicenseStore) updateLicense(ctx context.Context, license *models.License, createMissing bool) ([]string, []string, error) {
 	tx, err := s.DB.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -112,8 +133,11 @@ func (s *PostgresLicenseStore) UpdateLicense(ctx context.Context, license *model
 			updated_at = $5,
 			status = $6,
 			auto_allowed_ip = $7,
-			auto_allowed_ip_limit = $8
-		WHERE key = $9
+			auto_allowed_ip_limit = $8,
+			suspension_reason = $9,
+			suspended_until = $10,
+			plan_id = $11
+		WHERE key = $12
 	`
 	res, err := tx.Exec(ctx, query,
 		license.Type,
@@ -124,71 +148,180 @@ func (s *PostgresLicenseStore) UpdateLicense(ctx context.Context, license *model
 		license.Status,
 		license.AutoAllowedIP,
 		license.AutoAllowedIPLimit,
+		license.SuspensionReason,
+		license.SuspendedUntil,
+		license.PlanID,
 		license.Key,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update license: %w", err)
+		return nil, nil, fmt.Errorf("failed to update license: %w", err)
 	}
 	if res.RowsAffected() == 0 {
-		return fmt.Errorf("%w: license", ErrNotFound)
+		return nil, nil, fmt.Errorf("%w: license", ErrNotFound)
+	}
+
+	var createdFeatures, createdReleases []string
+	if createMissing {
+		if createdFeatures, createdReleases, err = createMissingCatalogEntries(ctx, tx, license); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// Re-link features
+	// Re-link features and releases
 	if _, err := tx.Exec(ctx, `DELETE FROM license_features WHERE license_id = $1`, license.ID); err != nil {
-		return fmt.Errorf("failed to clear features: %w", err)
+		return nil, nil, fmt.Errorf("failed to clear features: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM license_releases WHERE license_id = $1`, license.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to clear releases: %w", err)
+	}
+	if err := linkLicenseCatalog(ctx, tx, license); err != nil {
+		return nil, nil, err
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return createdFeatures, createdReleases, nil
+}
+
+// linkLicenseCatalog links the features and releases of license. Each code
+// and version links the entry of the product, else of its group, else the
+// global one. Codes and versions that match none fail with an
+// *UnknownCatalogError.
+func linkLicenseCatalog(ctx context.Context, tx pgx.Tx, license *models.License) error {
+	var unknownFeatures, unknownReleases []string
 	if len(license.Features) > 0 {
 		fQuery := `
-			INSERT INTO license_features (license_id, feature_id)
-			SELECT $1, f.id 
-			FROM features f
-			JOIN products p ON p.id = $2
-			WHERE (f.product_id = $2 OR (f.product_group_id = p.product_group_id AND f.product_group_id IS NOT NULL))
-			AND f.code = ANY($3)
+			WITH linked AS (
+				INSERT INTO license_features (license_id, feature_id, value)
+				SELECT DISTINCT ON (f.code) $1, f.id, $4::jsonb -> f.code
+				FROM features f
+				JOIN products p ON p.id = $2
+				WHERE f.code = ANY($3)
+				AND (f.product_id = $2
+					OR (f.product_group_id = p.product_group_id AND f.product_group_id IS NOT NULL)
+					OR (f.product_id IS NULL AND f.product_group_id IS NULL))
+				ORDER BY f.code, f.product_id IS NULL, f.product_group_id IS NULL
+				RETURNING feature_id
+			)
+			SELECT DISTINCT c FROM unnest($3::text[]) AS c
+			WHERE c NOT IN (SELECT f.code FROM linked JOIN features f ON f.id = linked.feature_id)
+			ORDER BY c
 		`
-
-		if _, err := tx.Exec(ctx, fQuery, license.ID, license.ProductID, license.Features); err != nil {
+		codes, err := queryStrings(ctx, tx, fQuery, license.ID, license.ProductID, license.Features, featureValuesOrEmpty(license.FeatureValues))
+		if err != nil {
 			return fmt.Errorf("failed to link features: %w", err)
 		}
+		unknownFeatures = codes
 	}
 
-	// Re-link releases
-	if _, err := tx.Exec(ctx, `DELETE FROM license_releases WHERE license_id = $1`, license.ID); err != nil {
-		return fmt.Errorf("failed to clear releases: %w", err)
-	}
 	if len(license.Releases) > 0 {
 		rQuery := `
-			INSERT INTO license_releases (license_id, release_id)
-			SELECT $1, r.id 
-			FROM releases r
-			JOIN products p ON p.id = $2
-			WHERE (r.product_id = $2 OR (r.product_group_id = p.product_group_id AND r.product_group_id IS NOT NULL))
-			AND r.version = ANY($3)
+			WITH linked AS (
+				INSERT INTO license_releases (license_id, release_id)
+				SELECT DISTINCT ON (r.version) $1, r.id
+				FROM releases r
+				JOIN products p ON p.id = $2
+				WHERE r.version = ANY($3)
+				AND (r.product_id = $2
+					OR (r.product_group_id = p.product_group_id AND r.product_group_id IS NOT NULL)
+					OR (r.product_id IS NULL AND r.product_group_id IS NULL))
+				ORDER BY r.version, r.product_id IS NULL, r.product_group_id IS NULL
+				RETURNING release_id
+			)
+			SELECT DISTINCT v FROM unnest($3::text[]) AS v
+			WHERE v NOT IN (SELECT r.version FROM linked JOIN releases r ON r.id = linked.release_id)
+			ORDER BY v
 		`
-		if _, err := tx.Exec(ctx, rQuery, license.ID, license.ProductID, license.Releases); err != nil {
+		versions, err := queryStrings(ctx, tx, rQuery, license.ID, license.ProductID, license.Releases)
+		if err != nil {
 			return fmt.Errorf("failed to link releases: %w", err)
 		}
+		unknownReleases = versions
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if len(unknownFeatures) > 0 || len(unknownReleases) > 0 {
+		return &UnknownCatalogError{FeatureCodes: unknownFeatures, ReleaseVersions: unknownReleases}
 	}
-
 	return nil
 }
 
+// createMissingCatalogEntries creates, in the scope of its product, the
+// features and releases of license that linkLicenseCatalog would not find,
+// and returns the codes and versions it created.
+func createMissingCatalogEntries(ctx context.Context, tx pgx.Tx, license *models.License) ([]string, []string, error) {
+	var createdFeatures, createdReleases []string
+	var err error
+	if len(license.Features) > 0 {
+		fQuery := `
+			INSERT INTO features (product_id, owner_id, name, code)
+			SELECT $1, $2, c, c
+			FROM (SELECT DISTINCT unnest($3::text[]) AS c) codes
+			WHERE NOT EXISTS (
+				SELECT 1 FROM features f
+				JOIN products p ON p.id = $1
+				WHERE f.code = codes.c
+				AND (f.product_id = $1
+					OR (f.product_group_id = p.product_group_id AND f.product_group_id IS NOT NULL)
+					OR (f.product_id IS NULL AND f.product_group_id IS NULL))
+			)
+			ON CONFLICT DO NOTHING
+			RETURNING code
+		`
+		createdFeatures, err = queryStrings(ctx, tx, fQuery, license.ProductID, license.OwnerID, license.Features)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create features: %w", err)
+		}
+	}
+
+	if len(license.Releases) > 0 {
+		rQuery := `
+			INSERT INTO releases (product_id, owner_id, version)
+			SELECT $1, $2, v
+			FROM (SELECT DISTINCT unnest($3::text[]) AS v) versions
+			WHERE NOT EXISTS (
+				SELECT 1 FROM releases r
+				JOIN products p ON p.id = $1
+				WHERE r.version = versions.v
+				AND (r.product_id = $1
+					OR (r.product_group_id = p.product_group_id AND r.product_group_id IS NOT NULL)
+					OR (r.product_id IS NULL AND r.product_group_id IS NULL))
+			)
+			ON CONFLICT DO NOTHING
+			RETURNING version
+		`
+		createdReleases, err = queryStrings(ctx, tx, rQuery, license.ProductID, license.OwnerID, license.Releases)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create releases: %w", err)
+		}
+	}
+
+	return createdFeatures, createdReleases, nil
+}
+
+// queryStrings returns the single text column of the rows of query.
+func queryStrings(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
 func (s *PostgresLicenseStore) GetLicenseByKey(ctx context.Context, key string) (*models.License, error) {
 	query := `
 		SELECT 
 			l.id, l.key, l.owner_id, l.type, l.product_id, 
 			l.allowed_ips::text[], l.allowed_networks::text[], 
-			l.expires_at, l.created_at, l.updated_at, l.status, l.auto_allowed_ip, l.auto_allowed_ip_limit,
+			l.expires_at, l.created_at, l.updated_at, l.status, l.auto_allowed_ip, l.auto_allowed_ip_limit, l.suspension_reason, l.suspended_until, l.plan_id,
 			COALESCE(array_agg(DISTINCT f.code) FILTER (WHERE f.code IS NOT NULL), '{}')::text[] as features,
+			COALESCE(jsonb_object_agg(f.code, lf.value) FILTER (WHERE f.code IS NOT NULL AND lf.value IS NOT NULL), '{}') as feature_values,
 			COALESCE(array_agg(DISTINCT r.version) FILTER (WHERE r.version IS NOT NULL), '{}')::text[] as releases
 		FROM licenses l
 		LEFT JOIN license_features lf ON l.id = lf.license_id
 		LEFT JOIN features f ON lf.feature_id = f.id
-		LEFT JOIN license_releases lr ON l.id = lr.release_id
+		LEFT JOIN license_releases lr ON l.id = lr.license_id
 		LEFT JOIN releases r ON lr.release_id = r.id
 		WHERE l.key = $1
 		GROUP BY l.id
@@ -208,7 +341,11 @@ func (s *PostgresLicenseStore) GetLicenseByKey(ctx context.Context, key string)
 		&l.Status,
 		&l.AutoAllowedIP,
 		&l.AutoAllowedIPLimit,
+		&l.SuspensionReason,
+		&l.SuspendedUntil,
+		&l.PlanID,
 		&l.Features,
+		&l.FeatureValues,
 		&l.Releases,
 	)
 	if err != nil {
@@ -233,18 +370,118 @@ func (s *PostgresLicenseStore) DeleteLicense(ctx context.Context, key string) er
 	return nil
 }
 
+// AddAutoAllowedIP checks the limit in the same statement that adds ip, so
+// concurrent checks from new IPs cannot exceed it. An ip added meanwhile by
+// another check counts as allowed.
+func (s *PostgresLicenseStore) AddAutoAllowedIP(ctx context.Context, key, ip string) (bool, error) {
+	query := `
+		UPDATE licenses SET
+			allowed_ips = CASE WHEN $2::inet = ANY(COALESCE(allowed_ips, '{}')) THEN allowed_ips
+				ELSE array_append(COALESCE(allowed_ips, '{}'), $2::inet) END,
+			updated_at = NOW()
+		WHERE key = $1 AND auto_allowed_ip
+		AND ($2::inet = ANY(COALESCE(allowed_ips, '{}')) OR COALESCE(cardinality(allowed_ips), 0) < auto_allowed_ip_limit)
+	`
+	tag, err := s.DB.Exec(ctx, query, key, ip)
+	if err != nil {
+		return false, fmt.Errorf("failed to add allowed IP: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RotateLicenseKey swaps the key of a license in place and keeps the old key
+// as an alias so it can still be traced back to the same license.
+func (s *PostgresLicenseStore) RotateLicenseKey(ctx context.Context, oldKey, newKey string) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var licenseID string
+	err = tx.QueryRow(ctx, `UPDATE licenses SET key = $1, updated_at = NOW() WHERE key = $2 RETURNING id`, newKey, oldKey).Scan(&licenseID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: license", ErrNotFound)
+		}
+		return fmt.Errorf("failed to rotate license key: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO license_key_aliases (key, license_id) VALUES ($1, $2)`, oldKey, licenseID); err != nil {
+		return fmt.Errorf("failed to record rotated key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresLicenseStore) ResumeExpiredSuspensions(ctx context.Context, now time.Time) ([]models.License, error) {
+	query := `
+		WITH due AS (
+			SELECT id, suspension_reason, suspended_until
+			FROM licenses
+			WHERE status = 'suspended' AND suspended_until <= $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE licenses l SET
+			status = 'active',
+			suspension_reason = NULL,
+			suspended_until = NULL,
+			updated_at = NOW()
+		FROM due
+		WHERE l.id = due.id
+		RETURNING l.id, l.key, l.owner_id, l.product_id, due.suspension_reason, due.suspended_until
+	`
+	rows, err := s.DB.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume suspended licenses: %w", err)
+	}
+	defer rows.Close()
+
+	var licenses []models.License
+	for rows.Next() {
+		l := models.License{Status: models.LicenseStatusSuspended}
+		if err := rows.Scan(&l.ID, &l.Key, &l.OwnerID, &l.ProductID, &l.SuspensionReason, &l.SuspendedUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan resumed license: %w", err)
+		}
+		licenses = append(licenses, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating resumed licenses: %w", err)
+	}
+
+	return licenses, nil
+}
+
+// GetLicenseByRotatedKey returns the license a rotated (retired) key used to belong to.
+func (s *PostgresLicenseStore) GetLicenseByRotatedKey(ctx context.Context, key string) (*models.License, error) {
+	var licenseID string
+	err := s.DB.QueryRow(ctx, `SELECT license_id FROM license_key_aliases WHERE key = $1`, key).Scan(&licenseID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: license key alias", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get license key alias: %w", err)
+	}
+	return s.GetLicense(ctx, licenseID)
+}
+
 func (s *PostgresLicenseStore) GetLicense(ctx context.Context, id string) (*models.License, error) {
 	query := `
 		SELECT 
 			l.id, l.key, l.owner_id, l.type, l.product_id, 
 			l.allowed_ips::text[], l.allowed_networks::text[], 
-			l.expires_at, l.created_at, l.updated_at, l.status, l.auto_allowed_ip, l.auto_allowed_ip_limit,
+			l.expires_at, l.created_at, l.updated_at, l.status, l.auto_allowed_ip, l.auto_allowed_ip_limit, l.suspension_reason, l.suspended_until, l.plan_id,
 			COALESCE(array_agg(DISTINCT f.code) FILTER (WHERE f.code IS NOT NULL), '{}')::text[] as features,
+			COALESCE(jsonb_object_agg(f.code, lf.value) FILTER (WHERE f.code IS NOT NULL AND lf.value IS NOT NULL), '{}') as feature_values,
 			COALESCE(array_agg(DISTINCT r.version) FILTER (WHERE r.version IS NOT NULL), '{}')::text[] as releases
 		FROM licenses l
 		LEFT JOIN license_features lf ON l.id = lf.license_id
 		LEFT JOIN features f ON lf.feature_id = f.id
-		LEFT JOIN license_releases lr ON l.id = lr.release_id
+		LEFT JOIN license_releases lr ON l.id = lr.license_id
 		LEFT JOIN releases r ON lr.release_id = r.id
 		WHERE l.id = $1
 		GROUP BY l.id
@@ -264,7 +501,11 @@ func (s *PostgresLicenseStore) GetLicense(ctx context.Context, id string) (*mode
 		&l.Status,
 		&l.AutoAllowedIP,
 		&l.AutoAllowedIPLimit,
+		&l.SuspensionReason,
+		&l.SuspendedUntil,
+		&l.PlanID,
 		&l.Features,
+		&l.FeatureValues,
 		&l.Releases,
 	)
 	if err != nil {
@@ -297,13 +538,14 @@ func (s *PostgresLicenseStore) ListLicenses(ctx context.Context, ownerID *string
 		SELECT 
 			l.id, l.key, l.owner_id, l.type, l.product_id, 
 			l.allowed_ips::text[], l.allowed_networks::text[], 
-			l.expires_at, l.created_at, l.updated_at, l.status, l.auto_allowed_ip, l.auto_allowed_ip_limit,
+			l.expires_at, l.created_at, l.updated_at, l.status, l.auto_allowed_ip, l.auto_allowed_ip_limit, l.suspension_reason, l.suspended_until, l.plan_id,
 			COALESCE(array_agg(DISTINCT f.code) FILTER (WHERE f.code IS NOT NULL), '{}')::text[] as features,
+			COALESCE(jsonb_object_agg(f.code, lf.value) FILTER (WHERE f.code IS NOT NULL AND lf.value IS NOT NULL), '{}') as feature_values,
 			COALESCE(array_agg(DISTINCT r.version) FILTER (WHERE r.version IS NOT NULL), '{}')::text[] as releases
 		FROM licenses l
 		LEFT JOIN license_features lf ON l.id = lf.license_id
 		LEFT JOIN features f ON lf.feature_id = f.id
-		LEFT JOIN license_releases lr ON l.id = lr.release_id
+		LEFT JOIN license_releases lr ON l.id = lr.license_id
 		LEFT JOIN releases r ON lr.release_id = r.id
 	`
 	
@@ -351,7 +593,11 @@ func (s *PostgresLicenseStore) ListLicenses(ctx context.Context, ownerID *string
 			&l.Status,
 			&l.AutoAllowedIP,
 			&l.AutoAllowedIPLimit,
+			&l.SuspensionReason,
+			&l.SuspendedUntil,
+			&l.PlanID,
 			&l.Features,
+			&l.FeatureValues,
 			&l.Releases,
 		)
 		if err != nil {
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/models"
+)
+
+func TestLicenseStore_LinkCatalog(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t, "clortho_test_catalog")
+
+	groupStore := NewPostgresProductGroupStore(pool)
+	productStore := NewPostgresProductStore(pool)
+	featureStore := NewPostgresFeatureStore(pool)
+	releaseStore := NewPostgresReleaseStore(pool)
+	licenseStore := NewPostgresLicenseStore(pool)
+
+	group := &models.ProductGroup{ID: uuid.New(), Name: "Suite", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, groupStore.CreateProductGroup(ctx, group))
+	product := &models.Product{ID: uuid.New(), Name: "Editor", ProductGroupID: &group.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, productStore.CreateProduct(ctx, product))
+
+	// "export" is global, and the product's own "sso" shadows the group's
+	globalExport := &models.Feature{ID: uuid.New(), Name: "Export", Code: "export", CreatedAt: time.Now()}
+	groupSSO := &models.Feature{ID: uuid.New(), ProductGroupID: &group.ID, Name: "SSO", Code: "sso", CreatedAt: time.Now()}
+	productSSO := &models.Feature{ID: uuid.New(), ProductID: &product.ID, Name: "SSO", Code: "sso", CreatedAt: time.Now()}
+	for _, f := range []*models.Feature{globalExport, groupSSO, productSSO} {
+		require.NoError(t, featureStore.CreateFeature(ctx, f))
+	}
+	require.NoError(t, releaseStore.CreateRelease(ctx, &models.Release{ID: uuid.New(), Version: "1.0.0", CreatedAt: time.Now()}))
+
+	newLicense := func(key string, features, releases []string) *models.License {
+		return &models.License{
+			ID: uuid.New(), Key: key, ProductID: product.ID, Type: models.LicenseTypePerpetual, Status: models.LicenseStatusActive,
+			Features: features, Releases: releases, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+	}
+	linkedFeatures := func(t *testing.T, license *models.License) map[string]uuid.UUID {
+		t.Helper()
+		rows, err := pool.Query(ctx, `SELECT f.code, f.id FROM license_features lf JOIN features f ON f.id = lf.feature_id WHERE lf.license_id = $1`, license.ID)
+		require.NoError(t, err)
+		defer rows.Close()
+		linked := map[string]uuid.UUID{}
+		for rows.Next() {
+			var code string
+			var id uuid.UUID
+			require.NoError(t, rows.Scan(&code, &id))
+			linked[code] = id
+		}
+		require.NoError(t, rows.Err())
+		return linked
+	}
+	countFeatures := func(t *testing.T, code string) int {
+		t.Helper()
+		var n int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM features WHERE code = $1`, code).Scan(&n))
+		return n
+	}
+
+	t.Run("GlobalAndShadowedEntries", func(t *testing.T) {
+		license := newLicense("CATALOG-LINKED", []string{"export", "sso"}, []string{"1.0.0"})
+		require.NoError(t, licenseStore.CreateLicense(ctx, license))
+
+		linked := linkedFeatures(t, license)
+		assert.Equal(t, map[string]uuid.UUID{"export": globalExport.ID, "sso": productSSO.ID}, linked)
+
+		stored, err := licenseStore.GetLicenseByKey(ctx, license.Key)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.0.0"}, stored.Releases)
+	})
+
+	t.Run("UnknownEntries", func(t *testing.T) {
+		license := newLicense("CATALOG-UNKNOWN", []string{"sso", "typo"}, []string{"9.9.9"})
+		err := licenseStore.CreateLicense(ctx, license)
+
+		var unknown *UnknownCatalogError
+		require.True(t, errors.As(err, &unknown), "expected an UnknownCatalogError, got %v", err)
+		assert.Equal(t, []string{"typo"}, unknown.FeatureCodes)
+		assert.Equal(t, []string{"9.9.9"}, unknown.ReleaseVersions)
+
+		_, err = licenseStore.GetLicenseByKey(ctx, license.Key)
+		assert.Error(t, err, "the rejected license must not be saved")
+	})
+
+	t.Run("CreateMissing", func(t *testing.T) {
+		license := newLicense("CATALOG-CREATED", []string{"export", "reports"}, []string{"1.0.0", "3.0.0"})
+		features, releases, err := licenseStore.CreateLicenseWithMissingCatalog(ctx, license)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reports"}, features)
+		assert.Equal(t, []string{"3.0.0"}, releases)
+
+		var productID *uuid.UUID
+		require.NoError(t, pool.QueryRow(ctx, `SELECT product_id FROM features WHERE code = 'reports'`).Scan(&productID))
+		require.NotNil(t, productID)
+		assert.Equal(t, product.ID, *productID)
+		assert.Contains(t, linkedFeatures(t, license), "reports")
+	})
+
+	t.Run("CreateMissingRolledBack", func(t *testing.T) {
+		// Linking fails after the missing entries were created
+		_, err := pool.Exec(ctx, `
+			CREATE FUNCTION fail_license_link() RETURNS trigger AS $$
+			BEGIN RAISE EXCEPTION 'link failed'; END
+			$$ LANGUAGE plpgsql;
+			CREATE TRIGGER fail_license_link BEFORE INSERT ON license_features
+			FOR EACH ROW EXECUTE FUNCTION fail_license_link();
+		`)
+		require.NoError(t, err)
+		defer pool.Exec(ctx, `DROP TRIGGER fail_license_link ON license_features; DROP FUNCTION fail_license_link()`)
+
+		license := newLicense("CATALOG-ROLLED-BACK", []string{"orphan"}, []string{"4.0.0"})
+		_, _, err = licenseStore.CreateLicenseWithMissingCatalog(ctx, license)
+		require.Error(t, err)
+		assert.Equal(t, 0, countFeatures(t, "orphan"))
+		var releases int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM releases WHERE version = '4.0.0'`).Scan(&releases))
+		assert.Equal(t, 0, releases)
+		_, err = licenseStore.GetLicenseByKey(ctx, license.Key)
+		assert.Error(t, err, "the failed license must not be saved")
+	})
+}
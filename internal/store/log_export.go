@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"clortho/internal/models"
+)
+
+// logExportBatch is the number of rows an export reads per query. The
+// connection is released between batches, so a slow client does not hold it.
+const logExportBatch = 1000
+
+// licenseCheckLogColumns are the columns of license_check_logs aliased as l
+// read by scanLicenseCheckLog.
+const licenseCheckLogColumns = `l.id, l.product_id, l.license_id, l.license_key, l.request_payload, l.response_payload, l.ip_address, l.user_agent, l.status_code,
+	l.client_app_version, l.client_os, l.client_hostname_hash, l.client_sdk_version, l.created_at`
+
+// scanLicenseCheckLog reads a row of licenseCheckLogColumns.
+func scanLicenseCheckLog(row pgx.Row) (models.LicenseCheckLog, error) {
+	var log models.LicenseCheckLog
+	var requestPayloadJSON, responsePayloadJSON []byte
+	if err := row.Scan(
+		&log.ID,
+		&log.ProductID,
+		&log.LicenseID,
+		&log.LicenseKey,
+		&requestPayloadJSON,
+		&responsePayloadJSON,
+		&log.IPAddress,
+		&log.UserAgent,
+		&log.StatusCode,
+		&log.Client.AppVersion,
+		&log.Client.OS,
+		&log.Client.HostnameHash,
+		&log.Client.SDKVersion,
+		&log.CreatedAt,
+	); err != nil {
+		return log, fmt.Errorf("failed to scan license check log: %w", err)
+	}
+
+	if err := json.Unmarshal(requestPayloadJSON, &log.RequestPayload); err != nil {
+		return log, fmt.Errorf("failed to unmarshal request payload: %w", err)
+	}
+	if err := json.Unmarshal(responsePayloadJSON, &log.ResponsePayload); err != nil {
+		return log, fmt.Errorf("failed to unmarshal response payload: %w", err)
+	}
+	return log, nil
+}
+
+// ExportLicenseCheckLogs calls fn with every license check log matching
+// filter, oldest first. It stops at the first error fn returns.
+func (s *PostgresLogStore) ExportLicenseCheckLogs(ctx context.Context, filter models.LicenseCheckLogFilter, fn func(*models.LicenseCheckLog) error) error {
+	q := newLicenseCheckLogQuery(filter)
+
+	return exportBatches(ctx, func(after *models.LogPageCursor) (int, *models.LogPageCursor, error) {
+		query, args := q.page(after, false, logExportBatch)
+		logs, err := queryBatch(ctx, s, query, args, scanLicenseCheckLog)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to export license check logs: %w", err)
+		}
+		for i := range logs {
+			if err := fn(&logs[i]); err != nil {
+				return 0, nil, err
+			}
+		}
+		if len(logs) == 0 {
+			return 0, nil, nil
+		}
+		last := logs[len(logs)-1]
+		return len(logs), &models.LogPageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+	})
+}
+
+// ExportAdminLogs calls fn with every admin log matching filter, oldest
+// first. It stops at the first error fn returns.
+func (s *PostgresLogStore) ExportAdminLogs(ctx context.Context, filter models.AdminLogFilter, fn func(*models.AdminLog) error) error {
+	conditions, args := adminLogFilterConditions(filter)
+
+	return exportBatches(ctx, func(after *models.LogPageCursor) (int, *models.LogPageCursor, error) {
+		batchConditions, batchArgs := conditions, args
+		if after != nil {
+			batchArgs = append(batchArgs, after.CreatedAt, after.ID)
+			batchConditions = append(batchConditions, fmt.Sprintf("(created_at, id) > ($%d, $%d)", len(batchArgs)-1, len(batchArgs)))
+		}
+		where := ""
+		if len(batchConditions) > 0 {
+			where = " WHERE " + strings.Join(batchConditions, " AND ")
+		}
+		query := `SELECT ` + adminLogColumns + ` FROM admin_logs` + where + fmt.Sprintf(` ORDER BY created_at, id LIMIT %d`, logExportBatch)
+
+		logs, err := queryBatch(ctx, s, query, batchArgs, scanAdminLog)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to export admin logs: %w", err)
+		}
+		for i := range logs {
+			if err := fn(&logs[i]); err != nil {
+				return 0, nil, err
+			}
+		}
+		if len(logs) == 0 {
+			return 0, nil, nil
+		}
+		last := logs[len(logs)-1]
+		return len(logs), &models.LogPageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+	})
+}
+
+// exportBatches runs batch from the start and then after the last row of the
+// previous batch until one comes back short.
+func exportBatches(ctx context.Context, batch func(after *models.LogPageCursor) (int, *models.LogPageCursor, error)) error {
+	var after *models.LogPageCursor
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, last, err := batch(after)
+		if err != nil {
+			return err
+		}
+		if n < logExportBatch {
+			return nil
+		}
+		after = last
+	}
+}
+
+// queryBatch reads every row of query before returning, releasing the connection.
+func queryBatch[T any](ctx context.Context, s *PostgresLogStore, query string, args []interface{}, scan func(pgx.Row) (T, error)) ([]T, error) {
+	rows, err := s.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
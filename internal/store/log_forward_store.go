@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"clortho/internal/models"
+)
+
+// LogForwardStore reads the log tables in the order rows were written and
+// keeps the cursors of the log forwarder.
+type LogForwardStore interface {
+	// LogForwardCursor returns the cursor saved under name, or nil if there
+	// is none.
+	LogForwardCursor(ctx context.Context, name string) (*models.LogCursor, error)
+	SaveLogForwardCursor(ctx context.Context, name string, cursor models.LogCursor) error
+	// ListLogRecords returns up to limit rows of table written after the
+	// cursor and before `before`, in the order they were written.
+	ListLogRecords(ctx context.Context, table string, after models.LogCursor, before time.Time, limit int) ([]models.LogRecord, error)
+}
+
+func (s *PostgresLogStore) LogForwardCursor(ctx context.Context, name string) (*models.LogCursor, error) {
+	var cursor models.LogCursor
+	err := s.DB.QueryRow(ctx, `SELECT ingested_at, last_id FROM log_forward_cursors WHERE name = $1`, name).Scan(&cursor.IngestedAt, &cursor.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read log forward cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+func (s *PostgresLogStore) SaveLogForwardCursor(ctx context.Context, name string, cursor models.LogCursor) error {
+	query := `
+		INSERT INTO log_forward_cursors (name, ingested_at, last_id, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE SET ingested_at = EXCLUDED.ingested_at, last_id = EXCLUDED.last_id, updated_at = NOW()`
+
+	if _, err := s.DB.Exec(ctx, query, name, cursor.IngestedAt, cursor.ID); err != nil {
+		return fmt.Errorf("failed to save log forward cursor: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresLogStore) ListLogRecords(ctx context.Context, table string, after models.LogCursor, before time.Time, limit int) ([]models.LogRecord, error) {
+	if table != LicenseCheckLogsTable && table != AdminLogsTable {
+		return nil, fmt.Errorf("unknown log table %q", table)
+	}
+
+	query := `
+		SELECT ingested_at, id, created_at, row_to_json(t)::text
+		FROM ` + pgx.Identifier{table}.Sanitize() + ` t
+		WHERE (ingested_at, id) > ($1, $2) AND ingested_at < $3
+		ORDER BY ingested_at, id
+		LIMIT $4`
+
+	rows, err := s.DB.Query(ctx, query, after.IngestedAt, after.ID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var records []models.LogRecord
+	for rows.Next() {
+		record := models.LogRecord{Table: table}
+		if err := rows.Scan(&record.Cursor.IngestedAt, &record.Cursor.ID, &record.CreatedAt, &record.Data); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
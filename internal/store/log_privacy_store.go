@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"clortho/internal/models"
+)
+
+// LogPrivacyStore enforces the privacy policy on license check logs already
+// written: it deletes expired checks and scrubs those of a data subject.
+type LogPrivacyStore interface {
+	// EraseLicenseCheckLogs scrubs the client data of every check of the
+	// licenses selected by req, under their current and rotated keys, and
+	// returns the number of checks scrubbed.
+	EraseLicenseCheckLogs(ctx context.Context, req models.LogErasureRequest) (int64, error)
+	// DeleteExpiredLicenseCheckLogs deletes the checks older than the
+	// retention of their product, or defaultDays for products without one,
+	// along with the client networks rolled up from them. A retention of 0
+	// keeps checks.
+	DeleteExpiredLicenseCheckLogs(ctx context.Context, defaultDays int, now time.Time) (int64, error)
+}
+
+func (s *PostgresLogStore) EraseLicenseCheckLogs(ctx context.Context, req models.LogErasureRequest) (int64, error) {
+	var where string
+	var arg interface{}
+	switch {
+	case req.LicenseID != nil:
+		where, arg = `l.id = $1`, *req.LicenseID
+	case req.OwnerID != "":
+		where, arg = `l.owner_id = $1`, req.OwnerID
+	case req.LicenseKey != "":
+		where, arg = `(l.key = $1 OR l.id IN (SELECT license_id FROM license_key_aliases WHERE key = $1))`, req.LicenseKey
+	default:
+		return 0, fmt.Errorf("no license or owner to erase")
+	}
+
+	query := `
+		SELECT l.id, l.key FROM licenses l WHERE ` + where + `
+		UNION ALL
+		SELECT a.license_id, a.key FROM license_key_aliases a JOIN licenses l ON a.license_id = l.id WHERE ` + where
+
+	rows, err := s.DB.Query(ctx, query, arg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find licenses to erase: %w", err)
+	}
+	var licenseIDs []uuid.UUID
+	var keys []string
+	for rows.Next() {
+		var id uuid.UUID
+		var key string
+		if err := rows.Scan(&id, &key); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan license to erase: %w", err)
+		}
+		licenseIDs = append(licenseIDs, id)
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to find licenses to erase: %w", err)
+	}
+
+	// A deleted license is only known by its key
+	if req.LicenseKey != "" {
+		keys = append(keys, req.LicenseKey)
+	}
+	hashed := make([]string, len(keys))
+	for i, key := range keys {
+		hashed[i] = models.HashLicenseKey(key)
+	}
+	keys = append(keys, hashed...)
+
+	// Keep what analytics need: the product, time, status and outcome
+	update := `
+		UPDATE license_check_logs SET
+			license_id = NULL,
+			license_key = '',
+			ip_address = '',
+			user_agent = '',
+			client_hostname_hash = '',
+			request_payload = '{}',
+			response_payload = jsonb_strip_nulls(jsonb_build_object(
+				'valid', response_payload->'valid',
+				'reason', response_payload->'reason',
+				'error', response_payload->'error'))
+		WHERE license_id = ANY($1) OR license_key = ANY($2)`
+
+	tag, err := s.DB.Exec(ctx, update, licenseIDs, keys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to erase license check logs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *PostgresLogStore) DeleteExpiredLicenseCheckLogs(ctx context.Context, defaultDays int, now time.Time) (int64, error) {
+	rows, err := s.DB.Query(ctx, `SELECT id, COALESCE((log_privacy->>'retention_days')::int, $1) FROM products`, defaultDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read log retention: %w", err)
+	}
+	retention := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var id uuid.UUID
+		var days int
+		if err := rows.Scan(&id, &days); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan log retention: %w", err)
+		}
+		retention[id] = days
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read log retention: %w", err)
+	}
+
+	var deleted int64
+	for productID, days := range retention {
+		if days <= 0 {
+			continue
+		}
+		n, err := s.deleteChecksBefore(ctx, &productID, now.AddDate(0, 0, -days))
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	// Checks of unknown keys have no product
+	if defaultDays > 0 {
+		n, err := s.deleteChecksBefore(ctx, nil, now.AddDate(0, 0, -defaultDays))
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// deleteChecksBefore deletes the checks of a product, or of no product when
+// productID is nil, from before cutoff. IP rollup buckets starting before
+// cutoff go with them, as they may hold the networks of deleted checks.
+func (s *PostgresLogStore) deleteChecksBefore(ctx context.Context, productID *uuid.UUID, cutoff time.Time) (int64, error) {
+	product, args := `product_id IS NULL`, []interface{}{cutoff}
+	if productID != nil {
+		product, args = `product_id = $2`, append(args, *productID)
+	}
+
+	for _, table := range []string{"check_ip_rollups_hourly", "check_ip_rollups_daily"} {
+		if _, err := s.DB.Exec(ctx, `DELETE FROM `+table+` WHERE `+product+` AND bucket_start < $1`, args...); err != nil {
+			return 0, fmt.Errorf("failed to delete expired IP rollups: %w", err)
+		}
+	}
+	tag, err := s.DB.Exec(ctx, `DELETE FROM license_check_logs WHERE `+product+` AND created_at < $1`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired license check logs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
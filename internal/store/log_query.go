@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"clortho/internal/models"
+)
+
+// licenseCheckLogQuery is a query on license_check_logs aliased as l,
+// restricted by a LicenseCheckLogFilter. Listing and exporting logs share it
+// so they always select the same rows.
+type licenseCheckLogQuery struct {
+	from       string
+	conditions []string
+	args       []interface{}
+}
+
+func newLicenseCheckLogQuery(filter models.LicenseCheckLogFilter) *licenseCheckLogQuery {
+	q := &licenseCheckLogQuery{from: `license_check_logs l`}
+
+	if filter.ProductGroupID != "" || filter.OwnerID != "" {
+		q.from += ` JOIN products p ON l.product_id = p.id`
+	}
+	if filter.LicenseKey != "" {
+		// Checks of products that hash keys are logged under the hash
+		q.where("l.license_key = ANY($%d)", []string{filter.LicenseKey, models.HashLicenseKey(filter.LicenseKey)})
+	}
+	if filter.ProductID != "" {
+		q.where("l.product_id = $%d", filter.ProductID)
+	}
+	if filter.ProductGroupID != "" {
+		q.where("p.product_group_id = $%d", filter.ProductGroupID)
+	}
+	if filter.OwnerID != "" {
+		q.where("p.owner_id = $%d", filter.OwnerID)
+	}
+	if filter.IPAddress != "" {
+		if strings.Contains(filter.IPAddress, "/") {
+			// ip_address is text; rows that are not addresses never match
+			q.where("CASE WHEN l.ip_address ~ '^[0-9A-Fa-f.:]+$' THEN l.ip_address::inet <<= $%d::cidr ELSE false END", filter.IPAddress)
+		} else {
+			q.where("l.ip_address = $%d", filter.IPAddress)
+		}
+	}
+	if filter.UserAgent != "" {
+		q.where("l.user_agent ILIKE $%d", "%"+escapeLike(filter.UserAgent)+"%")
+	}
+	if filter.StatusCode != nil {
+		q.where("l.status_code = $%d", *filter.StatusCode)
+	}
+	if filter.Valid != nil {
+		q.where("COALESCE(l.status_code = 200 AND l.response_payload->>'valid' = 'true', false) = $%d", *filter.Valid)
+	}
+	if filter.Reason != "" {
+		q.where("COALESCE(l.response_payload->>'reason', l.response_payload->>'error') = $%d", filter.Reason)
+	}
+	if filter.ClientAppVersion != "" {
+		q.where("l.client_app_version = $%d", filter.ClientAppVersion)
+	}
+	if filter.ClientOS != "" {
+		q.where("l.client_os = $%d", filter.ClientOS)
+	}
+	if filter.ClientSDKVersion != "" {
+		q.where("l.client_sdk_version = $%d", filter.ClientSDKVersion)
+	}
+	if filter.Since != nil {
+		q.where("l.created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		q.where("l.created_at < $%d", *filter.Until)
+	}
+	return q
+}
+
+// where adds a condition whose single placeholder is written $%d.
+func (q *licenseCheckLogQuery) where(condition string, value interface{}) {
+	q.args = append(q.args, value)
+	q.conditions = append(q.conditions, fmt.Sprintf(condition, len(q.args)))
+}
+
+// page returns the query of up to limit rows after the cursor, in
+// (created_at, id) order, newest first if desc, and its arguments.
+func (q *licenseCheckLogQuery) page(after *models.LogPageCursor, desc bool, limit int) (string, []interface{}) {
+	conditions, args := slices.Clone(q.conditions), slices.Clone(q.args)
+	order, op := "ASC", ">"
+	if desc {
+		order, op = "DESC", "<"
+	}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		conditions = append(conditions, fmt.Sprintf("(l.created_at, l.id) %s ($%d, $%d)", op, len(args)-1, len(args)))
+	}
+
+	query := `SELECT ` + licenseCheckLogColumns + ` FROM ` + q.from
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(` ORDER BY l.created_at %s, l.id %s LIMIT %d`, order, order, limit)
+	return query, args
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (s *PostgresLogStore) QueryLicenseCheckLogs(ctx context.Context, filter models.LicenseCheckLogFilter, after *models.LogPageCursor, limit int) ([]models.LicenseCheckLog, *models.LogPageCursor, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	// One more row tells whether there is a next page
+	query, args := newLicenseCheckLogQuery(filter).page(after, true, limit+1)
+	logs, err := queryBatch(ctx, s, query, args, scanLicenseCheckLog)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query license check logs: %w", err)
+	}
+	if len(logs) <= limit {
+		return logs, nil, nil
+	}
+
+	logs = logs[:limit]
+	last := logs[limit-1]
+	return logs, &models.LogPageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
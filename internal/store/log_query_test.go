@@ -0,0 +1,55 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"clortho/internal/models"
+)
+
+func TestLicenseCheckLogQuery(t *testing.T) {
+	valid := false
+	q := newLicenseCheckLogQuery(models.LicenseCheckLogFilter{
+		LicenseKey: "KEY",
+		OwnerID:    "owner-1",
+		IPAddress:  "10.0.0.0/8",
+		UserAgent:  "50%_off",
+		Valid:      &valid,
+	})
+	after := &models.LogPageCursor{CreatedAt: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), ID: uuid.New()}
+
+	query, args := q.page(after, true, 11)
+	assert.Contains(t, query, "JOIN products p ON l.product_id = p.id")
+	assert.Contains(t, query, "p.owner_id = $2")
+	assert.Contains(t, query, "::inet <<= $3::cidr")
+	assert.Contains(t, query, "(l.created_at, l.id) < ($6, $7)")
+	assert.True(t, strings.HasSuffix(query, "ORDER BY l.created_at DESC, l.id DESC LIMIT 11"), query)
+	assert.Contains(t, query, "l.license_key = ANY($1)")
+	assert.Equal(t, []interface{}{[]string{"KEY", models.HashLicenseKey("KEY")}, "owner-1", "10.0.0.0/8", `%50\%\_off%`, false, after.CreatedAt, after.ID}, args)
+
+	// Paging does not change the query it was built from
+	query, args = q.page(nil, false, 1000)
+	assert.NotContains(t, query, "l.id) >")
+	assert.Len(t, args, 5)
+
+	query, args = newLicenseCheckLogQuery(models.LicenseCheckLogFilter{IPAddress: "10.0.0.1"}).page(nil, false, 10)
+	assert.NotContains(t, query, "JOIN")
+	assert.Contains(t, query, "l.ip_address = $1")
+	assert.Equal(t, []interface{}{"10.0.0.1"}, args)
+}
+
+func TestLogPageCursor(t *testing.T) {
+	cursor := models.LogPageCursor{CreatedAt: time.Date(2026, 5, 1, 8, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+	parsed, err := models.ParseLogPageCursor(cursor.String())
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, *parsed)
+
+	for _, s := range []string{"", "!!", "bm9wZQ"} {
+		_, err := models.ParseLogPageCursor(s)
+		assert.Error(t, err, s)
+	}
+}
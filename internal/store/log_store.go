@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"clortho/internal/models"
@@ -13,10 +16,15 @@ import (
 type LogStore interface {
 	CreateLicenseCheckLog(ctx context.Context, log *models.LicenseCheckLog) error
 	CreateAdminLog(ctx context.Context, log *models.AdminLog) error
-	GetLicenseCheckLogsByLicenseKey(ctx context.Context, licenseKey string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error)
-	GetLicenseCheckLogsByProductID(ctx context.Context, productID string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error)
-	GetLicenseCheckLogsByProductGroupID(ctx context.Context, productGroupID string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error)
-	ListAdminLogs(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.AdminLog, int, error)
+	CopyLicenseCheckLogs(ctx context.Context, logs []*models.LicenseCheckLog) error
+	CopyAdminLogs(ctx context.Context, logs []*models.AdminLog) error
+	// QueryLicenseCheckLogs returns up to limit license check logs matching
+	// filter after the cursor, newest first, and the cursor of the next page
+	// if there is one.
+	QueryLicenseCheckLogs(ctx context.Context, filter models.LicenseCheckLogFilter, after *models.LogPageCursor, limit int) ([]models.LicenseCheckLog, *models.LogPageCursor, error)
+	ListAdminLogs(ctx context.Context, filter models.AdminLogFilter, pagination models.PaginationParams) ([]models.AdminLog, int, error)
+	ExportLicenseCheckLogs(ctx context.Context, filter models.LicenseCheckLogFilter, fn func(*models.LicenseCheckLog) error) error
+	ExportAdminLogs(ctx context.Context, filter models.AdminLogFilter, fn func(*models.AdminLog) error) error
 }
 
 type PostgresLogStore struct {
@@ -29,8 +37,9 @@ func NewPostgresLogStore(db *pgxpool.Pool) *PostgresLogStore {
 
 func (s *PostgresLogStore) CreateLicenseCheckLog(ctx context.Context, log *models.LicenseCheckLog) error {
 	query := `
-		INSERT INTO license_check_logs (product_id, license_id, license_key, request_payload, response_payload, ip_address, user_agent, status_code)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO license_check_logs (product_id, license_id, license_key, request_payload, response_payload, ip_address, user_agent, status_code,
+			client_app_version, client_os, client_hostname_hash, client_sdk_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at`
 
 	requestPayloadJSON, err := json.Marshal(log.RequestPayload)
@@ -54,270 +63,191 @@ func (s *PostgresLogStore) CreateLicenseCheckLog(ctx context.Context, log *model
 		log.IPAddress,
 		log.UserAgent,
 		log.StatusCode,
+		log.Client.AppVersion,
+		log.Client.OS,
+		log.Client.HostnameHash,
+		log.Client.SDKVersion,
 	).Scan(&log.ID, &log.CreatedAt)
 }
 
+// CreateAdminLog appends the entry to the admin log chain.
 func (s *PostgresLogStore) CreateAdminLog(ctx context.Context, log *models.AdminLog) error {
-	query := `
-		INSERT INTO admin_logs (action, entity_type, entity_id, owner_id, details)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at`
-
-	detailsJSON, err := json.Marshal(log.Details)
-	if err != nil {
-		return fmt.Errorf("failed to marshal details: %w", err)
-	}
-
-	return s.DB.QueryRow(
-		ctx,
-		query,
-		log.Action,
-		log.EntityType,
-		log.EntityID,
-		log.OwnerID,
-		detailsJSON,
-	).Scan(&log.ID, &log.CreatedAt)
+	return s.insertAdminLogs(ctx, []*models.AdminLog{log})
 }
 
-func (s *PostgresLogStore) GetLicenseCheckLogsByLicenseKey(ctx context.Context, licenseKey string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error) {
-	query := `
-		SELECT id, product_id, license_id, license_key, request_payload, response_payload, ip_address, user_agent, status_code, created_at
-		FROM license_check_logs
-		WHERE license_key = $1`
-	countQuery := `SELECT count(*) FROM license_check_logs WHERE license_key = $1`
-
-	args := []interface{}{licenseKey}
-	if statusCode != nil {
-		query += fmt.Sprintf(" AND status_code = $%d", len(args)+1)
-		countQuery += fmt.Sprintf(" AND status_code = $%d", len(args)+1)
-		args = append(args, *statusCode)
+// adminLogColumns are the columns read by scanAdminLog.
+const adminLogColumns = `id, action, entity_type, entity_id, owner_id, details, changes, actor, actor_token_id, actor_ip, actor_user_agent, created_at, seq, prev_hash, hash`
+
+// scanAdminLog reads a row of adminLogColumns.
+func scanAdminLog(row pgx.Row) (models.AdminLog, error) {
+	var log models.AdminLog
+	var detailsJSON, changesJSON []byte
+	var actor [4]*string
+	var prevHash, hash *string
+	if err := row.Scan(
+		&log.ID,
+		&log.Action,
+		&log.EntityType,
+		&log.EntityID,
+		&log.OwnerID,
+		&detailsJSON,
+		&changesJSON,
+		&actor[0],
+		&actor[1],
+		&actor[2],
+		&actor[3],
+		&log.CreatedAt,
+		&log.Seq,
+		&prevHash,
+		&hash,
+	); err != nil {
+		return log, fmt.Errorf("failed to scan admin log: %w", err)
 	}
-
-	query += ` ORDER BY created_at DESC`
-
-	// Pagination
-	limit := pagination.Limit
-	if limit <= 0 {
-		limit = 10
-	}
-	page := pagination.Page
-	if page <= 0 {
-		page = 1
-	}
-	offset := (page - 1) * limit
-	
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
-	args = append(args, limit, offset)
-
-	var totalCount int
-	err := s.DB.QueryRow(ctx, countQuery, args[:len(args)-2]...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get total count of log entries: %w", err)
+	if prevHash != nil && hash != nil {
+		log.PrevHash, log.Hash = *prevHash, *hash
 	}
 
-	rows, err := s.DB.Query(ctx, query, args...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to query license check logs: %w", err)
+	if err := json.Unmarshal(detailsJSON, &log.Details); err != nil {
+		return log, fmt.Errorf("failed to unmarshal details: %w", err)
 	}
-	defer rows.Close()
-
-	var logs []models.LicenseCheckLog
-	for rows.Next() {
-		var log models.LicenseCheckLog
-		var requestPayloadJSON, responsePayloadJSON []byte
-		if err := rows.Scan(
-			&log.ID,
-			&log.ProductID,
-			&log.LicenseID,
-			&log.LicenseKey,
-			&requestPayloadJSON,
-			&responsePayloadJSON,
-			&log.IPAddress,
-			&log.UserAgent,
-			&log.StatusCode,
-			&log.CreatedAt,
-		); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan license check log: %w", err)
-		}
-
-		if err := json.Unmarshal(requestPayloadJSON, &log.RequestPayload); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal request payload: %w", err)
+	if changesJSON != nil {
+		if err := json.Unmarshal(changesJSON, &log.Changes); err != nil {
+			return log, fmt.Errorf("failed to unmarshal changes: %w", err)
 		}
-		if err := json.Unmarshal(responsePayloadJSON, &log.ResponsePayload); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal response payload: %w", err)
+	}
+	if actor != [4]*string{} {
+		log.Actor = &models.AdminActor{}
+		for i, dst := range []*string{&log.Actor.Subject, &log.Actor.TokenID, &log.Actor.IPAddress, &log.Actor.UserAgent} {
+			if actor[i] != nil {
+				*dst = *actor[i]
+			}
 		}
-
-		logs = append(logs, log)
 	}
-
-	return logs, totalCount, nil
+	return log, nil
 }
 
-func (s *PostgresLogStore) GetLicenseCheckLogsByProductID(ctx context.Context, productID string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error) {
-	query := `
-		SELECT id, product_id, license_id, license_key, request_payload, response_payload, ip_address, user_agent, status_code, created_at
-		FROM license_check_logs
-		WHERE product_id = $1`
-	countQuery := `SELECT count(*) FROM license_check_logs WHERE product_id = $1`
-
-	args := []interface{}{productID}
-	if statusCode != nil {
-		query += fmt.Sprintf(" AND status_code = $%d", len(args)+1)
-		countQuery += fmt.Sprintf(" AND status_code = $%d", len(args)+1)
-		args = append(args, *statusCode)
+// adminActorColumns returns the values of the actor, actor_token_id,
+// actor_ip and actor_user_agent columns, NULL when unknown.
+func adminActorColumns(actor *models.AdminActor) [4]*string {
+	var columns [4]*string
+	if actor == nil {
+		return columns
 	}
-
-	query += ` ORDER BY created_at DESC`
-
-	limit := pagination.Limit
-	if limit <= 0 {
-		limit = 10
-	}
-	page := pagination.Page
-	if page <= 0 {
-		page = 1
+	for i, v := range []string{actor.Subject, actor.TokenID, actor.IPAddress, actor.UserAgent} {
+		if v != "" {
+			columns[i] = &v
+		}
 	}
-	offset := (page - 1) * limit
-	
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
-	args = append(args, limit, offset)
+	return columns
+}
 
-	var totalCount int
-	err := s.DB.QueryRow(ctx, countQuery, args[:len(args)-2]...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get total count of log entries: %w", err)
+func marshalChanges(changes map[string]models.FieldChange) ([]byte, error) {
+	if changes == nil {
+		return nil, nil
 	}
-
-	rows, err := s.DB.Query(ctx, query, args...)
+	changesJSON, err := json.Marshal(changes)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to query license check logs: %w", err)
+		return nil, fmt.Errorf("failed to marshal changes: %w", err)
 	}
-	defer rows.Close()
+	return changesJSON, nil
+}
 
-	var logs []models.LicenseCheckLog
-	for rows.Next() {
-		var log models.LicenseCheckLog
-		var requestPayloadJSON, responsePayloadJSON []byte
-		if err := rows.Scan(
-			&log.ID,
-			&log.ProductID,
-			&log.LicenseID,
-			&log.LicenseKey,
-			&requestPayloadJSON,
-			&responsePayloadJSON,
-			&log.IPAddress,
-			&log.UserAgent,
-			&log.StatusCode,
-			&log.CreatedAt,
-		); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan license check log: %w", err)
-		}
+// CopyLicenseCheckLogs bulk inserts check logs with COPY. IDs are assigned by the database.
+func (s *PostgresLogStore) CopyLicenseCheckLogs(ctx context.Context, logs []*models.LicenseCheckLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
 
-		if err := json.Unmarshal(requestPayloadJSON, &log.RequestPayload); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal request payload: %w", err)
+	columns := []string{"product_id", "license_id", "license_key", "request_payload", "response_payload", "ip_address", "user_agent", "status_code",
+		"client_app_version", "client_os", "client_hostname_hash", "client_sdk_version", "created_at"}
+	_, err := s.DB.CopyFrom(ctx, pgx.Identifier{"license_check_logs"}, columns, pgx.CopyFromSlice(len(logs), func(i int) ([]any, error) {
+		log := logs[i]
+		requestPayloadJSON, err := json.Marshal(log.RequestPayload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request payload: %w", err)
 		}
-		if err := json.Unmarshal(responsePayloadJSON, &log.ResponsePayload); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal response payload: %w", err)
+		responsePayloadJSON, err := json.Marshal(log.ResponsePayload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal response payload: %w", err)
 		}
-
-		logs = append(logs, log)
+		return []any{
+			log.ProductID,
+			log.LicenseID,
+			log.LicenseKey,
+			requestPayloadJSON,
+			responsePayloadJSON,
+			log.IPAddress,
+			log.UserAgent,
+			log.StatusCode,
+			log.Client.AppVersion,
+			log.Client.OS,
+			log.Client.HostnameHash,
+			log.Client.SDKVersion,
+			createdAtOrNow(log.CreatedAt),
+		}, nil
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to copy license check logs: %w", err)
 	}
-
-	return logs, totalCount, nil
+	return nil
 }
 
-func (s *PostgresLogStore) GetLicenseCheckLogsByProductGroupID(ctx context.Context, productGroupID string, statusCode *int, pagination models.PaginationParams) ([]models.LicenseCheckLog, int, error) {
-	query := `
-		SELECT l.id, l.product_id, l.license_id, l.license_key, l.request_payload, l.response_payload, l.ip_address, l.user_agent, l.status_code, l.created_at
-		FROM license_check_logs l
-		JOIN products p ON l.product_id = p.id
-		WHERE p.product_group_id = $1`
-	countQuery := `
-		SELECT count(*)
-		FROM license_check_logs l
-		JOIN products p ON l.product_id = p.id
-		WHERE p.product_group_id = $1`
-
-	args := []interface{}{productGroupID}
-	if statusCode != nil {
-		query += fmt.Sprintf(" AND l.status_code = $%d", len(args)+1)
-		countQuery += fmt.Sprintf(" AND l.status_code = $%d", len(args)+1)
-		args = append(args, *statusCode)
+// CopyAdminLogs appends the entries to the admin log chain in order, with COPY.
+func (s *PostgresLogStore) CopyAdminLogs(ctx context.Context, logs []*models.AdminLog) error {
+	if len(logs) == 0 {
+		return nil
 	}
+	return s.insertAdminLogs(ctx, logs)
+}
 
-	query += ` ORDER BY l.created_at DESC`
+func createdAtOrNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
 
-	// Pagination
-	limit := pagination.Limit
-	if limit <= 0 {
-		limit = 10
+// adminLogFilterConditions returns the conditions of filter on the columns of
+// admin_logs and their arguments.
+func adminLogFilterConditions(filter models.AdminLogFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
 	}
-	page := pagination.Page
-	if page <= 0 {
-		page = 1
+	if filter.OwnerID != nil {
+		add("owner_id = $%d", *filter.OwnerID)
 	}
-	offset := (page - 1) * limit
-	
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
-	args = append(args, limit, offset)
-
-	var totalCount int
-	err := s.DB.QueryRow(ctx, countQuery, args[:len(args)-2]...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get total count of log entries: %w", err)
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
 	}
-
-	rows, err := s.DB.Query(ctx, query, args...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to query license check logs: %w", err)
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
 	}
-	defer rows.Close()
-
-	var logs []models.LicenseCheckLog
-	for rows.Next() {
-		var log models.LicenseCheckLog
-		var requestPayloadJSON, responsePayloadJSON []byte
-		if err := rows.Scan(
-			&log.ID,
-			&log.ProductID,
-			&log.LicenseID,
-			&log.LicenseKey,
-			&requestPayloadJSON,
-			&responsePayloadJSON,
-			&log.IPAddress,
-			&log.UserAgent,
-			&log.StatusCode,
-			&log.CreatedAt,
-		); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan license check log: %w", err)
-		}
-
-		if err := json.Unmarshal(requestPayloadJSON, &log.RequestPayload); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal request payload: %w", err)
-		}
-		if err := json.Unmarshal(responsePayloadJSON, &log.ResponsePayload); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal response payload: %w", err)
-		}
-
-		logs = append(logs, log)
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
 	}
-
-	return logs, totalCount, nil
+	if filter.EntityID != nil {
+		add("entity_id = $%d", *filter.EntityID)
+	}
+	if filter.Since != nil {
+		add("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		add("created_at < $%d", *filter.Until)
+	}
+	return conditions, args
 }
 
-func (s *PostgresLogStore) ListAdminLogs(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.AdminLog, int, error) {
-	query := `
-		SELECT id, action, entity_type, entity_id, owner_id, details, created_at
-		FROM admin_logs
-	`
-	countQuery := `SELECT count(*) FROM admin_logs`
-	var args []interface{}
-	if ownerID != nil {
-		query += ` WHERE owner_id = $1`
-		countQuery += ` WHERE owner_id = $1`
-		args = append(args, ownerID)
+func (s *PostgresLogStore) ListAdminLogs(ctx context.Context, filter models.AdminLogFilter, pagination models.PaginationParams) ([]models.AdminLog, int, error) {
+	conditions, args := adminLogFilterConditions(filter)
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
-	query += ` ORDER BY created_at DESC`
+	query := `SELECT ` + adminLogColumns + ` FROM admin_logs` + where + ` ORDER BY created_at DESC`
+	countQuery := `SELECT count(*) FROM admin_logs` + where
 
 	limit := pagination.Limit
 	if limit <= 0 {
@@ -328,7 +258,7 @@ func (s *PostgresLogStore) ListAdminLogs(ctx context.Context, ownerID *string, p
 		page = 1
 	}
 	offset := (page - 1) * limit
-	
+
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
 	args = append(args, limit, offset)
 
@@ -346,26 +276,15 @@ func (s *PostgresLogStore) ListAdminLogs(ctx context.Context, ownerID *string, p
 
 	var logs []models.AdminLog
 	for rows.Next() {
-		var log models.AdminLog
-		var detailsJSON []byte
-		if err := rows.Scan(
-			&log.ID,
-			&log.Action,
-			&log.EntityType,
-			&log.EntityID,
-			&log.OwnerID,
-			&detailsJSON,
-			&log.CreatedAt,
-		); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan admin log: %w", err)
+		log, err := scanAdminLog(rows)
+		if err != nil {
+			return nil, 0, err
 		}
-
-		if err := json.Unmarshal(detailsJSON, &log.Details); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal details: %w", err)
-		}
-
 		logs = append(logs, log)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating admin logs: %w", err)
+	}
 
 	return logs, totalCount, nil
 }
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clortho/internal/models"
+)
+
+// Partitioned log tables.
+const (
+	LicenseCheckLogsTable = "license_check_logs"
+	AdminLogsTable        = "admin_logs"
+)
+
+// LogPartitionStore manages the monthly partitions of the log tables. A
+// partition holding month M of table T is named T_pYYYYMM.
+type LogPartitionStore interface {
+	ListLogPartitions(ctx context.Context, table string) ([]models.LogPartition, error)
+	// CreateLogPartition creates the partition holding the month of t unless
+	// it exists.
+	CreateLogPartition(ctx context.Context, table string, t time.Time) error
+	// ExportLogPartition writes every row of a partition as one JSON object
+	// per line.
+	ExportLogPartition(ctx context.Context, partition models.LogPartition, w io.Writer) error
+	DropLogPartition(ctx context.Context, partition models.LogPartition) error
+}
+
+type PostgresLogPartitionStore struct {
+	DB *pgxpool.Pool
+}
+
+func NewPostgresLogPartitionStore(db *pgxpool.Pool) *PostgresLogPartitionStore {
+	return &PostgresLogPartitionStore{DB: db}
+}
+
+// LogPartitionFor returns the partition of table holding t.
+func LogPartitionFor(table string, t time.Time) models.LogPartition {
+	t = t.UTC()
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return models.LogPartition{
+		Table: table,
+		Name:  fmt.Sprintf("%s_p%s", table, from.Format("200601")),
+		From:  from,
+		To:    from.AddDate(0, 1, 0),
+	}
+}
+
+// parseLogPartition returns the partition named name, or false if the name
+// does not follow the naming scheme.
+func parseLogPartition(table, name string) (models.LogPartition, bool) {
+	prefix := table + "_p"
+	if len(name) != len(prefix)+6 || name[:len(prefix)] != prefix {
+		return models.LogPartition{}, false
+	}
+	month, err := time.Parse("200601", name[len(prefix):])
+	if err != nil {
+		return models.LogPartition{}, false
+	}
+	return LogPartitionFor(table, month), true
+}
+
+func (s *PostgresLogPartitionStore) ListLogPartitions(ctx context.Context, table string) ([]models.LogPartition, error) {
+	query := `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass
+		ORDER BY c.relname
+	`
+	rows, err := s.DB.Query(ctx, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var partitions []models.LogPartition
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		// Partitions created by hand under other names are left alone
+		if p, ok := parseLogPartition(table, name); ok {
+			partitions = append(partitions, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partitions: %w", err)
+	}
+
+	return partitions, nil
+}
+
+func (s *PostgresLogPartitionStore) CreateLogPartition(ctx context.Context, table string, t time.Time) error {
+	p := LogPartitionFor(table, t)
+	query := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+		pgx.Identifier{p.Name}.Sanitize(), pgx.Identifier{table}.Sanitize(),
+		p.From.Format(time.RFC3339), p.To.Format(time.RFC3339),
+	)
+	if _, err := s.DB.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+func (s *PostgresLogPartitionStore) ExportLogPartition(ctx context.Context, partition models.LogPartition, w io.Writer) error {
+	rows, err := s.DB.Query(ctx, `SELECT row_to_json(t)::text FROM `+pgx.Identifier{partition.Name}.Sanitize()+` t ORDER BY created_at`)
+	if err != nil {
+		return fmt.Errorf("failed to read partition %s: %w", partition.Name, err)
+	}
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	for rows.Next() {
+		var line []byte
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("failed to scan row of %s: %w", partition.Name, err)
+		}
+		if _, err := bw.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write row of %s: %w", partition.Name, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading partition %s: %w", partition.Name, err)
+	}
+	return bw.Flush()
+}
+
+func (s *PostgresLogPartitionStore) DropLogPartition(ctx context.Context, partition models.LogPartition) error {
+	if _, err := s.DB.Exec(ctx, `DROP TABLE IF EXISTS `+pgx.Identifier{partition.Name}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", partition.Name, err)
+	}
+	return nil
+}
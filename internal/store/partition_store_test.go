@@ -0,0 +1,26 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogPartitionFor(t *testing.T) {
+	p := LogPartitionFor(AdminLogsTable, time.Date(2026, 12, 31, 23, 0, 0, 0, time.FixedZone("UTC-5", -5*3600)))
+	assert.Equal(t, "admin_logs_p202701", p.Name)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), p.From)
+	assert.Equal(t, time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC), p.To)
+}
+
+func TestParseLogPartition(t *testing.T) {
+	p, ok := parseLogPartition(LicenseCheckLogsTable, "license_check_logs_p202603")
+	assert.True(t, ok)
+	assert.Equal(t, LogPartitionFor(LicenseCheckLogsTable, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)), p)
+
+	for _, name := range []string{"license_check_logs_default", "license_check_logs_p2026031", "admin_logs_p202603", "license_check_logs_p202613"} {
+		_, ok := parseLogPartition(LicenseCheckLogsTable, name)
+		assert.False(t, ok, name)
+	}
+}
@@ -0,0 +1,329 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"clortho/internal/models"
+)
+
+type PlanStore interface {
+	// ListPlans lists the plans of a product or a product group, or all of
+	// them when both are nil.
+	ListPlans(ctx context.Context, productID, productGroupID, ownerID *string, pagination models.PaginationParams) ([]models.Plan, int, error)
+	GetPlan(ctx context.Context, id string) (*models.Plan, error)
+	// CreatePlan fails with an *UnknownCatalogError if a product of the
+	// plan cannot link one of its features or releases.
+	CreatePlan(ctx context.Context, plan *models.Plan) error
+	// UpdatePlan saves plan and, in the same transaction, re-links the
+	// features and releases of every license on it and applies its seat
+	// limit. It returns the number of licenses updated. Like CreatePlan, it
+	// fails with an *UnknownCatalogError, and changes nothing, if a product
+	// of the plan or a license on it cannot link a feature or release.
+	UpdatePlan(ctx context.Context, plan *models.Plan) (int64, error)
+	DeletePlan(ctx context.Context, id string) error
+}
+
+type PostgresPlanStore struct {
+	DB *pgxpool.Pool
+}
+
+func NewPostgresPlanStore(db *pgxpool.Pool) *PostgresPlanStore {
+	return &PostgresPlanStore{DB: db}
+}
+
+const planColumns = `id, owner_id, product_id, product_group_id, name, code, COALESCE(description, ''), features, releases, COALESCE(license_type, ''), COALESCE(license_duration, ''), seat_limit, created_at, updated_at`
+
+func scanPlan(row pgx.Row) (*models.Plan, error) {
+	var p models.Plan
+	err := row.Scan(&p.ID, &p.OwnerID, &p.ProductID, &p.ProductGroupID, &p.Name, &p.Code, &p.Description, &p.Features, &p.Releases, &p.LicenseType, &p.LicenseDuration, &p.SeatLimit, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *PostgresPlanStore) ListPlans(ctx context.Context, productID, productGroupID, ownerID *string, pagination models.PaginationParams) ([]models.Plan, int, error) {
+	where := ` WHERE true`
+	var args []interface{}
+	if productID != nil {
+		args = append(args, *productID)
+		where += fmt.Sprintf(` AND product_id = $%d`, len(args))
+	}
+	if productGroupID != nil {
+		args = append(args, *productGroupID)
+		where += fmt.Sprintf(` AND product_group_id = $%d`, len(args))
+	}
+	if ownerID != nil {
+		args = append(args, *ownerID)
+		where += fmt.Sprintf(` AND owner_id = $%d`, len(args))
+	}
+
+	var totalCount int
+	if err := s.DB.QueryRow(ctx, `SELECT count(*) FROM plans`+where, args...).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to get total count of plans: %w", err)
+	}
+
+	limit := pagination.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	page := pagination.Page
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := `SELECT ` + planColumns + ` FROM plans` + where + ` ORDER BY name ASC`
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := s.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []models.Plan
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan plan: %w", err)
+		}
+		plans = append(plans, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %w", err)
+	}
+
+	return plans, totalCount, nil
+}
+
+func (s *PostgresPlanStore) GetPlan(ctx context.Context, id string) (*models.Plan, error) {
+	p, err := scanPlan(s.DB.QueryRow(ctx, `SELECT `+planColumns+` FROM plans WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: plan", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get plan: %w", err)
+	}
+	return p, nil
+}
+
+func (s *PostgresPlanStore) CreatePlan(ctx context.Context, plan *models.Plan) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkPlanCatalog(ctx, tx, plan); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO plans (id, owner_id, product_id, product_group_id, name, code, description, features, releases, license_type, license_duration, seat_limit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12, $13, $14)
+	`
+	_, err = tx.Exec(ctx, query, plan.ID, plan.OwnerID, plan.ProductID, plan.ProductGroupID, plan.Name, plan.Code, plan.Description, featureValuesOrEmpty(plan.Features), stringsOrEmpty(plan.Releases), plan.LicenseType, plan.LicenseDuration, plan.SeatLimit, plan.CreatedAt, plan.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: plan code %q", ErrDuplicate, plan.Code)
+		}
+		return fmt.Errorf("failed to create plan: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresPlanStore) UpdatePlan(ctx context.Context, plan *models.Plan) (int64, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE plans
+		SET name = $1, code = $2, description = $3, features = $4, releases = $5, license_type = NULLIF($6, ''), license_duration = NULLIF($7, ''), seat_limit = $8, updated_at = $9
+		WHERE id = $10
+	`
+	tag, err := tx.Exec(ctx, query, plan.Name, plan.Code, plan.Description, featureValuesOrEmpty(plan.Features), stringsOrEmpty(plan.Releases), plan.LicenseType, plan.LicenseDuration, plan.SeatLimit, plan.UpdatedAt, plan.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: plan code %q", ErrDuplicate, plan.Code)
+		}
+		return 0, fmt.Errorf("failed to update plan: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, fmt.Errorf("%w: plan", ErrNotFound)
+	}
+
+	if err := checkPlanCatalog(ctx, tx, plan); err != nil {
+		return 0, err
+	}
+
+	// Re-link features
+	if _, err := tx.Exec(ctx, `DELETE FROM license_features WHERE license_id IN (SELECT id FROM licenses WHERE plan_id = $1)`, plan.ID); err != nil {
+		return 0, fmt.Errorf("failed to clear features: %w", err)
+	}
+	fQuery := `
+		INSERT INTO license_features (license_id, feature_id, value)
+		SELECT DISTINCT ON (l.id, f.code) l.id, f.id, $3::jsonb -> f.code
+		FROM licenses l
+		JOIN products p ON p.id = l.product_id
+		JOIN features f ON (f.product_id = l.product_id
+			OR (f.product_group_id = p.product_group_id AND f.product_group_id IS NOT NULL)
+			OR (f.product_id IS NULL AND f.product_group_id IS NULL))
+		WHERE l.plan_id = $1 AND f.code = ANY($2)
+		ORDER BY l.id, f.code, f.product_id IS NULL, f.product_group_id IS NULL
+	`
+	if _, err := tx.Exec(ctx, fQuery, plan.ID, plan.FeatureCodes(), featureValuesOrEmpty(plan.Features)); err != nil {
+		return 0, fmt.Errorf("failed to link features: %w", err)
+	}
+
+	// Re-link releases
+	if _, err := tx.Exec(ctx, `DELETE FROM license_releases WHERE license_id IN (SELECT id FROM licenses WHERE plan_id = $1)`, plan.ID); err != nil {
+		return 0, fmt.Errorf("failed to clear releases: %w", err)
+	}
+	rQuery := `
+		INSERT INTO license_releases (license_id, release_id)
+		SELECT DISTINCT ON (l.id, r.version) l.id, r.id
+		FROM licenses l
+		JOIN products p ON p.id = l.product_id
+		JOIN releases r ON (r.product_id = l.product_id
+			OR (r.product_group_id = p.product_group_id AND r.product_group_id IS NOT NULL)
+			OR (r.product_id IS NULL AND r.product_group_id IS NULL))
+		WHERE l.plan_id = $1 AND r.version = ANY($2)
+		ORDER BY l.id, r.version, r.product_id IS NULL, r.product_group_id IS NULL
+	`
+	if _, err := tx.Exec(ctx, rQuery, plan.ID, stringsOrEmpty(plan.Releases)); err != nil {
+		return 0, fmt.Errorf("failed to link releases: %w", err)
+	}
+
+	// Licenses may have moved to products the plan no longer suits
+	unlinkedFeatures, err := queryStrings(ctx, tx, `
+		SELECT DISTINCT c FROM licenses l CROSS JOIN unnest($2::text[]) AS c
+		WHERE l.plan_id = $1 AND NOT EXISTS (
+			SELECT 1 FROM license_features lf JOIN features f ON f.id = lf.feature_id
+			WHERE lf.license_id = l.id AND f.code = c)
+		ORDER BY c
+	`, plan.ID, plan.FeatureCodes())
+	if err != nil {
+		return 0, fmt.Errorf("failed to check linked features: %w", err)
+	}
+	unlinkedReleases, err := queryStrings(ctx, tx, `
+		SELECT DISTINCT v FROM licenses l CROSS JOIN unnest($2::text[]) AS v
+		WHERE l.plan_id = $1 AND NOT EXISTS (
+			SELECT 1 FROM license_releases lr JOIN releases r ON r.id = lr.release_id
+			WHERE lr.license_id = l.id AND r.version = v)
+		ORDER BY v
+	`, plan.ID, stringsOrEmpty(plan.Releases))
+	if err != nil {
+		return 0, fmt.Errorf("failed to check linked releases: %w", err)
+	}
+	if len(unlinkedFeatures) > 0 || len(unlinkedReleases) > 0 {
+		return 0, &UnknownCatalogError{FeatureCodes: unlinkedFeatures, ReleaseVersions: unlinkedReleases}
+	}
+
+	// The seat limit of the plan replaces the limit of its licenses, even
+	// with 0; whether they auto-allow IPs at all stays theirs to set
+	lQuery := `
+		UPDATE licenses SET
+			auto_allowed_ip_limit = $2,
+			updated_at = $3
+		WHERE plan_id = $1
+	`
+	tag, err = tx.Exec(ctx, lQuery, plan.ID, plan.SeatLimit, plan.UpdatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update licenses: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s *PostgresPlanStore) DeletePlan(ctx context.Context, id string) error {
+	tag, err := s.DB.Exec(ctx, `DELETE FROM plans WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete plan: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: plan", ErrNotFound)
+	}
+	return nil
+}
+
+// checkPlanCatalog fails with an *UnknownCatalogError if a feature or
+// release of plan cannot be linked by every product it covers. An entry of
+// the group or a global one does for all of them; otherwise each product
+// needs its own. A group without products needs a group or global entry.
+func checkPlanCatalog(ctx context.Context, tx pgx.Tx, plan *models.Plan) error {
+	features, err := unknownPlanCatalog(ctx, tx, "features", "code", plan, plan.FeatureCodes())
+	if err != nil {
+		return fmt.Errorf("failed to check plan features: %w", err)
+	}
+	releases, err := unknownPlanCatalog(ctx, tx, "releases", "version", plan, plan.Releases)
+	if err != nil {
+		return fmt.Errorf("failed to check plan releases: %w", err)
+	}
+	if len(features) > 0 || len(releases) > 0 {
+		return &UnknownCatalogError{FeatureCodes: features, ReleaseVersions: releases}
+	}
+	return nil
+}
+
+// unknownPlanCatalog returns the values of column in table, features or
+// releases, that some product covered by plan cannot link.
+func unknownPlanCatalog(ctx context.Context, tx pgx.Tx, table, column string, plan *models.Plan, values []string) ([]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	query := `
+		SELECT DISTINCT v FROM unnest($3::text[]) AS v
+		WHERE NOT EXISTS (
+			SELECT 1 FROM ` + table + ` e
+			WHERE e.` + column + ` = v
+			AND (e.product_group_id = $2 OR (e.product_id IS NULL AND e.product_group_id IS NULL))
+		)
+		AND (
+			NOT EXISTS (SELECT 1 FROM products p WHERE p.id = $1 OR p.product_group_id = $2)
+			OR EXISTS (
+				SELECT 1 FROM products p
+				WHERE (p.id = $1 OR p.product_group_id = $2)
+				AND NOT EXISTS (
+					SELECT 1 FROM ` + table + ` e
+					WHERE e.` + column + ` = v
+					AND (e.product_id = p.id OR (e.product_group_id = p.product_group_id AND e.product_group_id IS NOT NULL))
+				)
+			)
+		)
+		ORDER BY v
+	`
+	return queryStrings(ctx, tx, query, plan.ProductID, plan.ProductGroupID, values)
+}
+
+// featureValuesOrEmpty keeps NOT NULL JSONB columns an empty object rather
+// than NULL.
+func featureValuesOrEmpty(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return map[string]interface{}{}
+	}
+	return values
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
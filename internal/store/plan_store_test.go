@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"clortho/internal/models"
+)
+
+func TestPlanStore_UpdatePlanPropagates(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t, "clortho_test_plans")
+
+	groupStore := NewPostgresProductGroupStore(pool)
+	productStore := NewPostgresProductStore(pool)
+	featureStore := NewPostgresFeatureStore(pool)
+	releaseStore := NewPostgresReleaseStore(pool)
+	licenseStore := NewPostgresLicenseStore(pool)
+	planStore := NewPostgresPlanStore(pool)
+
+	group := &models.ProductGroup{ID: uuid.New(), Name: "Suite", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, groupStore.CreateProductGroup(ctx, group))
+	for _, code := range []string{"sso", "audit", "api_calls"} {
+		require.NoError(t, featureStore.CreateFeature(ctx, &models.Feature{ID: uuid.New(), ProductGroupID: &group.ID, Name: code, Code: code, CreatedAt: time.Now()}))
+	}
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		require.NoError(t, releaseStore.CreateRelease(ctx, &models.Release{ID: uuid.New(), ProductGroupID: &group.ID, Version: version, CreatedAt: time.Now()}))
+	}
+
+	plan := &models.Plan{
+		ID:             uuid.New(),
+		ProductGroupID: &group.ID,
+		Name:           "Pro",
+		Code:           "pro",
+		Features:       map[string]interface{}{"sso": true, "api_calls": 1000.0},
+		Releases:       []string{"1.0.0"},
+		SeatLimit:      3,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	require.NoError(t, planStore.CreatePlan(ctx, plan))
+
+	// One license on each product of the group, only the first auto-allowing
+	// IPs
+	var keys []string
+	for i, name := range []string{"Editor", "Viewer"} {
+		product := &models.Product{ID: uuid.New(), Name: name, ProductGroupID: &group.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		require.NoError(t, productStore.CreateProduct(ctx, product))
+		license := &models.License{
+			ID: uuid.New(), Key: "PLAN-KEY-" + name, ProductID: product.ID, Type: models.LicenseTypePerpetual, Status: models.LicenseStatusActive,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+		plan.ApplyTo(license)
+		license.AutoAllowedIP = i == 0
+		require.NoError(t, licenseStore.CreateLicense(ctx, license))
+		keys = append(keys, license.Key)
+	}
+
+	t.Run("FeaturesReleasesAndSeatLimit", func(t *testing.T) {
+		plan.Features = map[string]interface{}{"sso": true, "audit": true, "api_calls": 5000.0}
+		plan.Releases = []string{"1.0.0", "2.0.0"}
+		plan.SeatLimit = 10
+		plan.UpdatedAt = time.Now()
+		updated, err := planStore.UpdatePlan(ctx, plan)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), updated)
+
+		for i, key := range keys {
+			l, err := licenseStore.GetLicenseByKey(ctx, key)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"api_calls", "audit", "sso"}, l.Features, key)
+			assert.Equal(t, 5000.0, l.FeatureValues["api_calls"], key)
+			assert.ElementsMatch(t, []string{"1.0.0", "2.0.0"}, l.Releases, key)
+			assert.Equal(t, 10, l.AutoAllowedIPLimit, key)
+			assert.Equal(t, i == 0, l.AutoAllowedIP, key)
+		}
+	})
+
+	t.Run("SeatLimitLoweredToZero", func(t *testing.T) {
+		plan.SeatLimit = 0
+		plan.UpdatedAt = time.Now()
+		_, err := planStore.UpdatePlan(ctx, plan)
+		require.NoError(t, err)
+
+		for i, key := range keys {
+			l, err := licenseStore.GetLicenseByKey(ctx, key)
+			require.NoError(t, err)
+			assert.Equal(t, 0, l.AutoAllowedIPLimit, key)
+			assert.Equal(t, i == 0, l.AutoAllowedIP, key)
+		}
+	})
+}
+
+func TestPlanStore_CatalogChecks(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPool(t, "clortho_test_plan_catalog")
+
+	groupStore := NewPostgresProductGroupStore(pool)
+	productStore := NewPostgresProductStore(pool)
+	featureStore := NewPostgresFeatureStore(pool)
+	licenseStore := NewPostgresLicenseStore(pool)
+	planStore := NewPostgresPlanStore(pool)
+
+	group := &models.ProductGroup{ID: uuid.New(), Name: "Suite", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, groupStore.CreateProductGroup(ctx, group))
+	editor := &models.Product{ID: uuid.New(), Name: "Editor", ProductGroupID: &group.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	viewer := &models.Product{ID: uuid.New(), Name: "Viewer", ProductGroupID: &group.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	for _, p := range []*models.Product{editor, viewer} {
+		require.NoError(t, productStore.CreateProduct(ctx, p))
+	}
+	require.NoError(t, featureStore.CreateFeature(ctx, &models.Feature{ID: uuid.New(), ProductGroupID: &group.ID, Name: "SSO", Code: "sso", CreatedAt: time.Now()}))
+	require.NoError(t, featureStore.CreateFeature(ctx, &models.Feature{ID: uuid.New(), Name: "Export", Code: "export", CreatedAt: time.Now()}))
+	require.NoError(t, featureStore.CreateFeature(ctx, &models.Feature{ID: uuid.New(), ProductID: &editor.ID, Name: "Macros", Code: "macros", CreatedAt: time.Now()}))
+
+	newPlan := func(code string, features ...string) *models.Plan {
+		plan := &models.Plan{ID: uuid.New(), ProductGroupID: &group.ID, Name: code, Code: code, Features: map[string]interface{}{}, SeatLimit: 3, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		for _, f := range features {
+			plan.Features[f] = true
+		}
+		return plan
+	}
+
+	t.Run("FeatureOfOneProductOnly", func(t *testing.T) {
+		err := planStore.CreatePlan(ctx, newPlan("macros", "sso", "macros"))
+
+		var unknown *UnknownCatalogError
+		require.True(t, errors.As(err, &unknown), "expected an UnknownCatalogError, got %v", err)
+		assert.Equal(t, []string{"macros"}, unknown.FeatureCodes)
+	})
+
+	t.Run("UnlinkableLicenseRollsBack", func(t *testing.T) {
+		plan := newPlan("pro", "sso", "export")
+		require.NoError(t, planStore.CreatePlan(ctx, plan))
+		license := &models.License{
+			ID: uuid.New(), Key: "PLAN-VIEWER", ProductID: viewer.ID, Type: models.LicenseTypePerpetual, Status: models.LicenseStatusActive,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+		plan.ApplyTo(license)
+		require.NoError(t, licenseStore.CreateLicense(ctx, license))
+
+		// Out of the group, the license can no longer link the group's "sso"
+		viewer.ProductGroupID = nil
+		viewer.UpdatedAt = time.Now()
+		require.NoError(t, productStore.UpdateProduct(ctx, viewer))
+
+		plan.SeatLimit = 10
+		plan.UpdatedAt = time.Now()
+		_, err := planStore.UpdatePlan(ctx, plan)
+		var unknown *UnknownCatalogError
+		require.True(t, errors.As(err, &unknown), "expected an UnknownCatalogError, got %v", err)
+		assert.Equal(t, []string{"sso"}, unknown.FeatureCodes)
+
+		stored, err := planStore.GetPlan(ctx, plan.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, 3, stored.SeatLimit)
+		l, err := licenseStore.GetLicenseByKey(ctx, license.Key)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"export", "sso"}, l.Features)
+		assert.Equal(t, 3, l.AutoAllowedIPLimit)
+	})
+}
@@ -28,7 +28,7 @@ func NewPostgresProductStore(db *pgxpool.Pool) *PostgresProductStore {
 
 func (s *PostgresProductStore) ListProducts(ctx context.Context, ownerID *string, pagination models.PaginationParams) ([]models.Product, int, error) {
 	query := `
-		SELECT id, owner_id, name, COALESCE(description, ''), COALESCE(license_prefix, ''), COALESCE(license_separator, '-'), COALESCE(license_charset, ''), COALESCE(license_length, 0), COALESCE(license_type, ''), COALESCE(license_duration, ''), auto_allowed_ip, auto_allowed_ip_limit, product_group_id, created_at, updated_at
+		SELECT id, owner_id, name, COALESCE(description, ''), COALESCE(license_prefix, ''), COALESCE(license_separator, ''), COALESCE(license_charset, ''), COALESCE(license_length, 0), COALESCE(license_type, ''), COALESCE(license_duration, ''), auto_allowed_ip, auto_allowed_ip_limit, product_group_id, rate_limits, anomaly_thresholds, log_privacy, default_features, default_releases, created_at, updated_at
 		FROM products
 	`
 	countQuery := `SELECT count(*) FROM products`
@@ -69,7 +69,7 @@ func (s *PostgresProductStore) ListProducts(ctx context.Context, ownerID *string
 	var products []models.Product
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.ID, &p.OwnerID, &p.Name, &p.Description, &p.LicensePrefix, &p.LicenseSeparator, &p.LicenseCharset, &p.LicenseLength, &p.LicenseType, &p.LicenseDuration, &p.AutoAllowedIP, &p.AutoAllowedIPLimit, &p.ProductGroupID, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.OwnerID, &p.Name, &p.Description, &p.LicensePrefix, &p.LicenseSeparator, &p.LicenseCharset, &p.LicenseLength, &p.LicenseType, &p.LicenseDuration, &p.AutoAllowedIP, &p.AutoAllowedIPLimit, &p.ProductGroupID, &p.RateLimits, &p.AnomalyThresholds, &p.LogPrivacy, &p.DefaultFeatures, &p.DefaultReleases, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan product: %w", err)
 		}
 		products = append(products, p)
@@ -84,10 +84,10 @@ func (s *PostgresProductStore) ListProducts(ctx context.Context, ownerID *string
 
 func (s *PostgresProductStore) CreateProduct(ctx context.Context, product *models.Product) error {
 	query := `
-		INSERT INTO products (id, owner_id, name, description, license_prefix, license_separator, license_charset, license_length, license_type, license_duration, auto_allowed_ip, auto_allowed_ip_limit, product_group_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		INSERT INTO products (id, owner_id, name, description, license_prefix, license_separator, license_charset, license_length, license_type, license_duration, auto_allowed_ip, auto_allowed_ip_limit, product_group_id, rate_limits, anomaly_thresholds, log_privacy, default_features, default_releases, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 	`
-	_, err := s.DB.Exec(ctx, query, product.ID, product.OwnerID, product.Name, product.Description, product.LicensePrefix, product.LicenseSeparator, product.LicenseCharset, product.LicenseLength, product.LicenseType, product.LicenseDuration, product.AutoAllowedIP, product.AutoAllowedIPLimit, product.ProductGroupID, product.CreatedAt, product.UpdatedAt)
+	_, err := s.DB.Exec(ctx, query, product.ID, product.OwnerID, product.Name, product.Description, product.LicensePrefix, product.LicenseSeparator, product.LicenseCharset, product.LicenseLength, product.LicenseType, product.LicenseDuration, product.AutoAllowedIP, product.AutoAllowedIPLimit, product.ProductGroupID, rateLimitsOrEmpty(product.RateLimits), product.AnomalyThresholds, product.LogPrivacy, stringsOrEmpty(product.DefaultFeatures), stringsOrEmpty(product.DefaultReleases), product.CreatedAt, product.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create product: %w", err)
 	}
@@ -96,12 +96,12 @@ func (s *PostgresProductStore) CreateProduct(ctx context.Context, product *model
 
 func (s *PostgresProductStore) GetProduct(ctx context.Context, id string) (*models.Product, error) {
 	query := `
-		SELECT id, owner_id, name, COALESCE(description, ''), COALESCE(license_prefix, ''), COALESCE(license_separator, '-'), COALESCE(license_charset, ''), COALESCE(license_length, 0), COALESCE(license_type, ''), COALESCE(license_duration, ''), auto_allowed_ip, auto_allowed_ip_limit, product_group_id, created_at, updated_at
+		SELECT id, owner_id, name, COALESCE(description, ''), COALESCE(license_prefix, ''), COALESCE(license_separator, ''), COALESCE(license_charset, ''), COALESCE(license_length, 0), COALESCE(license_type, ''), COALESCE(license_duration, ''), auto_allowed_ip, auto_allowed_ip_limit, product_group_id, rate_limits, anomaly_thresholds, log_privacy, default_features, default_releases, created_at, updated_at
 		FROM products
 		WHERE id = $1
 	`
 	var p models.Product
-	err := s.DB.QueryRow(ctx, query, id).Scan(&p.ID, &p.OwnerID, &p.Name, &p.Description, &p.LicensePrefix, &p.LicenseSeparator, &p.LicenseCharset, &p.LicenseLength, &p.LicenseType, &p.LicenseDuration, &p.AutoAllowedIP, &p.AutoAllowedIPLimit, &p.ProductGroupID, &p.CreatedAt, &p.UpdatedAt)
+	err := s.DB.QueryRow(ctx, query, id).Scan(&p.ID, &p.OwnerID, &p.Name, &p.Description, &p.LicensePrefix, &p.LicenseSeparator, &p.LicenseCharset, &p.LicenseLength, &p.LicenseType, &p.LicenseDuration, &p.AutoAllowedIP, &p.AutoAllowedIPLimit, &p.ProductGroupID, &p.RateLimits, &p.AnomalyThresholds, &p.LogPrivacy, &p.DefaultFeatures, &p.DefaultReleases, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
@@ -111,11 +111,11 @@ func (s *PostgresProductStore) GetProduct(ctx context.Context, id string) (*mode
 func (s *PostgresProductStore) UpdateProduct(ctx context.Context, product *models.Product) error {
 	query := `
 		UPDATE products
-		SET name = $1, description = $2, license_prefix = $3, license_separator = $4, license_charset = $5, license_length = $6, license_type = $7, license_duration = $8, auto_allowed_ip = $9, auto_allowed_ip_limit = $10, product_group_id = $11, updated_at = $12
-		WHERE id = $13
+		SET name = $1, description = $2, license_prefix = $3, license_separator = NULLIF($4, ''), license_charset = $5, license_length = $6, license_type = $7, license_duration = $8, auto_allowed_ip = $9, auto_allowed_ip_limit = $10, product_group_id = $11, rate_limits = $12, anomaly_thresholds = $13, log_privacy = $14, default_features = $15, default_releases = $16, updated_at = $17
+		WHERE id = $18
 	`
 	
-	tag, err := s.DB.Exec(ctx, query, product.Name, product.Description, product.LicensePrefix, product.LicenseSeparator, product.LicenseCharset, product.LicenseLength, product.LicenseType, product.LicenseDuration, product.AutoAllowedIP, product.AutoAllowedIPLimit, product.ProductGroupID, product.UpdatedAt, product.ID)
+	tag, err := s.DB.Exec(ctx, query, product.Name, product.Description, product.LicensePrefix, product.LicenseSeparator, product.LicenseCharset, product.LicenseLength, product.LicenseType, product.LicenseDuration, product.AutoAllowedIP, product.AutoAllowedIPLimit, product.ProductGroupID, rateLimitsOrEmpty(product.RateLimits), product.AnomalyThresholds, product.LogPrivacy, stringsOrEmpty(product.DefaultFeatures), stringsOrEmpty(product.DefaultReleases), product.UpdatedAt, product.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %w", err)
 	}
@@ -136,3 +136,19 @@ func (s *PostgresProductStore) DeleteProduct(ctx context.Context, id string) err
 	}
 	return nil
 }
+
+// rateLimitsOrEmpty keeps the NOT NULL rate_limits column an object.
+func rateLimitsOrEmpty(limits map[string]models.RateLimit) map[string]models.RateLimit {
+	if limits == nil {
+		return map[string]models.RateLimit{}
+	}
+	return limits
+}
+
+// stringsOrEmpty keeps NOT NULL array columns empty rather than NULL.
+func stringsOrEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"clortho/internal/models"
+)
+
+// CheckRollupStore maintains the hourly and daily check rollups read by
+// PostgresStatsStore.
+type CheckRollupStore interface {
+	// CheckRollupWatermark returns the time before which rollups are
+	// complete, or nil if nothing was rolled up yet.
+	CheckRollupWatermark(ctx context.Context) (*time.Time, error)
+	// EarliestCheckLog returns the time of the oldest license check, or nil
+	// if there is none.
+	EarliestCheckLog(ctx context.Context) (*time.Time, error)
+	// RollupCheckLogs rebuilds the rollups of the hours in [from, to) and of
+	// the days ending within it. The watermark moves to `to` when the range
+	// continues the rolled up hours.
+	RollupCheckLogs(ctx context.Context, from, to time.Time) error
+}
+
+const (
+	hourBucket = `date_trunc('hour', lcl.created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`
+	dayBucket  = `date_trunc('day', bucket_start AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`
+)
+
+func (s *PostgresStatsStore) CheckRollupWatermark(ctx context.Context) (*time.Time, error) {
+	var watermark *time.Time
+	err := s.DB.QueryRow(ctx, `SELECT rolled_up_to FROM check_rollup_state`).Scan(&watermark)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get check rollup watermark: %w", err)
+	}
+	return watermark, nil
+}
+
+func (s *PostgresStatsStore) EarliestCheckLog(ctx context.Context) (*time.Time, error) {
+	var earliest *time.Time
+	if err := s.DB.QueryRow(ctx, `SELECT min(created_at) FROM license_check_logs`).Scan(&earliest); err != nil {
+		return nil, fmt.Errorf("failed to get earliest license check: %w", err)
+	}
+	return earliest, nil
+}
+
+func (s *PostgresStatsStore) RollupCheckLogs(ctx context.Context, from, to time.Time) error {
+	from = from.UTC().Truncate(time.Hour)
+	to = to.UTC().Truncate(time.Hour)
+	if !from.Before(to) {
+		return nil
+	}
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serializes concurrent runs, e.g. the background job and a backfill
+	if _, err := tx.Exec(ctx, `SELECT rolled_up_to FROM check_rollup_state FOR UPDATE`); err != nil {
+		return fmt.Errorf("failed to lock check rollup state: %w", err)
+	}
+
+	hourly := []string{
+		`INSERT INTO check_rollups_hourly (bucket_start, product_id, license_id, checks, failures)
+		SELECT ` + hourBucket + `, lcl.product_id, lcl.license_id, count(*), count(*) FILTER (WHERE ` + checkFailed + `)
+		FROM license_check_logs lcl
+		WHERE lcl.created_at >= $1 AND lcl.created_at < $2
+		GROUP BY 1, 2, 3`,
+		`INSERT INTO check_ip_rollups_hourly (bucket_start, product_id, ip_address, checks)
+		SELECT ` + hourBucket + `, lcl.product_id, anonymize_ip(lcl.ip_address), count(*)
+		FROM license_check_logs lcl
+		WHERE lcl.created_at >= $1 AND lcl.created_at < $2
+		GROUP BY 1, 2, 3`,
+		`INSERT INTO check_reason_rollups_hourly (bucket_start, product_id, reason, failures)
+		SELECT ` + hourBucket + `, lcl.product_id, ` + checkFailureReason + `, count(*)
+		FROM license_check_logs lcl
+		WHERE lcl.created_at >= $1 AND lcl.created_at < $2 AND ` + checkFailed + `
+		GROUP BY 1, 2, 3`,
+	}
+	if err := rebuildRollups(ctx, tx, "hourly", from, to, hourly); err != nil {
+		return err
+	}
+
+	// Days are rebuilt once all their hours are rolled up
+	dayFrom := truncateToInterval(from, models.StatsIntervalDay)
+	dayTo := truncateToInterval(to, models.StatsIntervalDay)
+	if dayFrom.Before(dayTo) {
+		daily := []string{
+			`INSERT INTO check_rollups_daily (bucket_start, product_id, license_id, checks, failures)
+			SELECT ` + dayBucket + `, product_id, license_id, sum(checks), sum(failures)
+			FROM check_rollups_hourly
+			WHERE bucket_start >= $1 AND bucket_start < $2
+			GROUP BY 1, 2, 3`,
+			`INSERT INTO check_ip_rollups_daily (bucket_start, product_id, ip_address, checks)
+			SELECT ` + dayBucket + `, product_id, ip_address, sum(checks)
+			FROM check_ip_rollups_hourly
+			WHERE bucket_start >= $1 AND bucket_start < $2
+			GROUP BY 1, 2, 3`,
+			`INSERT INTO check_reason_rollups_daily (bucket_start, product_id, reason, failures)
+			SELECT ` + dayBucket + `, product_id, reason, sum(failures)
+			FROM check_reason_rollups_hourly
+			WHERE bucket_start >= $1 AND bucket_start < $2
+			GROUP BY 1, 2, 3`,
+		}
+		if err := rebuildRollups(ctx, tx, "daily", dayFrom, dayTo, daily); err != nil {
+			return err
+		}
+	}
+
+	// Before anything was rolled up, the range must start at the first check
+	// for the rollups to be complete up to `to`.
+	watermarkQuery := `
+		UPDATE check_rollup_state SET rolled_up_to = $2
+		WHERE (rolled_up_to >= $1 AND rolled_up_to < $2)
+			OR (rolled_up_to IS NULL AND NOT EXISTS (SELECT 1 FROM license_check_logs WHERE created_at < $1))
+	`
+	if _, err := tx.Exec(ctx, watermarkQuery, from, to); err != nil {
+		return fmt.Errorf("failed to advance check rollup watermark: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// rebuildRollups replaces the rows of the rollup tables of a granularity in
+// [from, to) with the results of inserts.
+func rebuildRollups(ctx context.Context, tx pgx.Tx, granularity string, from, to time.Time, inserts []string) error {
+	for _, table := range []string{"check_rollups_", "check_ip_rollups_", "check_reason_rollups_"} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+granularity+` WHERE bucket_start >= $1 AND bucket_start < $2`, from, to); err != nil {
+			return fmt.Errorf("failed to clear %s rollups: %w", granularity, err)
+		}
+	}
+	for _, insert := range inserts {
+		if _, err := tx.Exec(ctx, insert, from, to); err != nil {
+			return fmt.Errorf("failed to build %s rollups: %w", granularity, err)
+		}
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package store
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"clortho/internal/models"
@@ -13,6 +15,9 @@ import (
 
 type StatsStore interface {
 	GetDashboardStats(ctx context.Context, ownerID *string, since *time.Time) (*models.DashboardStats, error)
+	GetCheckTimeseries(ctx context.Context, interval string, filter models.CheckStatsFilter) (*models.Timeseries, error)
+	GetTopChecks(ctx context.Context, dimension string, filter models.CheckStatsFilter, limit int) ([]models.TopEntry, error)
+	GetVersionAdoption(ctx context.Context, dimension string, filter models.CheckStatsFilter) ([]models.ProductAdoption, error)
 }
 
 type PostgresStatsStore struct {
@@ -48,36 +53,19 @@ func (s *PostgresStatsStore) GetDashboardStats(ctx context.Context, ownerID *str
 		return nil, fmt.Errorf("failed to count licenses: %w", err)
 	}
 
-	// 3. Total License Checks
-	checkQuery := `
-		SELECT count(*) 
-		FROM license_check_logs lcl
-	`
-	checkArgs := []interface{}{}
-	whereClauses := []string{}
-	argIdx := 1
-
-	if ownerID != nil {
-		checkQuery += ` JOIN products p ON lcl.product_id = p.id`
-		whereClauses = append(whereClauses, fmt.Sprintf("p.owner_id = $%d", argIdx))
-		checkArgs = append(checkArgs, ownerID)
-		argIdx++
+	// 3. Total License Checks and Errors, read from the check rollups where
+	// they exist
+	watermark, err := s.CheckRollupWatermark(ctx)
+	if err != nil {
+		return nil, err
 	}
-
+	now := time.Now()
+	checkFilter := models.CheckStatsFilter{Until: now, OwnerID: ownerID}
 	if since != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("lcl.created_at >= $%d", argIdx))
-		checkArgs = append(checkArgs, since)
-		argIdx++
+		checkFilter.Since = *since
 	}
-
-	if len(whereClauses) > 0 {
-		checkQuery += " WHERE " + whereClauses[0]
-		for i := 1; i < len(whereClauses); i++ {
-			checkQuery += " AND " + whereClauses[i]
-		}
-	}
-
-	if err := s.DB.QueryRow(ctx, checkQuery, checkArgs...).Scan(&stats.TotalLicenseChecks); err != nil {
+	stats.TotalLicenseChecks, stats.TotalLicenseCheckErrors, err = s.countChecks(ctx, watermark, checkFilter)
+	if err != nil {
 		return nil, fmt.Errorf("failed to count license checks: %w", err)
 	}
 
@@ -135,196 +123,463 @@ func (s *PostgresStatsStore) GetDashboardStats(ctx context.Context, ownerID *str
 		return nil, fmt.Errorf("failed to count license change: %w", err)
 	}
 
-	// 5c. TotalLicenseChecksChange (Last 24h vs Previous 24h)
-	// Checks Last 24h
-	checks24hQuery := `
-		SELECT count(*)
-		FROM license_check_logs lcl
-	`
-	checks24hArgs := []interface{}{}
-	checks24hWhere := []string{`lcl.created_at >= NOW() - INTERVAL '24 hours'`}
-	argIdx = 1
+	// 5c. TotalLicenseChecksChange and TotalLicenseCheckErrorsChange
+	// (Last 24h vs Previous 24h)
+	checks24h, errors24h, err := s.countChecks(ctx, watermark, models.CheckStatsFilter{Since: now.Add(-24 * time.Hour), Until: now, OwnerID: ownerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count checks 24h: %w", err)
+	}
+	checksPrev24h, errorsPrev24h, err := s.countChecks(ctx, watermark, models.CheckStatsFilter{Since: now.Add(-48 * time.Hour), Until: now.Add(-24 * time.Hour), OwnerID: ownerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count checks prev 24h: %w", err)
+	}
+
+	stats.TotalLicenseChecksChange = checks24h - checksPrev24h
+	stats.TotalLicenseCheckErrorsChange = errors24h - errorsPrev24h
 
+	// 6. Recent Admin Logs (Last 3)
+	recentLogsQuery := `SELECT ` + adminLogColumns + ` FROM admin_logs`
+	recentLogsArgs := []interface{}{}
 	if ownerID != nil {
-		checks24hQuery += ` JOIN products p ON lcl.product_id = p.id`
-		checks24hWhere = append(checks24hWhere, fmt.Sprintf("p.owner_id = $%d", argIdx))
-		checks24hArgs = append(checks24hArgs, ownerID)
-		argIdx++
+		recentLogsQuery += ` WHERE owner_id = $1`
+		recentLogsArgs = append(recentLogsArgs, ownerID)
 	}
-	
-	checks24hQuery += " WHERE " + checks24hWhere[0]
-	for i := 1; i < len(checks24hWhere); i++ {
-		checks24hQuery += " AND " + checks24hWhere[i]
+	recentLogsQuery += ` ORDER BY created_at DESC LIMIT 3`
+
+	rows, err := s.DB.Query(ctx, recentLogsQuery, recentLogsArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent admin logs: %w", err)
 	}
+	defer rows.Close()
 
-	var checks24h int
-	if err := s.DB.QueryRow(ctx, checks24hQuery, checks24hArgs...).Scan(&checks24h); err != nil {
-		return nil, fmt.Errorf("failed to count checks 24h: %w", err)
+	var recentLogs []models.AdminLog
+	for rows.Next() {
+		log, err := scanAdminLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		recentLogs = append(recentLogs, log)
 	}
+	stats.RecentAdminLogs = recentLogs
 
-	// Checks Previous 24h (48h to 24h ago)
-	checksPrev24hQuery := `
-		SELECT count(*)
-		FROM license_check_logs lcl
-	`
-	checksPrev24hArgs := []interface{}{}
-	checksPrev24hWhere := []string{
-		`lcl.created_at >= NOW() - INTERVAL '48 hours'`,
-		`lcl.created_at < NOW() - INTERVAL '24 hours'`,
+	return stats, nil
+}
+
+// checkFailed and checkFailureReason classify a license_check_logs row (lcl)
+// the same way the dashboard counts errors.
+const (
+	checkFailed        = `(lcl.status_code != 200 OR lcl.response_payload ->> 'reason' IS NOT NULL)`
+	checkFailureReason = `COALESCE(lcl.response_payload ->> 'reason', lcl.response_payload ->> 'error', 'HTTP ' || lcl.status_code)`
+)
+
+// checkSource is where a part of a time range is read from: the raw check
+// logs or one of the rollup granularities.
+type checkSource string
+
+const (
+	sourceLogs   checkSource = ""
+	sourceHourly checkSource = "hourly"
+	sourceDaily  checkSource = "daily"
+)
+
+type checkSegment struct {
+	source   checkSource
+	from, to time.Time
+}
+
+// checkSegments splits [since, until) into the parts read from rollups, which
+// cover whole hours (and days, when useDaily) before the watermark, and the
+// parts read from the raw logs.
+func checkSegments(since, until time.Time, watermark *time.Time, useDaily bool) []checkSegment {
+	since, until = since.UTC(), until.UTC()
+	if watermark == nil {
+		return []checkSegment{{sourceLogs, since, until}}
 	}
-	argIdx = 1
 
-	if ownerID != nil {
-		checksPrev24hQuery += ` JOIN products p ON lcl.product_id = p.id`
-		checksPrev24hWhere = append(checksPrev24hWhere, fmt.Sprintf("p.owner_id = $%d", argIdx))
-		checksPrev24hArgs = append(checksPrev24hArgs, ownerID)
-		argIdx++
+	rollFrom := ceilToInterval(since, models.StatsIntervalHour)
+	rollTo := truncateToInterval(until, models.StatsIntervalHour)
+	if w := watermark.UTC(); w.Before(rollTo) {
+		rollTo = w
+	}
+	if !rollFrom.Before(rollTo) {
+		return []checkSegment{{sourceLogs, since, until}}
 	}
 
-	checksPrev24hQuery += " WHERE " + checksPrev24hWhere[0]
-	for i := 1; i < len(checksPrev24hWhere); i++ {
-		checksPrev24hQuery += " AND " + checksPrev24hWhere[i]
+	var segments []checkSegment
+	add := func(source checkSource, from, to time.Time) {
+		if from.Before(to) {
+			segments = append(segments, checkSegment{source, from, to})
+		}
 	}
 
-	var checksPrev24h int
-	if err := s.DB.QueryRow(ctx, checksPrev24hQuery, checksPrev24hArgs...).Scan(&checksPrev24h); err != nil {
-		return nil, fmt.Errorf("failed to count checks prev 24h: %w", err)
+	add(sourceLogs, since, rollFrom)
+	dayFrom := ceilToInterval(rollFrom, models.StatsIntervalDay)
+	dayTo := truncateToInterval(rollTo, models.StatsIntervalDay)
+	if useDaily && dayFrom.Before(dayTo) {
+		add(sourceHourly, rollFrom, dayFrom)
+		add(sourceDaily, dayFrom, dayTo)
+		add(sourceHourly, dayTo, rollTo)
+	} else {
+		add(sourceHourly, rollFrom, rollTo)
 	}
+	add(sourceLogs, rollTo, until)
+	return segments
+}
 
-	stats.TotalLicenseChecksChange = checks24h - checksPrev24h
+func ceilToInterval(t time.Time, interval string) time.Time {
+	start := truncateToInterval(t, interval)
+	if start.Equal(t) {
+		return start
+	}
+	return nextInterval(start, interval)
+}
 
-	// 5d. TotalLicenseCheckErrors
-	errorQuery := `
-		SELECT count(*)
-		FROM license_check_logs lcl
-	`
-	errorArgs := []interface{}{}
-	errorWhere := []string{"(lcl.status_code != 200 OR lcl.response_payload ->> 'reason' IS NOT NULL)"}
-	argIdx = 1
+// checkRows is one shape of check rows, selected with the same columns from
+// the raw logs and from the rollup tables of that shape. Every shape starts
+// with (ts, product_id).
+type checkRows struct {
+	table string
+	// rollup and logs select the columns from a rollup table and from
+	// license_check_logs lcl; logsWhere restricts the raw rows.
+	rollup    string
+	logs      string
+	logsWhere string
+}
 
-	if ownerID != nil {
-		errorQuery += ` JOIN products p ON lcl.product_id = p.id`
-		errorWhere = append(errorWhere, fmt.Sprintf("p.owner_id = $%d", argIdx))
-		errorArgs = append(errorArgs, ownerID)
-		argIdx++
+var (
+	// Checks and failures per license
+	licenseCheckRows = checkRows{
+		table:  "check_rollups_",
+		rollup: "bucket_start AS ts, product_id, license_id, checks, failures",
+		logs:   "lcl.created_at AS ts, lcl.product_id, lcl.license_id, 1::bigint AS checks, (CASE WHEN " + checkFailed + " THEN 1 ELSE 0 END)::bigint AS failures",
+	}
+	// Client networks; IPs are anonymized as they are rolled up
+	ipCheckRows = checkRows{
+		table:  "check_ip_rollups_",
+		rollup: "bucket_start AS ts, product_id, ip_address",
+		logs:   "lcl.created_at AS ts, lcl.product_id, anonymize_ip(lcl.ip_address) AS ip_address",
+	}
+	// Failures per reason
+	reasonCheckRows = checkRows{
+		table:     "check_reason_rollups_",
+		rollup:    "bucket_start AS ts, product_id, reason, failures",
+		logs:      "lcl.created_at AS ts, lcl.product_id, " + checkFailureReason + " AS reason, 1::bigint AS failures",
+		logsWhere: checkFailed,
 	}
+)
 
-	if since != nil {
-		errorWhere = append(errorWhere, fmt.Sprintf("lcl.created_at >= $%d", argIdx))
-		errorArgs = append(errorArgs, since)
-		argIdx++
-	}
+// queryArgs collects positional query arguments.
+type queryArgs []interface{}
 
-	errorQuery += " WHERE " + errorWhere[0]
-	for i := 1; i < len(errorWhere); i++ {
-		errorQuery += " AND " + errorWhere[i]
-	}
+func (a *queryArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
 
-	if err := s.DB.QueryRow(ctx, errorQuery, errorArgs...).Scan(&stats.TotalLicenseCheckErrors); err != nil {
-		return nil, fmt.Errorf("failed to count license check errors: %w", err)
+// checkRowsFrom returns a FROM clause selecting the rows of a shape over the
+// segments as `c`, restricted to the products matched by filter.
+func checkRowsFrom(rows checkRows, segments []checkSegment, filter models.CheckStatsFilter, args *queryArgs) string {
+	parts := make([]string, len(segments))
+	for i, seg := range segments {
+		if seg.source == sourceLogs {
+			where := "lcl.created_at >= " + args.add(seg.from) + " AND lcl.created_at < " + args.add(seg.to)
+			if rows.logsWhere != "" {
+				where += " AND " + rows.logsWhere
+			}
+			parts[i] = "SELECT " + rows.logs + " FROM license_check_logs lcl WHERE " + where
+			continue
+		}
+		parts[i] = "SELECT " + rows.rollup + " FROM " + rows.table + string(seg.source) +
+			" WHERE bucket_start >= " + args.add(seg.from) + " AND bucket_start < " + args.add(seg.to)
 	}
 
-	// 5e. TotalLicenseCheckErrorsChange (Last 24h vs Previous 24h)
-	// Errors Last 24h
-	errors24hQuery := `
-		SELECT count(*)
-		FROM license_check_logs lcl
-	`
-	errors24hArgs := []interface{}{}
-	errors24hWhere := []string{
-		`(lcl.status_code != 200 OR lcl.response_payload ->> 'reason' IS NOT NULL)`,
-		`lcl.created_at >= NOW() - INTERVAL '24 hours'`,
+	from := " FROM (" + strings.Join(parts, " UNION ALL ") + ") c"
+	where := []string{}
+	if filter.ProductID != nil {
+		where = append(where, "c.product_id = "+args.add(*filter.ProductID))
+	}
+	if filter.ProductGroupID != nil || filter.OwnerID != nil {
+		from += " JOIN products p ON c.product_id = p.id"
+		if filter.ProductGroupID != nil {
+			where = append(where, "p.product_group_id = "+args.add(*filter.ProductGroupID))
+		}
+		if filter.OwnerID != nil {
+			where = append(where, "p.owner_id = "+args.add(*filter.OwnerID))
+		}
 	}
-	argIdx = 1
+	if len(where) > 0 {
+		from += " WHERE " + strings.Join(where, " AND ")
+	}
+	return from
+}
 
-	if ownerID != nil {
-		errors24hQuery += ` JOIN products p ON lcl.product_id = p.id`
-		errors24hWhere = append(errors24hWhere, fmt.Sprintf("p.owner_id = $%d", argIdx))
-		errors24hArgs = append(errors24hArgs, ownerID)
-		argIdx++
+func (s *PostgresStatsStore) GetCheckTimeseries(ctx context.Context, interval string, filter models.CheckStatsFilter) (*models.Timeseries, error) {
+	watermark, err := s.CheckRollupWatermark(ctx)
+	if err != nil {
+		return nil, err
 	}
-	
-	errors24hQuery += " WHERE " + errors24hWhere[0]
-	for i := 1; i < len(errors24hWhere); i++ {
-		errors24hQuery += " AND " + errors24hWhere[i]
+	segments := checkSegments(filter.Since, filter.Until, watermark, interval != models.StatsIntervalHour)
+
+	series := newTimeseries(interval, filter.Since, filter.Until)
+	buckets := make(map[time.Time]*models.TimeseriesBucket, len(series.Buckets))
+	for i := range series.Buckets {
+		buckets[series.Buckets[i].Start] = &series.Buckets[i]
 	}
 
-	var errors24h int
-	if err := s.DB.QueryRow(ctx, errors24hQuery, errors24hArgs...).Scan(&errors24h); err != nil {
-		return nil, fmt.Errorf("failed to count errors 24h: %w", err)
+	var args queryArgs
+	bucket := "date_trunc(" + args.add(interval) + ", c.ts AT TIME ZONE 'UTC')"
+	totalsQuery := "SELECT " + bucket + ", sum(c.checks)::bigint, sum(c.failures)::bigint, count(DISTINCT c.license_id)" +
+		checkRowsFrom(licenseCheckRows, segments, filter, &args) + " GROUP BY 1"
+	err = s.scanRows(ctx, totalsQuery, args, func(rows pgx.Rows) error {
+		var start time.Time
+		var checks, failures, licenses int
+		if err := rows.Scan(&start, &checks, &failures, &licenses); err != nil {
+			return err
+		}
+		if b, ok := buckets[start]; ok {
+			b.Checks, b.Failures, b.UniqueLicenses = checks, failures, licenses
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query check timeseries: %w", err)
+	}
+
+	args = nil
+	bucket = "date_trunc(" + args.add(interval) + ", c.ts AT TIME ZONE 'UTC')"
+	networksQuery := "SELECT " + bucket + ", count(DISTINCT c.ip_address)" +
+		checkRowsFrom(ipCheckRows, segments, filter, &args) + " GROUP BY 1"
+	err = s.scanRows(ctx, networksQuery, args, func(rows pgx.Rows) error {
+		var start time.Time
+		var networks int
+		if err := rows.Scan(&start, &networks); err != nil {
+			return err
+		}
+		if b, ok := buckets[start]; ok {
+			b.UniqueNetworks = networks
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unique network timeseries: %w", err)
+	}
+
+	args = nil
+	bucket = "date_trunc(" + args.add(interval) + ", c.ts AT TIME ZONE 'UTC')"
+	reasonsQuery := "SELECT " + bucket + ", c.reason, sum(c.failures)::bigint" +
+		checkRowsFrom(reasonCheckRows, segments, filter, &args) + " GROUP BY 1, 2"
+	err = s.scanRows(ctx, reasonsQuery, args, func(rows pgx.Rows) error {
+		var start time.Time
+		var reason string
+		var count int
+		if err := rows.Scan(&start, &reason, &count); err != nil {
+			return err
+		}
+		if b, ok := buckets[start]; ok {
+			b.FailuresByReason[reason] = count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query check failure reasons: %w", err)
 	}
 
-	// Errors Previous 24h (48h to 24h ago)
-	errorsPrev24hQuery := `
-		SELECT count(*)
-		FROM license_check_logs lcl
-	`
-	errorsPrev24hArgs := []interface{}{}
-	errorsPrev24hWhere := []string{
-		`(lcl.status_code != 200 OR lcl.response_payload ->> 'reason' IS NOT NULL)`,
-		`lcl.created_at >= NOW() - INTERVAL '48 hours'`,
-		`lcl.created_at < NOW() - INTERVAL '24 hours'`,
+	return series, nil
+}
+
+// countChecks returns the number of checks and failed checks matched by filter.
+func (s *PostgresStatsStore) countChecks(ctx context.Context, watermark *time.Time, filter models.CheckStatsFilter) (int, int, error) {
+	var args queryArgs
+	query := "SELECT COALESCE(sum(c.checks), 0)::bigint, COALESCE(sum(c.failures), 0)::bigint" +
+		checkRowsFrom(licenseCheckRows, checkSegments(filter.Since, filter.Until, watermark, true), filter, &args)
+	var checks, failures int
+	if err := s.DB.QueryRow(ctx, query, args...).Scan(&checks, &failures); err != nil {
+		return 0, 0, err
 	}
-	argIdx = 1
+	return checks, failures, nil
+}
 
-	if ownerID != nil {
-		errorsPrev24hQuery += ` JOIN products p ON lcl.product_id = p.id`
-		errorsPrev24hWhere = append(errorsPrev24hWhere, fmt.Sprintf("p.owner_id = $%d", argIdx))
-		errorsPrev24hArgs = append(errorsPrev24hArgs, ownerID)
-		argIdx++
+func (s *PostgresStatsStore) scanRows(ctx context.Context, query string, args queryArgs, scan func(pgx.Rows) error) error {
+	rows, err := s.DB.Query(ctx, query, args...)
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// newTimeseries returns the empty buckets covering [since, until).
+func newTimeseries(interval string, since, until time.Time) *models.Timeseries {
+	series := &models.Timeseries{
+		Interval: interval,
+		Since:    since,
+		Until:    until,
+		Buckets:  []models.TimeseriesBucket{},
+	}
+	for start := truncateToInterval(since, interval); start.Before(until); start = nextInterval(start, interval) {
+		series.Buckets = append(series.Buckets, models.TimeseriesBucket{
+			Start:            start,
+			FailuresByReason: map[string]int{},
+		})
+	}
+	return series
+}
 
-	errorsPrev24hQuery += " WHERE " + errorsPrev24hWhere[0]
-	for i := 1; i < len(errorsPrev24hWhere); i++ {
-		errorsPrev24hQuery += " AND " + errorsPrev24hWhere[i]
+// truncateToInterval returns the start of the UTC hour, day or week (starting
+// on Monday, like Postgres' date_trunc) containing t.
+func truncateToInterval(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case models.StatsIntervalHour:
+		return t.Truncate(time.Hour)
+	case models.StatsIntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 	}
+}
 
-	var errorsPrev24h int
-	if err := s.DB.QueryRow(ctx, errorsPrev24hQuery, errorsPrev24hArgs...).Scan(&errorsPrev24h); err != nil {
-		return nil, fmt.Errorf("failed to count errors prev 24h: %w", err)
+func nextInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case models.StatsIntervalHour:
+		return t.Add(time.Hour)
+	case models.StatsIntervalWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
 	}
+}
 
-	stats.TotalLicenseCheckErrorsChange = errors24h - errorsPrev24h
+func (s *PostgresStatsStore) GetTopChecks(ctx context.Context, dimension string, filter models.CheckStatsFilter, limit int) ([]models.TopEntry, error) {
+	var value, extra string
+	switch dimension {
+	case models.TopDimensionLicenses:
+		value, extra = `lcl.license_key`, `lcl.license_id IS NOT NULL`
+	case models.TopDimensionFailingKeys:
+		value, extra = `lcl.license_key`, `lcl.license_key <> '' AND `+checkFailed
+	case models.TopDimensionUserAgents:
+		value, extra = `lcl.user_agent`, `lcl.user_agent <> ''`
+	case models.TopDimensionVersions:
+		value, extra = `lcl.request_payload ->> 'version'`, `COALESCE(lcl.request_payload ->> 'version', '') <> ''`
+	default:
+		return nil, fmt.Errorf("unknown top dimension %q", dimension)
+	}
+
+	// Top values are not rolled up and always come from the raw logs
+	shape := checkRows{
+		logs:      "lcl.created_at AS ts, lcl.product_id, " + value + " AS value, " + checkFailed + " AS failed",
+		logsWhere: extra,
+	}
+	var args queryArgs
+	from := checkRowsFrom(shape, []checkSegment{{sourceLogs, filter.Since, filter.Until}}, filter, &args)
+	query := "SELECT c.value, count(*), count(*) FILTER (WHERE c.failed)" + from +
+		" GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT " + args.add(limit)
+
+	rows, err := s.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top %s: %w", dimension, err)
+	}
+	defer rows.Close()
 
-	// 6. Recent Admin Logs (Last 3)
-	recentLogsQuery := `
-		SELECT id, action, entity_type, entity_id, owner_id, details, created_at
-		FROM admin_logs
-	`
-	recentLogsArgs := []interface{}{}
-	if ownerID != nil {
-		recentLogsQuery += ` WHERE owner_id = $1`
-		recentLogsArgs = append(recentLogsArgs, ownerID)
+	entries := []models.TopEntry{}
+	for rows.Next() {
+		var e models.TopEntry
+		if err := rows.Scan(&e.Value, &e.Checks, &e.Failures); err != nil {
+			return nil, fmt.Errorf("failed to scan top %s: %w", dimension, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top %s: %w", dimension, err)
 	}
-	recentLogsQuery += ` ORDER BY created_at DESC LIMIT 3`
 
-	rows, err := s.DB.Query(ctx, recentLogsQuery, recentLogsArgs...)
+	return entries, nil
+}
+
+// GetVersionAdoption counts, per product, the licenses whose latest check in
+// the range reported each client version, along with the checks reporting it.
+// Checks without the version are ignored.
+func (s *PostgresStatsStore) GetVersionAdoption(ctx context.Context, dimension string, filter models.CheckStatsFilter) ([]models.ProductAdoption, error) {
+	var column string
+	switch dimension {
+	case models.AdoptionDimensionAppVersion:
+		column = "lcl.client_app_version"
+	case models.AdoptionDimensionSDKVersion:
+		column = "lcl.client_sdk_version"
+	default:
+		return nil, fmt.Errorf("unknown adoption dimension %q", dimension)
+	}
+
+	// Client metadata is not rolled up and always comes from the raw logs
+	shape := checkRows{
+		logs:      "lcl.created_at AS ts, lcl.product_id, lcl.license_id, " + column + " AS version",
+		logsWhere: "lcl.product_id IS NOT NULL AND " + column + " <> ''",
+	}
+	var args queryArgs
+	from := checkRowsFrom(shape, []checkSegment{{sourceLogs, filter.Since, filter.Until}}, filter, &args)
+	query := `
+		WITH checks AS (SELECT c.ts, c.product_id, c.license_id, c.version` + from + `),
+		latest AS (
+			SELECT DISTINCT ON (license_id) product_id, license_id, version
+			FROM checks
+			WHERE license_id IS NOT NULL
+			ORDER BY license_id, ts DESC
+		)
+		SELECT v.product_id, v.version, COALESCE(l.licenses, 0), v.checks, v.last_seen
+		FROM (
+			SELECT product_id, version, count(*) AS checks, max(ts) AS last_seen
+			FROM checks
+			GROUP BY 1, 2
+		) v
+		LEFT JOIN (
+			SELECT product_id, version, count(*) AS licenses
+			FROM latest
+			GROUP BY 1, 2
+		) l ON l.product_id = v.product_id AND l.version = v.version
+		ORDER BY 1, 3 DESC, 4 DESC, 2`
+
+	rows, err := s.DB.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query recent admin logs: %w", err)
+		return nil, fmt.Errorf("failed to query %s adoption: %w", dimension, err)
 	}
 	defer rows.Close()
 
-	var recentLogs []models.AdminLog
+	products := []models.ProductAdoption{}
 	for rows.Next() {
-		var log models.AdminLog
-		var detailsJSON []byte
-		if err := rows.Scan(
-			&log.ID,
-			&log.Action,
-			&log.EntityType,
-			&log.EntityID,
-			&log.OwnerID,
-			&detailsJSON,
-			&log.CreatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan admin log: %w", err)
+		var productID uuid.UUID
+		var v models.VersionAdoption
+		if err := rows.Scan(&productID, &v.Version, &v.Licenses, &v.Checks, &v.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan %s adoption: %w", dimension, err)
 		}
-		if err := json.Unmarshal(detailsJSON, &log.Details); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal details: %w", err)
+		if len(products) == 0 || products[len(products)-1].ProductID != productID {
+			products = append(products, models.ProductAdoption{ProductID: productID, Versions: []models.VersionAdoption{}})
 		}
-		recentLogs = append(recentLogs, log)
+		p := &products[len(products)-1]
+		p.Licenses += v.Licenses
+		p.Versions = append(p.Versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s adoption: %w", dimension, err)
 	}
-	stats.RecentAdminLogs = recentLogs
 
-	return stats, nil
+	for i := range products {
+		setAdoptionShares(&products[i])
+	}
+	return products, nil
+}
+
+// setAdoptionShares sets the share of the product's licenses on each version.
+func setAdoptionShares(p *models.ProductAdoption) {
+	if p.Licenses == 0 {
+		return
+	}
+	for i := range p.Versions {
+		p.Versions[i].Share = float64(p.Versions[i].Licenses) / float64(p.Licenses)
+	}
 }
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"clortho/internal/models"
+)
+
+func TestNewTimeseries(t *testing.T) {
+	// Wednesday afternoon to the next Tuesday morning
+	since := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+	until := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	hours := newTimeseries(models.StatsIntervalHour, since, until)
+	assert.Equal(t, time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC), hours.Buckets[0].Start)
+	assert.Len(t, hours.Buckets, 5*24+18)
+
+	days := newTimeseries(models.StatsIntervalDay, since, until)
+	assert.Equal(t, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), days.Buckets[0].Start)
+	assert.Len(t, days.Buckets, 7)
+	assert.NotNil(t, days.Buckets[6].FailuresByReason)
+
+	weeks := newTimeseries(models.StatsIntervalWeek, since, until)
+	assert.Equal(t, []time.Time{
+		time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+	}, []time.Time{weeks.Buckets[0].Start, weeks.Buckets[1].Start})
+	assert.Len(t, weeks.Buckets, 2)
+
+	// Non-UTC input lands in UTC buckets
+	local := since.In(time.FixedZone("UTC+9", 9*3600))
+	assert.Equal(t, days.Buckets[0].Start, newTimeseries(models.StatsIntervalDay, local, until).Buckets[0].Start)
+}
+
+func TestCheckSegments(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	watermark := at(6, 14, 0)
+
+	t.Run("NoRollups", func(t *testing.T) {
+		assert.Equal(t, []checkSegment{{sourceLogs, at(1, 0, 0), at(7, 0, 0)}}, checkSegments(at(1, 0, 0), at(7, 0, 0), nil, true))
+	})
+
+	t.Run("HourlyAndDaily", func(t *testing.T) {
+		assert.Equal(t, []checkSegment{
+			{sourceLogs, at(2, 9, 30), at(2, 10, 0)},
+			{sourceHourly, at(2, 10, 0), at(3, 0, 0)},
+			{sourceDaily, at(3, 0, 0), at(6, 0, 0)},
+			{sourceHourly, at(6, 0, 0), at(6, 14, 0)},
+			{sourceLogs, at(6, 14, 0), at(6, 18, 45)},
+		}, checkSegments(at(2, 9, 30), at(6, 18, 45), &watermark, true))
+	})
+
+	t.Run("HourlyOnly", func(t *testing.T) {
+		assert.Equal(t, []checkSegment{
+			{sourceHourly, at(2, 0, 0), at(6, 14, 0)},
+			{sourceLogs, at(6, 14, 0), at(7, 0, 0)},
+		}, checkSegments(at(2, 0, 0), at(7, 0, 0), &watermark, false))
+	})
+
+	t.Run("BeforeWatermark", func(t *testing.T) {
+		assert.Equal(t, []checkSegment{
+			{sourceDaily, at(1, 0, 0), at(3, 0, 0)},
+			{sourceHourly, at(3, 0, 0), at(3, 5, 0)},
+			{sourceLogs, at(3, 5, 0), at(3, 5, 20)},
+		}, checkSegments(at(1, 0, 0), at(3, 5, 20), &watermark, true))
+	})
+
+	t.Run("WithinOneHour", func(t *testing.T) {
+		assert.Equal(t, []checkSegment{{sourceLogs, at(3, 5, 10), at(3, 5, 50)}}, checkSegments(at(3, 5, 10), at(3, 5, 50), &watermark, true))
+	})
+}
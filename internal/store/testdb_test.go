@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"clortho/internal/database"
+)
+
+// newTestPool starts a migrated Postgres container for the test and returns
+// a pool connected to it. Both are closed when the test ends.
+func newTestPool(t *testing.T, dbName string) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	postgresContainer, err := postgres.Run(ctx,
+		"postgres:15-alpine",
+		postgres.WithDatabase(dbName),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(10*time.Second)),
+	)
+	if err != nil {
+		t.Fatalf("failed to start postgres container: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate postgres container: %s", err)
+		}
+	})
+
+	connStr, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	absPath, _ := filepath.Abs("../../migrations")
+	require.NoError(t, database.Migrate(connStr, absPath))
+
+	pool, err := database.New(ctx, connStr)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
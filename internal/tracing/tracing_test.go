@@ -0,0 +1,25 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"clortho/internal/config"
+)
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	shutdown, err := Setup(ctx, config.TracingConfig{Enabled: false})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(ctx))
+
+	_, err = Setup(ctx, config.TracingConfig{Enabled: true, Exporter: "carrier-pigeon"})
+	assert.Error(t, err)
+
+	shutdown, err = Setup(ctx, config.TracingConfig{Enabled: true, Exporter: ExporterStdout})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(ctx))
+}
@@ -0,0 +1,9 @@
+-- Keys retired by rotation. They keep pointing at the license so /check can
+-- explain why they stopped working and history stays tied to the license id.
+CREATE TABLE license_key_aliases (
+    key TEXT PRIMARY KEY,
+    license_id UUID NOT NULL REFERENCES licenses(id) ON DELETE CASCADE,
+    rotated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_license_key_aliases_license_id ON license_key_aliases(license_id);
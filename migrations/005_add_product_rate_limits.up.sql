@@ -0,0 +1,3 @@
+-- Per-product overrides of /check rate limit policies, keyed by policy name:
+-- {"license_key": {"requests_per_second": 1, "burst": 5}}
+ALTER TABLE products ADD COLUMN rate_limits JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_license_check_logs_license_id_created_at;
+DROP TABLE IF EXISTS license_flags;
+ALTER TABLE products DROP COLUMN IF EXISTS anomaly_thresholds;
+-- Enum values cannot be dropped; 'suspended' stays in license_status
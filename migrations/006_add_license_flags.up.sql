@@ -0,0 +1,22 @@
+-- Flagged licenses can be suspended, a hold that unlike revocation can be lifted
+ALTER TYPE license_status ADD VALUE IF NOT EXISTS 'suspended';
+
+-- Per-product overrides of the anomaly detection thresholds:
+-- {"distinct_ips": 50, "distinct_user_agents": 20, "distinct_prefixes": 10, "auto_suspend": true}
+ALTER TABLE products ADD COLUMN anomaly_thresholds JSONB NOT NULL DEFAULT '{}';
+
+-- Licenses whose checks came from unusually many distinct clients
+CREATE TABLE license_flags (
+    license_id UUID PRIMARY KEY REFERENCES licenses(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL,
+    distinct_ips INTEGER NOT NULL,
+    distinct_user_agents INTEGER NOT NULL,
+    distinct_prefixes INTEGER NOT NULL,
+    window_seconds INTEGER NOT NULL,
+    suspended BOOLEAN NOT NULL DEFAULT false,
+    first_detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_license_flags_last_detected_at ON license_flags(last_detected_at);
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_license_id_created_at ON license_check_logs(license_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_licenses_suspended_until;
+ALTER TABLE licenses DROP COLUMN IF EXISTS suspended_until;
+ALTER TABLE licenses DROP COLUMN IF EXISTS suspension_reason;
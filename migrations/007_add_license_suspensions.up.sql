@@ -0,0 +1,6 @@
+-- Why a license is suspended and when it resumes on its own (NULL: manual resume only)
+ALTER TABLE licenses ADD COLUMN suspension_reason TEXT;
+ALTER TABLE licenses ADD COLUMN suspended_until TIMESTAMP WITH TIME ZONE;
+
+-- Only suspended licenses have a resume time
+CREATE INDEX IF NOT EXISTS idx_licenses_suspended_until ON licenses(suspended_until) WHERE suspended_until IS NOT NULL;
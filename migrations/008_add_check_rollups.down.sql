@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS check_rollup_state;
+DROP TABLE IF EXISTS check_reason_rollups_daily;
+DROP TABLE IF EXISTS check_reason_rollups_hourly;
+DROP TABLE IF EXISTS check_ip_rollups_daily;
+DROP TABLE IF EXISTS check_ip_rollups_hourly;
+DROP TABLE IF EXISTS check_rollups_daily;
+DROP TABLE IF EXISTS check_rollups_hourly;
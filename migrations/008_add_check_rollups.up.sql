@@ -0,0 +1,50 @@
+-- Pre-aggregated license checks for analytics. Hourly rows are built from
+-- license_check_logs, daily rows from hourly ones. Rows keep their product
+-- and license ids without foreign keys so analytics outlive deleted entities.
+
+-- Checks per license; rows with a NULL license_id count unknown keys
+CREATE TABLE check_rollups_hourly (
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    product_id UUID,
+    license_id UUID,
+    checks BIGINT NOT NULL,
+    failures BIGINT NOT NULL
+);
+
+CREATE TABLE check_rollups_daily (LIKE check_rollups_hourly);
+
+-- Distinct client IPs, kept per bucket so unique counts stay exact across buckets
+CREATE TABLE check_ip_rollups_hourly (
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    product_id UUID,
+    ip_address VARCHAR(45),
+    checks BIGINT NOT NULL
+);
+
+CREATE TABLE check_ip_rollups_daily (LIKE check_ip_rollups_hourly);
+
+-- Failed checks per reason
+CREATE TABLE check_reason_rollups_hourly (
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    product_id UUID,
+    reason TEXT NOT NULL,
+    failures BIGINT NOT NULL
+);
+
+CREATE TABLE check_reason_rollups_daily (LIKE check_reason_rollups_hourly);
+
+CREATE INDEX IF NOT EXISTS idx_check_rollups_hourly_bucket ON check_rollups_hourly(bucket_start, product_id);
+CREATE INDEX IF NOT EXISTS idx_check_rollups_daily_bucket ON check_rollups_daily(bucket_start, product_id);
+CREATE INDEX IF NOT EXISTS idx_check_ip_rollups_hourly_bucket ON check_ip_rollups_hourly(bucket_start, product_id);
+CREATE INDEX IF NOT EXISTS idx_check_ip_rollups_daily_bucket ON check_ip_rollups_daily(bucket_start, product_id);
+CREATE INDEX IF NOT EXISTS idx_check_reason_rollups_hourly_bucket ON check_reason_rollups_hourly(bucket_start, product_id);
+CREATE INDEX IF NOT EXISTS idx_check_reason_rollups_daily_bucket ON check_reason_rollups_daily(bucket_start, product_id);
+
+-- Rollups are complete for every hour before rolled_up_to (NULL: none yet).
+-- Later checks are read from license_check_logs.
+CREATE TABLE check_rollup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    rolled_up_to TIMESTAMP WITH TIME ZONE
+);
+
+INSERT INTO check_rollup_state (id, rolled_up_to) VALUES (true, NULL);
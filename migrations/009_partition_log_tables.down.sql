@@ -0,0 +1,43 @@
+-- Back to plain tables; rows of every partition are kept
+
+CREATE TABLE license_check_logs_plain (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID REFERENCES products(id),
+    license_id UUID REFERENCES licenses(id) ON DELETE SET NULL,
+    license_key VARCHAR(255),
+    request_payload JSONB DEFAULT '{}',
+    response_payload JSONB DEFAULT '{}',
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    status_code INT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE admin_logs_plain (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(255) NOT NULL,
+    entity_id UUID,
+    actor VARCHAR(255),
+    details JSONB DEFAULT '{}',
+    owner_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO license_check_logs_plain SELECT * FROM license_check_logs;
+INSERT INTO admin_logs_plain SELECT * FROM admin_logs;
+
+DROP TABLE license_check_logs;
+DROP TABLE admin_logs;
+
+ALTER TABLE license_check_logs_plain RENAME TO license_check_logs;
+ALTER TABLE admin_logs_plain RENAME TO admin_logs;
+ALTER TABLE license_check_logs RENAME CONSTRAINT license_check_logs_plain_pkey TO license_check_logs_pkey;
+ALTER TABLE admin_logs RENAME CONSTRAINT admin_logs_plain_pkey TO admin_logs_pkey;
+
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_license_key ON license_check_logs(license_key);
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_product_id ON license_check_logs(product_id);
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_created_at ON license_check_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_license_id_created_at ON license_check_logs(license_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_admin_logs_owner_id ON admin_logs(owner_id);
+CREATE INDEX IF NOT EXISTS idx_admin_logs_created_at ON admin_logs(created_at);
@@ -0,0 +1,74 @@
+-- license_check_logs and admin_logs become partitioned by month on created_at.
+-- Partitions are named <table>_pYYYYMM. The server creates future partitions
+-- and drops expired ones (see log_partitions in config.yaml.example); this
+-- migration creates those holding existing rows plus the next three months.
+
+ALTER TABLE license_check_logs RENAME TO license_check_logs_unpartitioned;
+ALTER TABLE admin_logs RENAME TO admin_logs_unpartitioned;
+ALTER TABLE license_check_logs_unpartitioned RENAME CONSTRAINT license_check_logs_pkey TO license_check_logs_unpartitioned_pkey;
+ALTER TABLE admin_logs_unpartitioned RENAME CONSTRAINT admin_logs_pkey TO admin_logs_unpartitioned_pkey;
+
+CREATE TABLE license_check_logs (
+    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    product_id UUID REFERENCES products(id),
+    license_id UUID REFERENCES licenses(id) ON DELETE SET NULL,
+    license_key VARCHAR(255),
+    request_payload JSONB DEFAULT '{}',
+    response_payload JSONB DEFAULT '{}',
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    status_code INT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE TABLE admin_logs (
+    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    action VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(255) NOT NULL,
+    entity_id UUID,
+    actor VARCHAR(255),
+    details JSONB DEFAULT '{}',
+    owner_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+DO $$
+DECLARE
+    t TEXT;
+    first_month TIMESTAMP WITH TIME ZONE;
+    month TIMESTAMP WITH TIME ZONE;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['license_check_logs', 'admin_logs'] LOOP
+        EXECUTE format('SELECT min(created_at) FROM %I', t || '_unpartitioned') INTO first_month;
+        first_month := date_trunc('month', LEAST(first_month, NOW()) AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+        month := first_month;
+        WHILE month < (date_trunc('month', NOW() AT TIME ZONE 'UTC') + INTERVAL '4 months') AT TIME ZONE 'UTC' LOOP
+            EXECUTE format(
+                'CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                t || '_p' || to_char(month AT TIME ZONE 'UTC', 'YYYYMM'), t,
+                month, (month AT TIME ZONE 'UTC' + INTERVAL '1 month') AT TIME ZONE 'UTC'
+            );
+            month := (month AT TIME ZONE 'UTC' + INTERVAL '1 month') AT TIME ZONE 'UTC';
+        END LOOP;
+    END LOOP;
+END $$;
+
+INSERT INTO license_check_logs (id, product_id, license_id, license_key, request_payload, response_payload, ip_address, user_agent, status_code, created_at)
+SELECT id, product_id, license_id, license_key, request_payload, response_payload, ip_address, user_agent, status_code, COALESCE(created_at, NOW())
+FROM license_check_logs_unpartitioned;
+
+INSERT INTO admin_logs (id, action, entity_type, entity_id, actor, details, owner_id, created_at)
+SELECT id, action, entity_type, entity_id, actor, details, owner_id, COALESCE(created_at, NOW())
+FROM admin_logs_unpartitioned;
+
+DROP TABLE license_check_logs_unpartitioned;
+DROP TABLE admin_logs_unpartitioned;
+
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_license_key ON license_check_logs(license_key);
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_product_id ON license_check_logs(product_id);
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_created_at ON license_check_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_license_id_created_at ON license_check_logs(license_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_admin_logs_owner_id ON admin_logs(owner_id);
+CREATE INDEX IF NOT EXISTS idx_admin_logs_created_at ON admin_logs(created_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_license_check_logs_client_sdk_version;
+DROP INDEX IF EXISTS idx_license_check_logs_client_app_version;
+
+ALTER TABLE license_check_logs
+    DROP COLUMN IF EXISTS client_sdk_version,
+    DROP COLUMN IF EXISTS client_hostname_hash,
+    DROP COLUMN IF EXISTS client_os,
+    DROP COLUMN IF EXISTS client_app_version;
@@ -0,0 +1,8 @@
+ALTER TABLE license_check_logs
+    ADD COLUMN client_app_version TEXT NOT NULL DEFAULT '',
+    ADD COLUMN client_os TEXT NOT NULL DEFAULT '',
+    ADD COLUMN client_hostname_hash TEXT NOT NULL DEFAULT '',
+    ADD COLUMN client_sdk_version TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_license_check_logs_client_app_version ON license_check_logs (product_id, client_app_version, created_at) WHERE client_app_version <> '';
+CREATE INDEX idx_license_check_logs_client_sdk_version ON license_check_logs (product_id, client_sdk_version, created_at) WHERE client_sdk_version <> '';
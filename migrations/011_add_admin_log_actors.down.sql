@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_admin_logs_entity;
+DROP INDEX IF EXISTS idx_admin_logs_action;
+DROP INDEX IF EXISTS idx_admin_logs_actor;
+
+ALTER TABLE admin_logs
+    DROP COLUMN IF EXISTS changes,
+    DROP COLUMN IF EXISTS actor_user_agent,
+    DROP COLUMN IF EXISTS actor_ip,
+    DROP COLUMN IF EXISTS actor_token_id;
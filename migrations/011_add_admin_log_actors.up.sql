@@ -0,0 +1,10 @@
+-- actor holds the subject of the admin token
+ALTER TABLE admin_logs
+    ADD COLUMN actor_token_id VARCHAR(255),
+    ADD COLUMN actor_ip VARCHAR(64),
+    ADD COLUMN actor_user_agent TEXT,
+    ADD COLUMN changes JSONB;
+
+CREATE INDEX idx_admin_logs_actor ON admin_logs (actor, created_at);
+CREATE INDEX idx_admin_logs_action ON admin_logs (action, created_at);
+CREATE INDEX idx_admin_logs_entity ON admin_logs (entity_type, entity_id, created_at);
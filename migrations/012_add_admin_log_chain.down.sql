@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS admin_log_checkpoints;
+DROP TABLE IF EXISTS admin_log_chain;
+
+DROP INDEX IF EXISTS idx_admin_logs_seq;
+
+ALTER TABLE admin_logs
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash,
+    DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,27 @@
+-- Admin logs form a hash chain: each entry stores the SHA-256 of its content
+-- and of the previous entry's hash (see store.AdminLogHash). Entries written
+-- before this migration stay outside the chain with a NULL seq.
+ALTER TABLE admin_logs
+    ADD COLUMN seq BIGINT,
+    ADD COLUMN prev_hash CHAR(64),
+    ADD COLUMN hash CHAR(64);
+
+CREATE INDEX idx_admin_logs_seq ON admin_logs (seq) WHERE seq IS NOT NULL;
+
+-- Head of the chain. Writers lock this row so entries are chained one at a time.
+CREATE TABLE admin_log_chain (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    seq BIGINT NOT NULL,
+    hash CHAR(64) NOT NULL
+);
+
+INSERT INTO admin_log_chain (id, seq, hash) VALUES (true, 0, repeat('0', 64));
+
+-- Chain heads signed with the response signing key
+CREATE TABLE admin_log_checkpoints (
+    seq BIGINT PRIMARY KEY,
+    hash CHAR(64) NOT NULL,
+    public_key TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS log_forward_cursors;
+
+DROP INDEX IF EXISTS idx_admin_logs_ingested_at;
+DROP INDEX IF EXISTS idx_license_check_logs_ingested_at;
+
+ALTER TABLE admin_logs DROP COLUMN IF EXISTS ingested_at;
+ALTER TABLE license_check_logs DROP COLUMN IF EXISTS ingested_at;
@@ -0,0 +1,16 @@
+-- ingested_at is when a row was written, which is later than created_at for
+-- queued entries and can be much later for entries replayed from a spill
+-- file. The log forwarder follows it so such rows are not skipped.
+ALTER TABLE license_check_logs ADD COLUMN ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+ALTER TABLE admin_logs ADD COLUMN ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE INDEX idx_license_check_logs_ingested_at ON license_check_logs (ingested_at, id);
+CREATE INDEX idx_admin_logs_ingested_at ON admin_logs (ingested_at, id);
+
+-- Last row of each log table delivered to a sink, one row per <name>:<table>
+CREATE TABLE log_forward_cursors (
+    name VARCHAR(255) PRIMARY KEY,
+    ingested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_id UUID NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- Rolled up addresses stay anonymized
+DROP FUNCTION IF EXISTS anonymize_ip(TEXT);
+DROP INDEX IF EXISTS idx_license_check_logs_product_id_created_at;
+
+ALTER TABLE products DROP COLUMN IF EXISTS log_privacy;
@@ -0,0 +1,32 @@
+-- Per-product overrides of the license check log privacy policy:
+-- {"anonymize_ip": true, "license_key": "hash", "drop_user_agent": true, "redact_payloads": true, "retention_days": 30}
+ALTER TABLE products ADD COLUMN log_privacy JSONB NOT NULL DEFAULT '{}';
+
+-- Retention deletes the checks of one product at a time
+CREATE INDEX IF NOT EXISTS idx_license_check_logs_product_id_created_at ON license_check_logs(product_id, created_at);
+
+-- Rollups outlive the checks they come from, so client IPs are only rolled
+-- up as networks, as models.AnonymizeIP writes them: the /24 of IPv4 and the
+-- /48 of IPv6. Anything else, such as an erased address, is NULL so unique
+-- counts skip it.
+CREATE OR REPLACE FUNCTION anonymize_ip(ip TEXT) RETURNS TEXT AS $$
+DECLARE
+    addr INET;
+BEGIN
+    addr := ip::inet;
+    IF family(addr) = 4 THEN
+        RETURN host(network(set_masklen(addr, 24)));
+    END IF;
+    RETURN host(network(set_masklen(addr, 48)));
+EXCEPTION WHEN OTHERS THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+WITH old AS (DELETE FROM check_ip_rollups_hourly RETURNING *)
+INSERT INTO check_ip_rollups_hourly (bucket_start, product_id, ip_address, checks)
+SELECT bucket_start, product_id, anonymize_ip(ip_address), sum(checks) FROM old GROUP BY 1, 2, 3;
+
+WITH old AS (DELETE FROM check_ip_rollups_daily RETURNING *)
+INSERT INTO check_ip_rollups_daily (bucket_start, product_id, ip_address, checks)
+SELECT bucket_start, product_id, anonymize_ip(ip_address), sum(checks) FROM old GROUP BY 1, 2, 3;
@@ -0,0 +1,7 @@
+UPDATE product_groups SET license_separator = '-' WHERE license_separator IS NULL;
+UPDATE products SET license_separator = '-' WHERE license_separator IS NULL;
+ALTER TABLE product_groups ALTER COLUMN license_separator SET DEFAULT '-';
+ALTER TABLE products ALTER COLUMN license_separator SET DEFAULT '-';
+
+ALTER TABLE product_groups DROP COLUMN IF EXISTS license_duration;
+ALTER TABLE product_groups DROP COLUMN IF EXISTS license_type;
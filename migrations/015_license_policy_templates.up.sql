@@ -0,0 +1,10 @@
+-- Product groups carry the same license defaults as products
+ALTER TABLE product_groups ADD COLUMN IF NOT EXISTS license_type TEXT;
+ALTER TABLE product_groups ADD COLUMN IF NOT EXISTS license_duration TEXT;
+
+-- An unset separator is NULL rather than '-', so a product can choose '-'
+-- over the separator of its group
+ALTER TABLE products ALTER COLUMN license_separator DROP DEFAULT;
+ALTER TABLE product_groups ALTER COLUMN license_separator DROP DEFAULT;
+UPDATE products SET license_separator = NULL WHERE license_separator IN ('', '-');
+UPDATE product_groups SET license_separator = NULL WHERE license_separator IN ('', '-');
@@ -0,0 +1,4 @@
+ALTER TABLE product_groups DROP COLUMN IF EXISTS default_releases;
+ALTER TABLE product_groups DROP COLUMN IF EXISTS default_features;
+ALTER TABLE products DROP COLUMN IF EXISTS default_releases;
+ALTER TABLE products DROP COLUMN IF EXISTS default_features;
@@ -0,0 +1,5 @@
+-- Feature codes and release versions licenses get when the request lists none
+ALTER TABLE products ADD COLUMN IF NOT EXISTS default_features TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS default_releases TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE product_groups ADD COLUMN IF NOT EXISTS default_features TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE product_groups ADD COLUMN IF NOT EXISTS default_releases TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+ALTER TABLE license_features DROP COLUMN IF EXISTS value;
+DROP INDEX IF EXISTS idx_licenses_plan_id;
+ALTER TABLE licenses DROP COLUMN IF EXISTS plan_id;
+DROP TABLE IF EXISTS plans;
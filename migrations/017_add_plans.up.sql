@@ -0,0 +1,31 @@
+-- Plans (editions) bundle the features, releases, term and seats of the
+-- licenses of a product or of every product of a group
+CREATE TABLE plans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+    product_group_id UUID REFERENCES product_groups(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    code TEXT NOT NULL,
+    description TEXT,
+    -- Feature code to the value licenses on the plan get for it
+    features JSONB NOT NULL DEFAULT '{}',
+    releases TEXT[] NOT NULL DEFAULT '{}',
+    license_type TEXT,
+    license_duration TEXT,
+    seat_limit INTEGER NOT NULL DEFAULT 0,
+    owner_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((product_id IS NULL) <> (product_group_id IS NULL))
+);
+
+CREATE UNIQUE INDEX plans_product_code_idx ON plans (product_id, code) WHERE product_id IS NOT NULL;
+CREATE UNIQUE INDEX plans_group_code_idx ON plans (product_group_id, code) WHERE product_group_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_plans_owner_id ON plans(owner_id);
+
+-- Licenses on a deleted plan keep what it gave them
+ALTER TABLE licenses ADD COLUMN IF NOT EXISTS plan_id UUID REFERENCES plans(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_licenses_plan_id ON licenses(plan_id) WHERE plan_id IS NOT NULL;
+
+-- Value of a feature for a license, such as a quota; NULL when linked without one
+ALTER TABLE license_features ADD COLUMN IF NOT EXISTS value JSONB;
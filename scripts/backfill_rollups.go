@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"clortho/internal/config"
+	"clortho/internal/database"
+	"clortho/internal/service"
+	"clortho/internal/store"
+)
+
+func main() {
+	var databaseURL string
+	var fromStr string
+	var toStr string
+
+	flag.StringVar(&databaseURL, "database-url", "", "Database URL")
+	flag.StringVar(&fromStr, "from", "", "Start of the range to rebuild, RFC 3339 (default: oldest license check)")
+	flag.StringVar(&toStr, "to", "", "End of the range to rebuild, RFC 3339 (default: start of the current hour)")
+	flag.Parse()
+
+	if databaseURL == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		databaseURL = cfg.DatabaseURL
+	}
+
+	if databaseURL == "" {
+		log.Fatal("database-url is required (via flag or config.yaml)")
+	}
+
+	ctx := context.Background()
+	pool, err := database.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	statsStore := store.NewPostgresStatsStore(pool)
+
+	to := time.Now().UTC().Truncate(time.Hour)
+	if toStr != "" {
+		if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+			log.Fatalf("Invalid to: %v", err)
+		}
+	}
+
+	var from time.Time
+	if fromStr != "" {
+		if from, err = time.Parse(time.RFC3339, fromStr); err != nil {
+			log.Fatalf("Invalid from: %v", err)
+		}
+	} else {
+		earliest, err := statsStore.EarliestCheckLog(ctx)
+		if err != nil {
+			log.Fatalf("Failed to find oldest license check: %v", err)
+		}
+		if earliest == nil {
+			fmt.Println("No license checks to roll up")
+			return
+		}
+		from = *earliest
+	}
+
+	err = service.BackfillCheckRollups(ctx, statsStore, from, to, func(from, to time.Time) {
+		fmt.Printf("Rolled up %s - %s\n", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	})
+	if err != nil {
+		log.Fatalf("Failed to backfill rollups: %v", err)
+	}
+	fmt.Println("Rollups backfilled successfully")
+}
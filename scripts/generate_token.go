@@ -4,14 +4,17 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	"clortho/internal/config"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 func main() {
-	var configPath string
+	var configPath, subject string
 	flag.StringVar(&configPath, "config", "config.yaml", "Path to config file")
+	flag.StringVar(&subject, "subject", "admin", "Who the token is issued to, recorded in admin logs")
 	flag.Parse()
 
 	cfg, err := config.LoadFromPath(configPath)
@@ -19,7 +22,12 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{})
+	// The token ID tells apart the tokens of a subject in admin logs
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": subject,
+		"jti": uuid.NewString(),
+		"iat": time.Now().Unix(),
+	})
 
 	tokenString, err := token.SignedString([]byte(cfg.AdminSecret))
 	if err != nil {
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"clortho/internal/config"
+	"clortho/internal/database"
+	"clortho/internal/service"
+	"clortho/internal/store"
+)
+
+func main() {
+	var databaseURL string
+	var publicKeyB64 string
+
+	flag.StringVar(&databaseURL, "database-url", "", "Database URL")
+	flag.StringVar(&publicKeyB64, "pubkey", "", "Base64 encoded public key checkpoints are verified with (default: response_signing_public_key)")
+	flag.Parse()
+
+	if databaseURL == "" || publicKeyB64 == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		if databaseURL == "" {
+			databaseURL = cfg.DatabaseURL
+		}
+		if publicKeyB64 == "" {
+			publicKeyB64 = cfg.ResponseSigningPublicKey
+		}
+	}
+
+	if databaseURL == "" {
+		log.Fatal("database-url is required (via flag or config.yaml)")
+	}
+	publicKey, err := service.ParseVerifyingKey(publicKeyB64)
+	if err != nil {
+		log.Fatalf("Invalid public key: %v", err)
+	}
+
+	ctx := context.Background()
+	pool, err := database.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	report, err := service.VerifyAdminLogChain(ctx, store.NewPostgresLogStore(pool), publicKey)
+	if err != nil {
+		log.Fatalf("Failed to verify admin logs: %v", err)
+	}
+
+	fmt.Printf("Checked %d entries (seq %d - %d) and %d checkpoints\n", report.Entries, report.FirstSeq, report.LastSeq, report.Checkpoints)
+	if report.UnverifiedCheckpoints > 0 {
+		fmt.Printf("Skipped %d checkpoints signed with another key\n", report.UnverifiedCheckpoints)
+	}
+	if !report.Valid {
+		fmt.Printf("Chain broken at seq %d: %s\n", *report.BrokenAt, report.Reason)
+		if report.BrokenEntryID != nil {
+			fmt.Printf("Entry: %s\n", report.BrokenEntryID)
+		}
+		pool.Close()
+		os.Exit(1)
+	}
+	fmt.Println("Admin log chain is intact")
+}